@@ -0,0 +1,24 @@
+package aci
+
+import "fmt"
+
+// WithSuffix returns the group renamed to <name>-<suffix>. The DNS name label gets the same
+// suffix, since labels must be unique per region and both groups run side by side.
+func WithSuffix(doc []byte, suffix string) ([]byte, error) {
+	group, err := decodeGroup(doc)
+	if err != nil {
+		return nil, err
+	}
+	name, _ := group["name"].(string)
+	if name == "" {
+		return nil, fmt.Errorf("container group has no name")
+	}
+	group["name"] = name + "-" + suffix
+
+	props, _ := group["properties"].(map[string]any)
+	ipAddress, _ := props["ipAddress"].(map[string]any)
+	if label, _ := ipAddress["dnsNameLabel"].(string); label != "" {
+		ipAddress["dnsNameLabel"] = label + "-" + suffix
+	}
+	return encodeGroup(group)
+}
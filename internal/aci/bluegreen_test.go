@@ -0,0 +1,18 @@
+package aci
+
+import "testing"
+
+func TestWithSuffix(t *testing.T) {
+	doc := `{"name":"api","properties":{"ipAddress":{"type":"Public","dnsNameLabel":"api-prod","ports":[]}}}`
+	out, err := WithSuffix([]byte(doc), "green")
+	if err != nil {
+		t.Fatal(err)
+	}
+	group, err := Parse(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group.Name != "api-green" || group.Properties.IPAddress.DNSNameLabel != "api-prod-green" {
+		t.Errorf("unexpected group: %s", out)
+	}
+}
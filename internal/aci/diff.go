@@ -0,0 +1,445 @@
+package aci
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Change kinds reported by Diff
+const (
+	ChangeAdded    = "add"
+	ChangeRemoved  = "remove"
+	ChangeModified = "modify"
+)
+
+// SecretsTag holds an HMAC of the group's secret values. Azure never returns secrets, so the
+// hash is what lets Diff notice a rotated secret.
+const SecretsTag = "azctl-secrets"
+
+// masked replaces secret values in plans
+const masked = "(secret)"
+
+// Change is a single field-level difference between the desired and live container group
+type Change struct {
+	// Path locates the field; items of named lists are addressed by name, e.g.
+	// properties.containers[app].properties.image
+	Path   string
+	Kind   string
+	Before string
+	After  string
+}
+
+// Plan is the result of comparing a desired container group with the live one
+type Plan struct {
+	Name string
+	// Create is set when no live group exists
+	Create  bool
+	Changes []Change
+}
+
+// HasChanges reports whether deploying would change anything
+func (p *Plan) HasChanges() bool {
+	return p.Create || len(p.Changes) > 0
+}
+
+// Write prints the plan in a readable form
+func (p *Plan) Write(w io.Writer) error {
+	var b strings.Builder
+	switch {
+	case p.Create:
+		fmt.Fprintf(&b, "+ container group %s will be created\n", p.Name)
+	case len(p.Changes) == 0:
+		fmt.Fprintf(&b, "= container group %s is unchanged\n", p.Name)
+	default:
+		fmt.Fprintf(&b, "~ container group %s will be updated\n", p.Name)
+		for _, c := range p.Changes {
+			switch c.Kind {
+			case ChangeAdded:
+				fmt.Fprintf(&b, "    + %s: %s\n", c.Path, c.After)
+			case ChangeRemoved:
+				fmt.Fprintf(&b, "    - %s: %s\n", c.Path, c.Before)
+			default:
+				fmt.Fprintf(&b, "    ~ %s: %s => %s\n", c.Path, c.Before, c.After)
+			}
+		}
+		fmt.Fprintf(&b, "\n%d change(s)\n", len(p.Changes))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err //nolint:wrapcheck // writer errors need no extra context
+}
+
+// Diff compares a desired container group with the live one. live may be the ARM resource
+// or the flattened `az container show` output; nil means the group does not exist. Fields
+// Azure fills in (provisioning state, instance view, IP) are ignored because only fields
+// present in the desired document are compared, named lists (containers, volumes,
+// environment variables, ports) are matched by name regardless of order, and secrets,
+// which Azure never returns, are masked.
+func Diff(desired, live []byte) (*Plan, error) {
+	var want map[string]any
+	if err := json.Unmarshal(desired, &want); err != nil {
+		return nil, fmt.Errorf("invalid desired container group: %w", err)
+	}
+	name, _ := want["name"].(string)
+	plan := &Plan{Name: name}
+	if live == nil {
+		plan.Create = true
+		return plan, nil
+	}
+
+	var have map[string]any
+	if err := json.Unmarshal(live, &have); err != nil {
+		return nil, fmt.Errorf("invalid live container group: %w", err)
+	}
+	have = unflatten(have)
+
+	d := &differ{}
+	for _, key := range sortedKeys(want) {
+		switch key {
+		case "type", "apiVersion", "id":
+			continue
+		}
+		d.compare(key, key, want[key], lookup(have, key))
+	}
+	plan.Changes = d.changes
+	return plan, nil
+}
+
+// SecretsHash returns an HMAC of every secret value in the group keyed with key, or "" if it
+// has none. It is stored in SecretsTag so a rotated secret shows up as a change. Tags are
+// visible to anyone who can read the group, so the key must not be stored in Azure alongside
+// it; without the key the tag cannot be used to guess the secrets offline.
+func SecretsHash(doc, key []byte) (string, error) {
+	var group map[string]any
+	if err := json.Unmarshal(doc, &group); err != nil {
+		return "", fmt.Errorf("invalid container group: %w", err)
+	}
+	var secrets []string
+	collectSecrets("", group, &secrets)
+	if len(secrets) == 0 {
+		return "", nil
+	}
+	sort.Strings(secrets)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strings.Join(secrets, "\x00")))
+	return hex.EncodeToString(mac.Sum(nil)[:16]), nil
+}
+
+func collectSecrets(path string, v any, secrets *[]string) {
+	switch node := v.(type) {
+	case map[string]any:
+		for key, value := range node {
+			child := path + "." + key
+			if s, ok := value.(string); ok && isSecretPath(child) && s != "" {
+				*secrets = append(*secrets, child+"="+s)
+				continue
+			}
+			collectSecrets(child, value, secrets)
+		}
+	case []any:
+		for _, item := range node {
+			collectSecrets(path+"[]", item, secrets)
+		}
+	}
+}
+
+type differ struct {
+	changes []Change
+}
+
+func (d *differ) add(path, kind string, before, after any) {
+	change := Change{Path: path, Kind: kind}
+	if kind != ChangeAdded {
+		change.Before = display(path, before)
+	}
+	if kind != ChangeRemoved {
+		change.After = display(path, after)
+	}
+	d.changes = append(d.changes, change)
+}
+
+func (d *differ) compare(path, key string, want, have any) {
+	if want == nil {
+		return
+	}
+	if isSecretPath(path) {
+		// Azure returns null for secrets; rotations are caught by SecretsTag instead
+		if have != nil && !equalLeaf(key, want, have) {
+			d.add(path, ChangeModified, have, want)
+		}
+		return
+	}
+
+	switch w := want.(type) {
+	case map[string]any:
+		h, ok := have.(map[string]any)
+		if !ok {
+			d.missingOrReplaced(path, want, have)
+			return
+		}
+		for _, k := range sortedKeys(w) {
+			d.compare(path+"."+k, k, w[k], lookup(h, k))
+		}
+		if key == "tags" {
+			for _, k := range sortedKeys(h) {
+				if _, ok := w[k]; !ok {
+					d.add(path+"."+k, ChangeRemoved, h[k], nil)
+				}
+			}
+		}
+	case []any:
+		h, ok := have.([]any)
+		if !ok {
+			d.missingOrReplaced(path, want, have)
+			return
+		}
+		if identity(w) != nil {
+			d.compareKeyed(path, w, h)
+			return
+		}
+		if !equalList(key, w, h) {
+			d.add(path, ChangeModified, have, want)
+		}
+	default:
+		if have == nil {
+			d.add(path, ChangeAdded, nil, want)
+		} else if !equalLeaf(key, want, have) {
+			d.add(path, ChangeModified, have, want)
+		}
+	}
+}
+
+func (d *differ) missingOrReplaced(path string, want, have any) {
+	if have == nil {
+		d.add(path, ChangeAdded, nil, want)
+		return
+	}
+	d.add(path, ChangeModified, have, want)
+}
+
+// compareKeyed matches list items by identity (name, or port and protocol) so reordering
+// is not reported as a change
+func (d *differ) compareKeyed(path string, want, have []any) {
+	id := identity(want)
+	haveByID := make(map[string]any, len(have))
+	for _, item := range have {
+		haveByID[id(item)] = item
+	}
+	seen := make(map[string]bool, len(want))
+	for _, item := range want {
+		key := id(item)
+		seen[key] = true
+		itemPath := fmt.Sprintf("%s[%s]", path, key)
+		if existing, ok := haveByID[key]; ok {
+			d.compare(itemPath, "", item, existing)
+		} else {
+			d.add(itemPath, ChangeAdded, nil, item)
+		}
+	}
+	for _, item := range have {
+		if key := id(item); !seen[key] {
+			d.add(fmt.Sprintf("%s[%s]", path, key), ChangeRemoved, item, nil)
+		}
+	}
+}
+
+// identity returns the function identifying items of a list of objects, or nil if the list
+// is ordered (e.g. a command)
+func identity(items []any) func(any) string {
+	if len(items) == 0 {
+		return nil
+	}
+	first, ok := items[0].(map[string]any)
+	if !ok {
+		return nil
+	}
+	for _, field := range []string{"name", "server"} {
+		if _, ok := first[field]; ok {
+			return func(item any) string {
+				obj, _ := item.(map[string]any)
+				return fmt.Sprint(lookup(obj, field))
+			}
+		}
+	}
+	if _, ok := first["port"]; ok {
+		return func(item any) string {
+			obj, _ := item.(map[string]any)
+			protocol, _ := lookup(obj, "protocol").(string)
+			if protocol == "" {
+				protocol = "TCP"
+			}
+			return fmt.Sprintf("%v/%s", numberOrValue(lookup(obj, "port")), strings.ToUpper(protocol))
+		}
+	}
+	return nil
+}
+
+// caseInsensitive lists fields whose values Azure may return with different casing
+var caseInsensitive = map[string]bool{
+	"location": true, "osType": true, "protocol": true, "type": true, "restartPolicy": true, "scheme": true,
+}
+
+func equalLeaf(key string, want, have any) bool {
+	if a, ok := numeric(want); ok {
+		if b, ok := numeric(have); ok {
+			return a == b
+		}
+	}
+	ws, wok := want.(string)
+	hs, hok := have.(string)
+	if wok && hok && caseInsensitive[key] {
+		normalize := func(s string) string { return strings.ToLower(strings.ReplaceAll(s, " ", "")) }
+		return normalize(ws) == normalize(hs)
+	}
+	return fmt.Sprint(want) == fmt.Sprint(have)
+}
+
+func equalList(key string, want, have []any) bool {
+	if len(want) != len(have) {
+		return false
+	}
+	for i := range want {
+		if !equalLeaf(key, want[i], have[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// numeric accepts numbers and numeric strings, since Azure accepts "1.5" for 1.5
+func numeric(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func numberOrValue(v any) any {
+	if f, ok := numeric(v); ok {
+		return f
+	}
+	return v
+}
+
+// lookup finds a key case-insensitively; az CLI output spells some fields differently
+// from ARM (memoryInGb vs memoryInGB)
+func lookup(m map[string]any, key string) any {
+	if v, ok := m[key]; ok {
+		return v
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return nil
+}
+
+// groupFields stay at the top level of a container group; everything else in flattened
+// `az container show` output belongs under properties
+var groupFields = map[string]bool{
+	"id": true, "name": true, "location": true, "type": true, "tags": true, "identity": true, "zones": true,
+}
+
+// unflatten converts `az container show` output into the ARM resource shape
+func unflatten(group map[string]any) map[string]any {
+	if _, ok := group["properties"]; ok {
+		return group
+	}
+	result := map[string]any{}
+	props := map[string]any{}
+	for key, value := range group {
+		if groupFields[key] {
+			result[key] = value
+		} else {
+			props[key] = value
+		}
+	}
+	for _, listKey := range []string{"containers", "initContainers"} {
+		items, _ := props[listKey].([]any)
+		for i, item := range items {
+			obj, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			if _, ok := obj["properties"]; ok {
+				continue
+			}
+			container := map[string]any{"name": obj["name"]}
+			containerProps := map[string]any{}
+			for key, value := range obj {
+				if key != "name" {
+					containerProps[key] = value
+				}
+			}
+			container["properties"] = containerProps
+			items[i] = container
+		}
+	}
+	result["properties"] = props
+	return result
+}
+
+// isSecretPath reports whether a path holds a secret value
+func isSecretPath(path string) bool {
+	last := path[strings.LastIndexAny(path, ".]")+1:]
+	switch last {
+	case "secureValue", "password", "storageAccountKey":
+		return true
+	}
+	return strings.Contains(path, ".secret.")
+}
+
+// display formats a value for a plan with secrets masked
+func display(path string, v any) string {
+	if isSecretPath(path) {
+		return masked
+	}
+	data, err := json.Marshal(maskSecrets(path, v))
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func maskSecrets(path string, v any) any {
+	switch node := v.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(node))
+		for key, value := range node {
+			child := path + "." + key
+			if _, isString := value.(string); isString && isSecretPath(child) {
+				result[key] = masked
+			} else {
+				result[key] = maskSecrets(child, value)
+			}
+		}
+		return result
+	case []any:
+		result := make([]any, len(node))
+		for i, item := range node {
+			result[i] = maskSecrets(path+"[]", item)
+		}
+		return result
+	}
+	return v
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
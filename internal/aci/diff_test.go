@@ -0,0 +1,154 @@
+package aci
+
+import (
+	"strings"
+	"testing"
+)
+
+const desiredGroup = `{
+  "name": "api",
+  "location": "westeurope",
+  "type": "Microsoft.ContainerInstance/containerGroups",
+  "apiVersion": "2023-05-01",
+  "tags": {"team": "platform"},
+  "properties": {
+    "osType": "Linux",
+    "ipAddress": {"type": "Public", "ports": [{"protocol": "TCP", "port": 8080}]},
+    "containers": [
+      {"name": "app", "properties": {
+        "image": "registry.azurecr.io/api:v2",
+        "command": ["./api", "--serve"],
+        "resources": {"requests": {"cpu": "1", "memoryInGB": 1.5}},
+        "environmentVariables": [
+          {"name": "SUPABASE_URL", "value": "https://db.example.com"},
+          {"name": "SUPABASE_KEY", "secureValue": "new-secret"}
+        ]}},
+      {"name": "fluentbit", "properties": {
+        "image": "registry.azurecr.io/fluent-bit:4.0.8",
+        "resources": {"requests": {"cpu": 0.25, "memoryInGB": 0.3}}}}
+    ],
+    "imageRegistryCredentials": [{"server": "registry.azurecr.io", "username": "u", "password": "pw"}]
+  }
+}`
+
+// liveGroup is flattened `az container show` output with containers in a different order
+const liveGroup = `{
+  "id": "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerInstance/containerGroups/api",
+  "name": "api",
+  "location": "West Europe",
+  "provisioningState": "Succeeded",
+  "tags": {"team": "platform", "owner": "someone"},
+  "osType": "linux",
+  "ipAddress": {"type": "Public", "ip": "20.1.2.3", "ports": [{"protocol": "Tcp", "port": 8080}]},
+  "containers": [
+    {"name": "fluentbit", "image": "registry.azurecr.io/fluent-bit:4.0.8",
+     "resources": {"requests": {"cpu": 0.25, "memoryInGb": 0.3}}},
+    {"name": "app", "image": "registry.azurecr.io/api:v1", "command": ["./api", "--serve"],
+     "instanceView": {"restartCount": 0},
+     "resources": {"requests": {"cpu": 1.0, "memoryInGb": 1.5}},
+     "environmentVariables": [
+       {"name": "SUPABASE_KEY", "secureValue": null, "value": null},
+       {"name": "SUPABASE_URL", "value": "https://db.example.com"},
+       {"name": "DEBUG", "value": "true"}
+     ]}
+  ],
+  "imageRegistryCredentials": [{"server": "registry.azurecr.io", "username": "u", "password": null}]
+}`
+
+func TestDiff(t *testing.T) {
+	plan, err := Diff([]byte(desiredGroup), []byte(liveGroup))
+	if err != nil {
+		t.Fatalf("diff failed: %v", err)
+	}
+
+	got := make(map[string]Change)
+	for _, c := range plan.Changes {
+		got[c.Path] = c
+	}
+	if len(got) != 3 {
+		t.Errorf("expected 3 changes, got %+v", plan.Changes)
+	}
+	image := got["properties.containers[app].properties.image"]
+	if image.Kind != ChangeModified || image.Before != `"registry.azurecr.io/api:v1"` {
+		t.Errorf("unexpected image change: %+v", image)
+	}
+	if c := got["properties.containers[app].properties.environmentVariables[DEBUG]"]; c.Kind != ChangeRemoved {
+		t.Errorf("expected removed DEBUG variable, got %+v", c)
+	}
+	if c := got["tags.owner"]; c.Kind != ChangeRemoved {
+		t.Errorf("expected removed tag, got %+v", c)
+	}
+
+	var out strings.Builder
+	if err := plan.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "~ container group api will be updated") || strings.Contains(out.String(), "pw") {
+		t.Errorf("unexpected plan:\n%s", out.String())
+	}
+}
+
+func TestDiffUnchangedAndCreate(t *testing.T) {
+	plan, err := Diff([]byte(desiredGroup), []byte(desiredGroup))
+	if err != nil || plan.HasChanges() {
+		t.Errorf("identical groups should have no changes: %+v (err %v)", plan, err)
+	}
+
+	plan, err = Diff([]byte(desiredGroup), nil)
+	if err != nil || !plan.Create || !plan.HasChanges() {
+		t.Errorf("expected create plan, got %+v (err %v)", plan, err)
+	}
+}
+
+func TestDiffMasksSecrets(t *testing.T) {
+	desired := `{"name":"api","properties":{"containers":[{"name":"app","properties":{"environmentVariables":[
+		{"name":"TOKEN","secureValue":"s3cret"}]}}]}}`
+	live := `{"name":"api","properties":{"containers":[]}}`
+
+	plan, err := Diff([]byte(desired), []byte(live))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Changes) != 1 || strings.Contains(plan.Changes[0].After, "s3cret") ||
+		!strings.Contains(plan.Changes[0].After, masked) {
+		t.Errorf("secret not masked: %+v", plan.Changes)
+	}
+}
+
+func TestSecretsHash(t *testing.T) {
+	stamp := func(doc string) []byte {
+		t.Helper()
+		hash, err := SecretsHash([]byte(doc), []byte("hash-key"))
+		if err != nil || hash == "" {
+			t.Fatalf("expected a hash, got %q (err %v)", hash, err)
+		}
+		out, err := SetTag([]byte(doc), FormatJSON, SecretsTag, hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	first := stamp(desiredGroup)
+	rotated := stamp(strings.Replace(desiredGroup, "new-secret", "rotated", 1))
+
+	// Azure returns null for secrets, so only the tag reveals the rotation
+	live := strings.Replace(string(first), `"new-secret"`, "null", 1)
+	plan, err := Diff(rotated, []byte(live))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Changes) != 1 || plan.Changes[0].Path != "tags."+SecretsTag {
+		t.Errorf("expected only the secrets tag to change, got %+v", plan.Changes)
+	}
+
+	if hash, err := SecretsHash([]byte(`{"name":"api"}`), []byte("hash-key")); err != nil || hash != "" {
+		t.Errorf("group without secrets should have no hash: %q (err %v)", hash, err)
+	}
+
+	// The hash depends on the key, so it cannot be recomputed from guessed secrets alone
+	withKey, _ := SecretsHash([]byte(desiredGroup), []byte("hash-key"))
+	otherKey, _ := SecretsHash([]byte(desiredGroup), []byte("other-key"))
+	if withKey == otherKey {
+		t.Errorf("expected the key to change the hash, got %s for both", withKey)
+	}
+}
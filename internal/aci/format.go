@@ -0,0 +1,140 @@
+package aci
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Container group document formats accepted by `az container create --file`
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// FormatFromPath returns FormatYAML for .yaml/.yml files and FormatJSON otherwise
+func FormatFromPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	}
+	return FormatJSON
+}
+
+// YAMLToJSON converts a YAML document (a container group or an overlay) to JSON
+func YAMLToJSON(data []byte) ([]byte, error) {
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("YAML cannot be represented as JSON: %w", err)
+	}
+	return out, nil
+}
+
+// JSONToYAML converts a JSON container group document to YAML
+func JSONToYAML(data []byte) ([]byte, error) {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Convert encodes a JSON container group document in the given format
+func Convert(data []byte, format string) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return data, nil
+	case FormatYAML:
+		return JSONToYAML(data)
+	}
+	return nil, fmt.Errorf("unknown format %q (expected %s or %s)", format, FormatJSON, FormatYAML)
+}
+
+// SetTag sets a tag on a JSON or YAML container group document. YAML documents are edited
+// in place so comments and layout are kept.
+func SetTag(doc []byte, format, key, value string) ([]byte, error) {
+	if format == FormatYAML {
+		return setYAMLTag(doc, key, value)
+	}
+
+	var group map[string]any
+	if err := json.Unmarshal(doc, &group); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	tags, _ := group["tags"].(map[string]any)
+	if tags == nil {
+		tags = map[string]any{}
+	}
+	tags[key] = value
+	group["tags"] = tags
+	out, err := json.MarshalIndent(group, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode container group: %w", err)
+	}
+	return out, nil
+}
+
+func setYAMLTag(doc []byte, key, value string) ([]byte, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(doc, &root); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("invalid YAML: container group must be a mapping")
+	}
+	group := root.Content[0]
+
+	tags := mappingValue(group, "tags")
+	if tags == nil || tags.Kind != yaml.MappingNode {
+		tags = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		setMappingValue(group, "tags", tags)
+	}
+	setMappingValue(tags, key, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&root); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
@@ -0,0 +1,85 @@
+package aci
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatFromPath(t *testing.T) {
+	for path, want := range map[string]string{
+		"aci.json": FormatJSON, "aci.yaml": FormatYAML, "deploy/ACI.YML": FormatYAML, "aci": FormatJSON,
+	} {
+		if got := FormatFromPath(path); got != want {
+			t.Errorf("FormatFromPath(%s) = %s, want %s", path, got, want)
+		}
+	}
+}
+
+func TestYAMLRoundTrip(t *testing.T) {
+	input := `# container group for the API
+name: api
+location: westeurope
+properties:
+  osType: Linux
+  containers:
+    - name: app
+      properties:
+        image: registry.azurecr.io/api:v1
+        command:
+          - /bin/sh
+          - -c
+          - |
+            echo starting
+            exec ./api
+        resources:
+          requests: {cpu: 1, memoryInGB: 1.5}
+`
+	converted, err := YAMLToJSON([]byte(input))
+	if err != nil {
+		t.Fatalf("YAMLToJSON failed: %v", err)
+	}
+	group, err := Parse(converted)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	container := group.Properties.Containers[0]
+	if group.Name != "api" || container.Properties.Resources.Requests.MemoryInGB != 1.5 {
+		t.Errorf("unexpected group: %+v", group)
+	}
+	if got := container.Properties.Command[2]; got != "echo starting\nexec ./api\n" {
+		t.Errorf("multi-line command not preserved: %q", got)
+	}
+
+	back, err := Convert(converted, FormatYAML)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if !strings.Contains(string(back), "name: api") || !strings.Contains(string(back), "memoryInGB: 1.5") {
+		t.Errorf("unexpected YAML:\n%s", back)
+	}
+
+	if _, err := YAMLToJSON([]byte("name: [unclosed")); err == nil {
+		t.Error("expected error for invalid YAML")
+	}
+	if _, err := Convert(converted, "toml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestSetTagYAML(t *testing.T) {
+	input := "# API group\nname: api # the group name\nlocation: westeurope\n"
+	out, err := SetTag([]byte(input), FormatYAML, SecretsTag, "abc123")
+	if err != nil {
+		t.Fatalf("SetTag failed: %v", err)
+	}
+	for _, want := range []string{"# API group", "# the group name", "tags:\n  azctl-secrets: abc123"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	out, err = SetTag(out, FormatYAML, SecretsTag, "def456")
+	if err != nil || strings.Contains(string(out), "abc123") || !strings.Contains(string(out), "def456") {
+		t.Errorf("existing tag not replaced:\n%s (err %v)", out, err)
+	}
+}
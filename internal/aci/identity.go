@@ -0,0 +1,62 @@
+package aci
+
+import (
+	"strings"
+)
+
+// registryIdentityAPIVersion is the first API version with imageRegistryCredentials[].identity
+const registryIdentityAPIVersion = "2021-07-01"
+
+// UseRegistryIdentity makes the group pull images from server with the user-assigned managed
+// identity identityID instead of a username and password. The identity is added to the
+// group's identities, the credential for server is replaced, or added if the group has
+// none, and an older apiVersion is raised to one that supports identity credentials.
+func UseRegistryIdentity(doc []byte, server, identityID string) ([]byte, error) {
+	group, err := decodeGroup(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	ident, _ := group["identity"].(map[string]any)
+	if ident == nil {
+		ident = map[string]any{}
+		group["identity"] = ident
+	}
+	switch kind, _ := ident["type"].(string); {
+	case kind == "" || strings.EqualFold(kind, "None"):
+		ident["type"] = "UserAssigned"
+	case !strings.Contains(strings.ToLower(kind), "userassigned"):
+		ident["type"] = kind + ", UserAssigned"
+	}
+	assigned, _ := ident["userAssignedIdentities"].(map[string]any)
+	if assigned == nil {
+		assigned = map[string]any{}
+		ident["userAssignedIdentities"] = assigned
+	}
+	if _, ok := assigned[identityID]; !ok {
+		assigned[identityID] = map[string]any{}
+	}
+
+	props, _ := group["properties"].(map[string]any)
+	if props == nil {
+		props = map[string]any{}
+		group["properties"] = props
+	}
+	credentials, _ := props["imageRegistryCredentials"].([]any)
+	found := false
+	for _, c := range credentials {
+		credential, _ := c.(map[string]any)
+		if s, _ := credential["server"].(string); !strings.EqualFold(s, server) {
+			continue
+		}
+		delete(credential, "username")
+		delete(credential, "password")
+		credential["identity"] = identityID
+		found = true
+	}
+	if !found {
+		props["imageRegistryCredentials"] = append(credentials, map[string]any{"server": server, "identity": identityID})
+	}
+	requireAPIVersion(group, registryIdentityAPIVersion)
+	return encodeGroup(group)
+}
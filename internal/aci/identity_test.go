@@ -0,0 +1,58 @@
+package aci
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUseRegistryIdentity(t *testing.T) {
+	const id = "/subscriptions/s/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/pull"
+	doc := `{"name":"api","location":"eastus","apiVersion":"2019-12-01",
+		"identity":{"type":"SystemAssigned"},"properties":{
+		"containers":[{"name":"app","properties":{"image":"reg.azurecr.io/api:v1",
+			"resources":{"requests":{"cpu":1,"memoryInGB":1}}}}],
+		"imageRegistryCredentials":[{"server":"reg.azurecr.io","username":"","password":""},
+			{"server":"docker.io","username":"me","password":"pw"}]}}`
+
+	out, err := UseRegistryIdentity([]byte(doc), "reg.azurecr.io", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	group, err := Parse(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group.Identity.Type != "SystemAssigned, UserAssigned" || group.Identity.UserAssignedIdentities[id] == nil {
+		t.Errorf("unexpected identity: %+v", group.Identity)
+	}
+	credentials := group.Properties.ImageRegistryCredentials
+	if credentials[0].Identity != id || credentials[0].Username != "" || credentials[0].Password != "" {
+		t.Errorf("ACR credential not replaced: %+v", credentials[0])
+	}
+	if credentials[1].Password != "pw" {
+		t.Errorf("other registries must keep their credentials: %+v", credentials[1])
+	}
+	if group.APIVersion != registryIdentityAPIVersion {
+		t.Errorf("expected apiVersion %s for identity credentials, got %q", registryIdentityAPIVersion, group.APIVersion)
+	}
+	if issues := Validate(group, DefaultLimits); len(issues) != 0 {
+		t.Errorf("expected no issues, got %v", issues)
+	}
+
+	// A group without credentials gets one, and the identity must be assigned to the group
+	out, err = UseRegistryIdentity([]byte(validGroup), "reg.azurecr.io", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group, err = Parse(out); err != nil {
+		t.Fatal(err)
+	}
+	if len(group.Properties.ImageRegistryCredentials) != 1 || group.Identity.Type != "UserAssigned" {
+		t.Errorf("unexpected group: %s", out)
+	}
+	group.Identity = nil
+	issues := Validate(group, DefaultLimits)
+	if len(issues) != 1 || !strings.Contains(issues[0].Message, "not assigned to the group") {
+		t.Errorf("expected an unassigned identity issue, got %v", issues)
+	}
+}
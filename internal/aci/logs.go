@@ -0,0 +1,105 @@
+package aci
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// LogLine is one line of container output
+type LogLine struct {
+	Time      time.Time
+	Container string
+	Message   string
+}
+
+// ParseLogs splits the output of the container logs API, requested with timestamps=true,
+// into lines. Each line starts with an RFC 3339 timestamp; lines without one keep a zero
+// Time.
+func ParseLogs(container, content string) []LogLine {
+	content = strings.TrimRight(content, "\n")
+	if content == "" {
+		return nil
+	}
+	raw := strings.Split(content, "\n")
+	lines := make([]LogLine, 0, len(raw))
+	for _, text := range raw {
+		line := LogLine{Container: container, Message: strings.TrimSuffix(text, "\r")}
+		if stamp, message, ok := strings.Cut(line.Message, " "); ok {
+			if t, err := time.Parse(time.RFC3339Nano, stamp); err == nil {
+				line.Time, line.Message = t, message
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// LogCursor remembers how far a container's output has been read. The logs API returns a
+// capped tail, so lines are told apart by timestamp rather than by position.
+type LogCursor struct {
+	last time.Time
+	// atLast counts the lines read with timestamp last, which may share it with newer lines
+	atLast int
+}
+
+// Next returns the lines not read before and advances the cursor past them. Lines without a
+// timestamp go with the line before them.
+func (c *LogCursor) Next(lines []LogLine) []LogLine {
+	var fresh []LogLine
+	last, atLast := c.last, 0
+	seen, keep := 0, c.last.IsZero()
+	for _, line := range lines {
+		if !line.Time.IsZero() {
+			switch {
+			case line.Time.After(c.last):
+				keep = true
+			case line.Time.Equal(c.last):
+				seen++
+				keep = seen > c.atLast
+			default:
+				keep = false
+			}
+			switch {
+			case line.Time.After(last):
+				last, atLast = line.Time, 1
+			case line.Time.Equal(last):
+				atLast++
+			}
+		}
+		if keep {
+			fresh = append(fresh, line)
+		}
+	}
+	if last.Equal(c.last) {
+		atLast = max(atLast, c.atLast)
+	}
+	c.last, c.atLast = last, atLast
+	return fresh
+}
+
+// MergeLogs interleaves the lines of several containers by time. Lines without a timestamp
+// stay after the line that preceded them.
+func MergeLogs(lines []LogLine) []LogLine {
+	var last time.Time
+	keys := make([]time.Time, len(lines))
+	for i, line := range lines {
+		if i > 0 && line.Container != lines[i-1].Container {
+			last = time.Time{}
+		}
+		if !line.Time.IsZero() {
+			last = line.Time
+		}
+		keys[i] = last
+	}
+	merged := make([]LogLine, len(lines))
+	order := make([]int, len(lines))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return keys[order[a]].Before(keys[order[b]]) })
+	for i, idx := range order {
+		merged[i] = lines[idx]
+	}
+	return merged
+}
@@ -0,0 +1,60 @@
+package aci
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAndMergeLogs(t *testing.T) {
+	app := ParseLogs("app", "2024-05-01T10:00:00.000000001Z starting\n"+
+		"2024-05-01T10:00:02Z listening on :8080\n  at main.go:12\n")
+	sidecar := ParseLogs("fluentbit", "2024-05-01T10:00:01Z [info] fluent bit started\r\n")
+	if len(app) != 3 || app[0].Message != "starting" || !app[2].Time.IsZero() {
+		t.Fatalf("unexpected app lines: %+v", app)
+	}
+
+	var got []string
+	for _, line := range MergeLogs(append(app, sidecar...)) {
+		got = append(got, line.Container+": "+line.Message)
+	}
+	want := []string{
+		"app: starting",
+		"fluentbit: [info] fluent bit started",
+		"app: listening on :8080",
+		"app:   at main.go:12",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("merged logs:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if ParseLogs("app", "") != nil {
+		t.Error("expected no lines for empty logs")
+	}
+}
+
+func TestLogCursor(t *testing.T) {
+	var cursor LogCursor
+	read := func(content string) string {
+		var messages []string
+		for _, line := range cursor.Next(ParseLogs("app", content)) {
+			messages = append(messages, line.Message)
+		}
+		return strings.Join(messages, ",")
+	}
+
+	if got := read("2024-05-01T10:00:00Z a\n2024-05-01T10:00:01Z b\n2024-05-01T10:00:01Z c\n"); got != "a,b,c" {
+		t.Errorf("first read = %q", got)
+	}
+	// The capped tail dropped a and gained d; a line sharing c's timestamp is new too
+	got := read("2024-05-01T10:00:01Z b\n2024-05-01T10:00:01Z c\n2024-05-01T10:00:01Z d\n" +
+		"2024-05-01T10:00:02Z e\n  continued\n")
+	if got != "d,e,  continued" {
+		t.Errorf("second read = %q", got)
+	}
+	if got := read("2024-05-01T10:00:02Z e\n  continued\n"); got != "" {
+		t.Errorf("expected nothing new, got %q", got)
+	}
+	// A restarted container starts a shorter log, which is still newer
+	if got := read("2024-05-01T10:05:00Z restarted\n"); got != "restarted" {
+		t.Errorf("after restart = %q", got)
+	}
+}
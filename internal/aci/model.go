@@ -0,0 +1,193 @@
+// Package aci provides a typed model of Microsoft.ContainerInstance/containerGroups
+// definitions and semantic checks that run before anything is sent to Azure.
+package aci
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// ResourceType is the ARM resource type of a container group
+const ResourceType = "Microsoft.ContainerInstance/containerGroups"
+
+// ContainerGroup is the container group document accepted by `az container create --file`
+type ContainerGroup struct {
+	Name       string            `json:"name"`
+	Location   string            `json:"location"`
+	Type       string            `json:"type,omitempty"`
+	APIVersion string            `json:"apiVersion,omitempty"`
+	Tags       map[string]string `json:"tags,omitempty"`
+	Identity   *Identity         `json:"identity,omitempty"`
+	Properties GroupProperties   `json:"properties"`
+}
+
+// Identity is the managed identity assigned to a container group
+type Identity struct {
+	Type                   string                    `json:"type"`
+	UserAssignedIdentities map[string]map[string]any `json:"userAssignedIdentities,omitempty"`
+}
+
+// GroupProperties holds the container group properties
+type GroupProperties struct {
+	OSType                   string                    `json:"osType"`
+	RestartPolicy            string                    `json:"restartPolicy,omitempty"`
+	IPAddress                *IPAddress                `json:"ipAddress,omitempty"`
+	Containers               []Container               `json:"containers"`
+	InitContainers           []Container               `json:"initContainers,omitempty"`
+	Volumes                  []Volume                  `json:"volumes,omitempty"`
+	ImageRegistryCredentials []ImageRegistryCredential `json:"imageRegistryCredentials,omitempty"`
+	SubnetIDs                []SubnetID                `json:"subnetIds,omitempty"`
+	DNSConfig                *DNSConfig                `json:"dnsConfig,omitempty"`
+}
+
+// IPAddress describes the group's public or private IP address
+type IPAddress struct {
+	Type         string `json:"type"`
+	IP           string `json:"ip,omitempty"`
+	DNSNameLabel string `json:"dnsNameLabel,omitempty"`
+	FQDN         string `json:"fqdn,omitempty"`
+	Ports        []Port `json:"ports"`
+}
+
+// Port is an exposed port on the group IP or a container
+type Port struct {
+	Protocol string `json:"protocol,omitempty"`
+	Port     int    `json:"port"`
+}
+
+// Container is a single container in the group
+type Container struct {
+	Name       string              `json:"name"`
+	Properties ContainerProperties `json:"properties"`
+}
+
+// ContainerProperties holds a container's image, resources and runtime settings
+type ContainerProperties struct {
+	Image                string                `json:"image"`
+	Command              []string              `json:"command,omitempty"`
+	Ports                []Port                `json:"ports,omitempty"`
+	EnvironmentVariables []EnvironmentVariable `json:"environmentVariables,omitempty"`
+	Resources            Resources             `json:"resources"`
+	VolumeMounts         []VolumeMount         `json:"volumeMounts,omitempty"`
+	LivenessProbe        *Probe                `json:"livenessProbe,omitempty"`
+	ReadinessProbe       *Probe                `json:"readinessProbe,omitempty"`
+}
+
+// EnvironmentVariable is a plain or secure container environment variable
+type EnvironmentVariable struct {
+	Name        string `json:"name"`
+	Value       string `json:"value,omitempty"`
+	SecureValue string `json:"secureValue,omitempty"`
+}
+
+// Resources holds resource requests and limits
+type Resources struct {
+	Requests ResourceSpec  `json:"requests"`
+	Limits   *ResourceSpec `json:"limits,omitempty"`
+}
+
+// ResourceSpec is a CPU and memory amount
+type ResourceSpec struct {
+	CPU        Quantity `json:"cpu"`
+	MemoryInGB Quantity `json:"memoryInGB"`
+}
+
+// VolumeMount mounts a group volume into a container
+type VolumeMount struct {
+	Name      string `json:"name"`
+	MountPath string `json:"mountPath"`
+	ReadOnly  bool   `json:"readOnly,omitempty"`
+}
+
+// Volume is a volume that containers in the group can mount
+type Volume struct {
+	Name      string            `json:"name"`
+	AzureFile *AzureFileVolume  `json:"azureFile,omitempty"`
+	EmptyDir  map[string]any    `json:"emptyDir,omitempty"`
+	Secret    map[string]string `json:"secret,omitempty"`
+	GitRepo   map[string]any    `json:"gitRepo,omitempty"`
+}
+
+// AzureFileVolume is an Azure Files share mounted as a volume
+type AzureFileVolume struct {
+	ShareName          string `json:"shareName"`
+	StorageAccountName string `json:"storageAccountName"`
+	StorageAccountKey  string `json:"storageAccountKey,omitempty"`
+	ReadOnly           bool   `json:"readOnly,omitempty"`
+}
+
+// ImageRegistryCredential authenticates image pulls from a private registry
+type ImageRegistryCredential struct {
+	Server   string `json:"server"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Identity string `json:"identity,omitempty"`
+}
+
+// SubnetID references a delegated subnet for private deployments
+type SubnetID struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// DNSConfig configures DNS for containers in a private group
+type DNSConfig struct {
+	NameServers   []string `json:"nameServers"`
+	SearchDomains string   `json:"searchDomains,omitempty"`
+	Options       string   `json:"options,omitempty"`
+}
+
+// Probe is a liveness or readiness probe
+type Probe struct {
+	Exec                *ExecProbe `json:"exec,omitempty"`
+	HTTPGet             *HTTPProbe `json:"httpGet,omitempty"`
+	InitialDelaySeconds int        `json:"initialDelaySeconds,omitempty"`
+	PeriodSeconds       int        `json:"periodSeconds,omitempty"`
+	FailureThreshold    int        `json:"failureThreshold,omitempty"`
+	SuccessThreshold    int        `json:"successThreshold,omitempty"`
+	TimeoutSeconds      int        `json:"timeoutSeconds,omitempty"`
+}
+
+// ExecProbe runs a command inside the container
+type ExecProbe struct {
+	Command []string `json:"command"`
+}
+
+// HTTPProbe issues an HTTP GET against the container
+type HTTPProbe struct {
+	Path   string `json:"path,omitempty"`
+	Port   int    `json:"port"`
+	Scheme string `json:"scheme,omitempty"`
+}
+
+// Quantity is a CPU or memory amount. Azure accepts both numbers and numeric strings.
+type Quantity float64
+
+// UnmarshalJSON accepts 1, 1.5 or "1.5"
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	var f float64
+	if err := json.Unmarshal(data, &f); err == nil {
+		*q = Quantity(f)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("quantity must be a number: %s", data)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("quantity must be a number: %q", s)
+	}
+	*q = Quantity(f)
+	return nil
+}
+
+// Parse decodes a rendered container group definition
+func Parse(data []byte) (*ContainerGroup, error) {
+	var group ContainerGroup
+	if err := json.Unmarshal(data, &group); err != nil {
+		return nil, fmt.Errorf("invalid container group definition: %w", err)
+	}
+	return &group, nil
+}
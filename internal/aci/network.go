@@ -0,0 +1,95 @@
+package aci
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/furiatona/azctl/internal/config"
+)
+
+// Network modes of a container group (ACI_NETWORK_MODE)
+const (
+	NetworkPublic  = "public"
+	NetworkPrivate = "private"
+)
+
+// Network places a container group in delegated subnets of a virtual network
+type Network struct {
+	SubnetIDs []string
+	// DNS overrides the virtual network's DNS servers for the containers, if set
+	DNS *DNSConfig
+}
+
+// NetworkFromConfig returns the network set by ACI_NETWORK_MODE=private, ACI_SUBNET_IDS,
+// ACI_DNS_SERVERS and ACI_DNS_SEARCH_DOMAINS, or nil in public mode
+func NetworkFromConfig(cfg *config.Config) *Network {
+	if !strings.EqualFold(cfg.Get("ACI_NETWORK_MODE"), NetworkPrivate) {
+		return nil
+	}
+	network := &Network{SubnetIDs: config.SplitList(cfg.Get("ACI_SUBNET_IDS"))}
+	if servers := config.SplitList(cfg.Get("ACI_DNS_SERVERS")); len(servers) > 0 {
+		network.DNS = &DNSConfig{NameServers: servers, SearchDomains: cfg.Get("ACI_DNS_SEARCH_DOMAINS")}
+	}
+	return network
+}
+
+// subnetIDsAPIVersion is the first API version with properties.subnetIds
+const subnetIDsAPIVersion = "2021-07-01"
+
+// UsePrivateNetwork gives the group a private IP address in the network's subnets. The DNS
+// name label is dropped since private addresses have none, and an older apiVersion is raised
+// to one that supports subnets.
+func UsePrivateNetwork(doc []byte, network *Network) ([]byte, error) {
+	if len(network.SubnetIDs) == 0 {
+		return nil, errors.New("private network requires at least one subnet ID")
+	}
+	group, err := decodeGroup(doc)
+	if err != nil {
+		return nil, err
+	}
+	props, _ := group["properties"].(map[string]any)
+	if props == nil {
+		props = map[string]any{}
+		group["properties"] = props
+	}
+
+	if ipAddress, ok := props["ipAddress"].(map[string]any); ok {
+		ipAddress["type"] = "Private"
+		delete(ipAddress, "dnsNameLabel")
+		delete(ipAddress, "fqdn")
+	}
+	subnets := make([]any, 0, len(network.SubnetIDs))
+	for _, id := range network.SubnetIDs {
+		subnets = append(subnets, map[string]any{"id": id})
+	}
+	props["subnetIds"] = subnets
+	requireAPIVersion(group, subnetIDsAPIVersion)
+
+	if network.DNS != nil {
+		dnsConfig := map[string]any{"nameServers": toAny(network.DNS.NameServers)}
+		if network.DNS.SearchDomains != "" {
+			dnsConfig["searchDomains"] = network.DNS.SearchDomains
+		}
+		if network.DNS.Options != "" {
+			dnsConfig["options"] = network.DNS.Options
+		}
+		props["dnsConfig"] = dnsConfig
+	}
+	return encodeGroup(group)
+}
+
+func toAny(values []string) []any {
+	items := make([]any, len(values))
+	for i, v := range values {
+		items[i] = v
+	}
+	return items
+}
+
+// requireAPIVersion raises the group's apiVersion to minimum when it is unset or older.
+// API versions are dates, so they compare as strings.
+func requireAPIVersion(group map[string]any, minimum string) {
+	if version, _ := group["apiVersion"].(string); version < minimum {
+		group["apiVersion"] = minimum
+	}
+}
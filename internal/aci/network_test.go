@@ -0,0 +1,72 @@
+package aci
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/furiatona/azctl/internal/config"
+)
+
+func TestUsePrivateNetwork(t *testing.T) {
+	const subnet = "/subscriptions/s/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/aci"
+	cfg := config.New()
+	if NetworkFromConfig(cfg) != nil {
+		t.Fatal("expected no network in public mode")
+	}
+	cfg.Set("ACI_NETWORK_MODE", "Private")
+	cfg.Set("ACI_SUBNET_IDS", subnet+", ")
+	cfg.Set("ACI_DNS_SERVERS", "10.0.0.4,10.0.0.5")
+	cfg.Set("ACI_DNS_SEARCH_DOMAINS", "internal.example.com")
+	network := NetworkFromConfig(cfg)
+	if network == nil || len(network.SubnetIDs) != 1 || len(network.DNS.NameServers) != 2 {
+		t.Fatalf("unexpected network: %+v", network)
+	}
+
+	public := strings.Replace(validGroup, `"type": "Public",`, `"type": "Public", "dnsNameLabel": "app-dev",`, 1)
+	out, err := UsePrivateNetwork([]byte(public), network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	group, err := Parse(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ip := group.Properties.IPAddress
+	if ip.Type != "Private" || ip.DNSNameLabel != "" || len(ip.Ports) != 1 {
+		t.Errorf("unexpected ipAddress: %+v", ip)
+	}
+	if group.Properties.SubnetIDs[0].ID != subnet || group.Properties.DNSConfig.SearchDomains != "internal.example.com" {
+		t.Errorf("unexpected network: %s", out)
+	}
+	// An older apiVersion is raised to one with subnetIds; a newer one is kept
+	for version, want := range map[string]string{"": subnetIDsAPIVersion, "2019-12-01": subnetIDsAPIVersion,
+		"2023-05-01": "2023-05-01"} {
+		doc := strings.Replace(public, `"name": "app",`, `"name": "app", "apiVersion": "`+version+`",`, 1)
+		out, err := UsePrivateNetwork([]byte(doc), network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Parse(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.APIVersion != want {
+			t.Errorf("apiVersion %q: got %q, want %q", version, got.APIVersion, want)
+		}
+	}
+	if issues := Validate(group, DefaultLimits); len(issues) != 0 {
+		t.Errorf("expected no issues, got %v", issues)
+	}
+
+	// The same settings on a public group are rejected
+	group.Properties.IPAddress.Type = "Public"
+	group.Properties.IPAddress.DNSNameLabel = "app-dev"
+	issues := Validate(group, DefaultLimits)
+	if len(issues) != 1 || !strings.Contains(issues[0].Message, "only have a private IP address") {
+		t.Errorf("expected a public IP issue, got %v", issues)
+	}
+
+	if _, err := UsePrivateNetwork([]byte(validGroup), &Network{}); err == nil {
+		t.Error("expected an error without subnets")
+	}
+}
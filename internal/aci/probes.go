@@ -0,0 +1,260 @@
+package aci
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/furiatona/azctl/internal/config"
+	"github.com/furiatona/azctl/internal/validation"
+)
+
+// Default probe timing, used for fields neither the manifest nor configuration set. Liveness
+// waits longer before the first check so a slow start is not mistaken for a hang.
+var (
+	DefaultLivenessTiming  = Probe{InitialDelaySeconds: 30, PeriodSeconds: 10, FailureThreshold: 3, TimeoutSeconds: 5}
+	DefaultReadinessTiming = Probe{InitialDelaySeconds: 5, PeriodSeconds: 10, FailureThreshold: 3, TimeoutSeconds: 5}
+)
+
+// ProbeKind holds the settings of liveness or readiness probes
+type ProbeKind struct {
+	// Field is the container property, livenessProbe or readinessProbe
+	Field string
+	// Probe is added to the selected containers that define no probe of this kind; nil adds none
+	Probe *Probe
+	// Timing fills the unset timing fields of every probe of this kind
+	Timing Probe
+}
+
+// ProbeSettings configures the probes of a container group's containers
+type ProbeSettings struct {
+	Kinds []ProbeKind
+	// Containers receive the configured probes; empty selects the containers exposing the
+	// probe's port, or the first container if none does
+	Containers []string
+	// Port is the application port, probed by HTTP probes without a port of their own
+	Port int
+}
+
+// ProbesFromConfig reads the probe settings. For each kind (LIVENESS, READINESS):
+// ACI_<KIND>_PATH and ACI_<KIND>_PORT (default ACI_PORT) for an HTTP probe, or
+// ACI_<KIND>_COMMAND for an exec probe, and ACI_<KIND>_INITIAL_DELAY, _PERIOD, _TIMEOUT
+// (seconds) and _FAILURE_THRESHOLD. ACI_PROBE_CONTAINERS selects the containers. Every
+// invalid setting is reported as a *validation.FieldError.
+func ProbesFromConfig(cfg *config.Config) (*ProbeSettings, error) {
+	var errs []error
+	settings := &ProbeSettings{Containers: config.SplitList(cfg.Get("ACI_PROBE_CONTAINERS"))}
+	if port := cfg.Get("ACI_PORT"); port != "" {
+		p, err := parsePort("ACI_PORT", port)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		settings.Port = p
+	}
+
+	kinds := []struct {
+		name, field string
+		timing      Probe
+	}{
+		{"LIVENESS", "livenessProbe", DefaultLivenessTiming},
+		{"READINESS", "readinessProbe", DefaultReadinessTiming},
+	}
+	for _, k := range kinds {
+		prefix := "ACI_" + k.name + "_"
+		kind := ProbeKind{Field: k.field, Timing: k.timing}
+		timing := []struct {
+			key   string
+			value *int
+		}{
+			{"INITIAL_DELAY", &kind.Timing.InitialDelaySeconds},
+			{"PERIOD", &kind.Timing.PeriodSeconds},
+			{"FAILURE_THRESHOLD", &kind.Timing.FailureThreshold},
+			{"TIMEOUT", &kind.Timing.TimeoutSeconds},
+		}
+		for _, t := range timing {
+			if value := cfg.Get(prefix + t.key); value != "" {
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 0 {
+					errs = append(errs, &validation.FieldError{Key: prefix + t.key,
+						Message: fmt.Sprintf("expected a non-negative integer, got %s", value)})
+					continue
+				}
+				*t.value = n
+			}
+		}
+
+		path, command := cfg.Get(prefix+"PATH"), cfg.Get(prefix+"COMMAND")
+		switch {
+		case path != "" && command != "":
+			errs = append(errs, &validation.FieldError{Key: prefix + "COMMAND",
+				Message: fmt.Sprintf("set either %sPATH or %sCOMMAND, not both", prefix, prefix)})
+		case path != "":
+			if !strings.HasPrefix(path, "/") {
+				errs = append(errs, &validation.FieldError{Key: prefix + "PATH", Message: "must start with /"})
+			}
+			port := settings.Port
+			if value := cfg.Get(prefix + "PORT"); value != "" {
+				p, err := parsePort(prefix+"PORT", value)
+				if err != nil {
+					errs = append(errs, err)
+				}
+				port = p
+			}
+			kind.Probe = &Probe{HTTPGet: &HTTPProbe{Path: path, Port: port}}
+		case command != "":
+			args, err := parseCommand(command)
+			if err != nil {
+				errs = append(errs, &validation.FieldError{Key: prefix + "COMMAND", Message: err.Error()})
+			}
+			kind.Probe = &Probe{Exec: &ExecProbe{Command: args}}
+		}
+		settings.Kinds = append(settings.Kinds, kind)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return settings, nil
+}
+
+// ProbeValidation reports invalid probe settings with the checks ProbesFromConfig applies
+// when deploying
+var ProbeValidation = validation.ValidationRule{
+	Name: "ACI Probes",
+	Custom: func(cfg *config.Config) error {
+		_, err := ProbesFromConfig(cfg)
+		return err
+	},
+}
+
+// parsePort parses a TCP port setting
+func parsePort(key, value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > 65535 {
+		return 0, &validation.FieldError{Key: key, Message: fmt.Sprintf("invalid port: %s", value)}
+	}
+	return n, nil
+}
+
+// parseCommand accepts a JSON array, ["sh", "-c", "test -f /tmp/ready"], or words separated
+// by spaces
+func parseCommand(command string) ([]string, error) {
+	if !strings.HasPrefix(strings.TrimSpace(command), "[") {
+		return strings.Fields(command), nil
+	}
+	var args []string
+	if err := json.Unmarshal([]byte(command), &args); err != nil {
+		return nil, fmt.Errorf("expected a JSON array of strings: %w", err)
+	}
+	return args, nil
+}
+
+// ApplyProbes adds the configured probes to the selected containers that define none, and
+// fills the unset timing fields of every probe. It returns the document and the probes it
+// added (e.g. "livenessProbe on app"), or doc unchanged if there was nothing to do.
+func ApplyProbes(doc []byte, settings *ProbeSettings) ([]byte, []string, error) {
+	group, err := decodeGroup(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	props, _ := group["properties"].(map[string]any)
+	containers, _ := props["containers"].([]any)
+
+	modified := false
+	var added []string
+	for _, kind := range settings.Kinds {
+		selected := settings.selected(containers, kind.Probe)
+		for i, c := range containers {
+			container, _ := c.(map[string]any)
+			containerProps, _ := container["properties"].(map[string]any)
+			if containerProps == nil {
+				continue
+			}
+			name, _ := container["name"].(string)
+
+			probe, _ := containerProps[kind.Field].(map[string]any)
+			if probe == nil && kind.Probe != nil && selected[i] {
+				if probe, err = toMap(kind.Probe); err != nil {
+					return nil, nil, err
+				}
+				containerProps[kind.Field] = probe
+				added = append(added, kind.Field+" on "+name)
+				modified = true
+			}
+			if probe == nil {
+				continue
+			}
+			for key, value := range kind.Timing.timing() {
+				if _, ok := probe[key]; !ok && value > 0 {
+					probe[key] = value
+					modified = true
+				}
+			}
+		}
+	}
+	if !modified {
+		return doc, nil, nil
+	}
+	out, err := encodeGroup(group)
+	return out, added, err
+}
+
+// selected returns the indexes of the containers that receive the configured probe
+func (s *ProbeSettings) selected(containers []any, probe *Probe) map[int]bool {
+	selected := make(map[int]bool)
+	if len(s.Containers) > 0 {
+		for i, c := range containers {
+			container, _ := c.(map[string]any)
+			name, _ := container["name"].(string)
+			for _, want := range s.Containers {
+				if name == want {
+					selected[i] = true
+				}
+			}
+		}
+		return selected
+	}
+
+	port := s.Port
+	if probe != nil && probe.HTTPGet != nil {
+		port = probe.HTTPGet.Port
+	}
+	for i, c := range containers {
+		container, _ := c.(map[string]any)
+		containerProps, _ := container["properties"].(map[string]any)
+		ports, _ := containerProps["ports"].([]any)
+		for _, p := range ports {
+			portMap, _ := p.(map[string]any)
+			if f, ok := numeric(portMap["port"]); ok && int(f) == port {
+				selected[i] = true
+			}
+		}
+	}
+	if len(selected) == 0 && len(containers) > 0 {
+		selected[0] = true
+	}
+	return selected
+}
+
+// timing returns the probe's timing fields by JSON name
+func (p Probe) timing() map[string]int {
+	return map[string]int{
+		"initialDelaySeconds": p.InitialDelaySeconds,
+		"periodSeconds":       p.PeriodSeconds,
+		"failureThreshold":    p.FailureThreshold,
+		"timeoutSeconds":      p.TimeoutSeconds,
+	}
+}
+
+func toMap(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode probe: %w", err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to encode probe: %w", err)
+	}
+	return m, nil
+}
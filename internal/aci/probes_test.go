@@ -0,0 +1,136 @@
+package aci
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/furiatona/azctl/internal/config"
+	"github.com/furiatona/azctl/internal/validation"
+)
+
+func TestApplyProbes(t *testing.T) {
+	doc := `{"name":"api","location":"eastus","properties":{"osType":"Linux","containers":[
+		{"name":"app","properties":{"image":"api:v1","ports":[{"port":8080}],
+			"resources":{"requests":{"cpu":1,"memoryInGB":1}},
+			"readinessProbe":{"exec":{"command":["cat","/tmp/ready"]},"periodSeconds":20}}},
+		{"name":"fluentbit","properties":{"image":"fluent-bit:4",
+			"resources":{"requests":{"cpu":0.25,"memoryInGB":0.3}}}}]}}`
+
+	cfg := config.New()
+	settings, err := ProbesFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, added, err := ApplyProbes([]byte(validGroup), settings); err != nil || added != nil ||
+		string(out) != validGroup {
+		t.Errorf("expected no change without probes, got %v, %v", added, err)
+	}
+
+	cfg.Set("ACI_PORT", "8080")
+	cfg.Set("ACI_LIVENESS_PATH", "/healthz")
+	cfg.Set("ACI_LIVENESS_FAILURE_THRESHOLD", "5")
+	cfg.Set("ACI_READINESS_COMMAND", `["sh", "-c", "test -f /tmp/ready"]`)
+	if settings, err = ProbesFromConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	out, added, err := ApplyProbes([]byte(doc), settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(added, ", ") != "livenessProbe on app" {
+		t.Errorf("unexpected probes added: %v", added)
+	}
+	group, err := Parse(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, sidecar := group.Properties.Containers[0].Properties, group.Properties.Containers[1].Properties
+	liveness := app.LivenessProbe
+	if liveness.HTTPGet.Path != "/healthz" || liveness.HTTPGet.Port != 8080 || liveness.FailureThreshold != 5 ||
+		liveness.InitialDelaySeconds != DefaultLivenessTiming.InitialDelaySeconds {
+		t.Errorf("unexpected liveness probe: %+v", liveness)
+	}
+	// The manifest's probe wins; only its unset timing is filled in
+	readiness := app.ReadinessProbe
+	if readiness.Exec.Command[0] != "cat" || readiness.PeriodSeconds != 20 || readiness.TimeoutSeconds != 5 {
+		t.Errorf("unexpected readiness probe: %+v", readiness)
+	}
+	if sidecar.LivenessProbe != nil || sidecar.ReadinessProbe != nil {
+		t.Errorf("the sidecar does not expose ACI_PORT and must not be probed: %+v", sidecar)
+	}
+	if issues := Validate(group, DefaultLimits); len(issues) != 0 {
+		t.Errorf("expected no issues, got %v", issues)
+	}
+
+	cfg.Set("ACI_READINESS_PATH", "/ready")
+	if _, err := ProbesFromConfig(cfg); err == nil || !strings.Contains(err.Error(), "not both") {
+		t.Errorf("expected an error for a path and a command, got %v", err)
+	}
+}
+
+func TestValidateProbes(t *testing.T) {
+	group, err := Parse([]byte(validGroup))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &group.Properties.Containers[0].Properties
+	app.LivenessProbe = &Probe{HTTPGet: &HTTPProbe{Path: "healthz", Port: 9090}, PeriodSeconds: 5, TimeoutSeconds: 10}
+	app.ReadinessProbe = &Probe{Exec: &ExecProbe{}, HTTPGet: &HTTPProbe{Port: 8080}, InitialDelaySeconds: -1}
+
+	wants := []string{
+		"probed on port 9090, which it does not expose",
+		`probe path "healthz" of container "app" must start with /`,
+		"times out after 10s but runs every 5s",
+		"must set exactly one of exec and httpGet",
+		"initialDelaySeconds of container \"app\" must not be negative",
+	}
+	issues := Validate(group, DefaultLimits)
+	for _, want := range wants {
+		found := false
+		for _, issue := range issues {
+			if strings.Contains(issue.Message, want) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected issue containing %q in %v", want, issues)
+		}
+	}
+	if len(issues) != len(wants) {
+		t.Errorf("expected %d issues, got %v", len(wants), issues)
+	}
+}
+
+func TestProbeSettings(t *testing.T) {
+	cfg := config.New()
+	cfg.Set("ACI_LIVENESS_PATH", "healthz")
+	cfg.Set("ACI_LIVENESS_PERIOD", "10s")
+	cfg.Set("ACI_READINESS_PATH", "/ready")
+	cfg.Set("ACI_READINESS_COMMAND", "cat /tmp/ready")
+	cfg.Set("ACI_READINESS_PORT", "70000")
+
+	engine := validation.NewEngine()
+	engine.AddRule(ProbeValidation)
+	keys := make(map[string]bool)
+	for _, issue := range engine.Run(cfg).Issues {
+		keys[issue.Key] = true
+	}
+	wants := []string{"ACI_LIVENESS_PATH", "ACI_LIVENESS_PERIOD", "ACI_READINESS_COMMAND"}
+	for _, key := range wants {
+		if !keys[key] {
+			t.Errorf("expected an issue for %s, got %v", key, keys)
+		}
+	}
+	if keys["ACI_READINESS_PATH"] {
+		t.Error("unexpected issue for a valid probe path")
+	}
+
+	// Deploying reports the same settings
+	cfg.Set("ACI_READINESS_COMMAND", "")
+	_, err := ProbesFromConfig(cfg)
+	for _, want := range []string{"ACI_LIVENESS_PATH: must start with /", "ACI_READINESS_PORT: invalid port: 70000"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q, got %v", want, err)
+		}
+	}
+}
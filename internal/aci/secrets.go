@@ -0,0 +1,149 @@
+package aci
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SecretPlaceholder replaces secret values in stored container groups
+const SecretPlaceholder = "$secret"
+
+// StripSecrets replaces every secret value (secure environment variables, registry passwords,
+// Azure Files keys and secret volumes) with SecretPlaceholder so the group can be stored
+func StripSecrets(doc []byte) ([]byte, error) {
+	group, err := decodeGroup(doc)
+	if err != nil {
+		return nil, err
+	}
+	walkSecrets("", group, func(_ string, parent map[string]any, key string) {
+		parent[key] = SecretPlaceholder
+	})
+	return encodeGroup(group)
+}
+
+// RestoreSecrets fills the placeholders left by StripSecrets. Values come from current, the
+// container group as rendered from today's configuration, matched by container, variable,
+// registry or volume name. A secure environment variable missing from current falls back
+// to lookup by variable name.
+func RestoreSecrets(doc, current []byte, lookup func(key string) (string, bool)) ([]byte, error) {
+	group, err := decodeGroup(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	if current != nil {
+		currentGroup, err := decodeGroup(current)
+		if err != nil {
+			return nil, err
+		}
+		walkSecrets("", currentGroup, func(path string, parent map[string]any, key string) {
+			values[path] = fmt.Sprint(parent[key])
+		})
+	}
+
+	var unresolved []string
+	walkSecrets("", group, func(path string, parent map[string]any, key string) {
+		if parent[key] != SecretPlaceholder {
+			return
+		}
+		if value, ok := values[path]; ok {
+			parent[key] = value
+			return
+		}
+		if name, _ := parent["name"].(string); key == "secureValue" && name != "" && lookup != nil {
+			if value, ok := lookup(name); ok {
+				parent[key] = value
+				return
+			}
+		}
+		unresolved = append(unresolved, path)
+	})
+	if len(unresolved) > 0 {
+		sort.Strings(unresolved)
+		return nil, errors.New("no current value for secrets: " + strings.Join(unresolved, ", "))
+	}
+	return encodeGroup(group)
+}
+
+// SecureEnvironment moves the environment variables isSecret classifies as secrets from value
+// to secureValue, so Azure does not return them from `az container show`. It returns the
+// document and the names of the variables it moved, or doc unchanged if there were none.
+func SecureEnvironment(doc []byte, isSecret func(name, value string) bool) ([]byte, []string, error) {
+	group, err := decodeGroup(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	var moved []string
+	props, _ := group["properties"].(map[string]any)
+	for _, list := range []string{"containers", "initContainers"} {
+		containers, _ := props[list].([]any)
+		for _, c := range containers {
+			container, _ := c.(map[string]any)
+			containerProps, _ := container["properties"].(map[string]any)
+			vars, _ := containerProps["environmentVariables"].([]any)
+			for _, v := range vars {
+				envVar, _ := v.(map[string]any)
+				name, _ := envVar["name"].(string)
+				value, ok := envVar["value"].(string)
+				if !ok || !isSecret(name, value) {
+					continue
+				}
+				delete(envVar, "value")
+				envVar["secureValue"] = value
+				moved = append(moved, name)
+			}
+		}
+	}
+	if len(moved) == 0 {
+		return doc, nil, nil
+	}
+	out, err := encodeGroup(group)
+	return out, moved, err
+}
+
+// walkSecrets calls fn for every string secret in v with the path Diff would report for it
+func walkSecrets(path string, v any, fn func(path string, parent map[string]any, key string)) {
+	switch node := v.(type) {
+	case map[string]any:
+		for _, key := range sortedKeys(node) {
+			child := key
+			if path != "" {
+				child = path + "." + key
+			}
+			if _, ok := node[key].(string); ok && isSecretPath(child) {
+				fn(child, node, key)
+				continue
+			}
+			walkSecrets(child, node[key], fn)
+		}
+	case []any:
+		id := identity(node)
+		for i, item := range node {
+			key := fmt.Sprint(i)
+			if id != nil {
+				key = id(item)
+			}
+			walkSecrets(fmt.Sprintf("%s[%s]", path, key), item, fn)
+		}
+	}
+}
+
+func decodeGroup(doc []byte) (map[string]any, error) {
+	var group map[string]any
+	if err := json.Unmarshal(doc, &group); err != nil {
+		return nil, fmt.Errorf("invalid container group: %w", err)
+	}
+	return group, nil
+}
+
+func encodeGroup(group map[string]any) ([]byte, error) {
+	out, err := json.MarshalIndent(group, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode container group: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,93 @@
+package aci
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStripAndRestoreSecrets(t *testing.T) {
+	deployed := `{"name":"api","properties":{
+		"containers":[{"name":"app","properties":{"image":"api:v1","environmentVariables":[
+			{"name":"LOG_LEVEL","value":"info"},
+			{"name":"DB_PASSWORD","secureValue":"old"},
+			{"name":"API_TOKEN","secureValue":"old-token"}]}}],
+		"imageRegistryCredentials":[{"server":"reg.azurecr.io","username":"reg","password":"old-pw"}]}}`
+
+	stripped, err := StripSecrets([]byte(deployed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"old", "old-token", "old-pw"} {
+		if strings.Contains(string(stripped), `"`+secret+`"`) {
+			t.Errorf("secret %q left in stored group: %s", secret, stripped)
+		}
+	}
+	if !strings.Contains(string(stripped), `"info"`) {
+		t.Errorf("plain values must be kept: %s", stripped)
+	}
+
+	// Today's render has a rotated password and no longer defines API_TOKEN
+	current := `{"name":"api","properties":{
+		"containers":[{"name":"app","properties":{"image":"api:v2","environmentVariables":[
+			{"name":"DB_PASSWORD","secureValue":"new"}]}}],
+		"imageRegistryCredentials":[{"server":"reg.azurecr.io","username":"reg","password":"new-pw"}]}}`
+	lookup := func(key string) (string, bool) {
+		if key == "API_TOKEN" {
+			return "config-token", true
+		}
+		return "", false
+	}
+	restored, err := RestoreSecrets(stripped, []byte(current), lookup)
+	if err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	group, err := Parse(restored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := group.Properties.Containers[0].Properties.EnvironmentVariables
+	if env[1].SecureValue != "new" || env[2].SecureValue != "config-token" {
+		t.Errorf("unexpected secrets: %+v", env)
+	}
+	if group.Properties.ImageRegistryCredentials[0].Password != "new-pw" {
+		t.Errorf("registry password not restored: %+v", group.Properties.ImageRegistryCredentials)
+	}
+	if group.Properties.Containers[0].Properties.Image != "api:v1" {
+		t.Errorf("the stored image must be kept, got %s", group.Properties.Containers[0].Properties.Image)
+	}
+
+	_, err = RestoreSecrets(stripped, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "environmentVariables[DB_PASSWORD].secureValue") {
+		t.Errorf("expected unresolved secrets error, got %v", err)
+	}
+}
+
+func TestSecureEnvironment(t *testing.T) {
+	doc := `{"name":"api","properties":{"containers":[{"name":"app","properties":{"environmentVariables":[
+		{"name":"LOG_LEVEL","value":"info"},
+		{"name":"SUPABASE_KEY","value":"service-role"},
+		{"name":"DB_PASSWORD","secureValue":"already"}]}}]}}`
+	isSecret := func(name, _ string) bool { return strings.HasSuffix(name, "_KEY") || name == "DB_PASSWORD" }
+
+	out, moved, err := SecureEnvironment([]byte(doc), isSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(moved, ",") != "SUPABASE_KEY" {
+		t.Errorf("moved = %v", moved)
+	}
+	group, err := Parse(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := group.Properties.Containers[0].Properties.EnvironmentVariables
+	if vars[0].Value != "info" || vars[1].Value != "" || vars[1].SecureValue != "service-role" ||
+		vars[2].SecureValue != "already" {
+		t.Errorf("unexpected variables: %+v", vars)
+	}
+
+	none := func(string, string) bool { return false }
+	if same, moved, _ := SecureEnvironment([]byte(doc), none); string(same) != doc || moved != nil {
+		t.Error("expected the document to be returned unchanged")
+	}
+}
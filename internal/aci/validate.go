@@ -0,0 +1,266 @@
+package aci
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/furiatona/azctl/internal/config"
+	"github.com/furiatona/azctl/internal/validation"
+)
+
+// RuleName is reported as the rule for every container group issue
+const RuleName = "ACI Container Group"
+
+// Limits are the maximum resources a single container group may request
+type Limits struct {
+	CPU        float64
+	MemoryInGB float64
+}
+
+// DefaultLimits are the standard per-group limits for Linux container groups in most regions.
+// Regions with lower or higher quotas can be described with ACI_MAX_CPU and ACI_MAX_MEMORY.
+var DefaultLimits = Limits{CPU: 4, MemoryInGB: 16}
+
+// LimitsFromConfig returns DefaultLimits overridden by ACI_MAX_CPU and ACI_MAX_MEMORY
+func LimitsFromConfig(cfg *config.Config) Limits {
+	limits := DefaultLimits
+	if v, err := strconv.ParseFloat(cfg.Get("ACI_MAX_CPU"), 64); err == nil && v > 0 {
+		limits.CPU = v
+	}
+	if v, err := strconv.ParseFloat(cfg.Get("ACI_MAX_MEMORY"), 64); err == nil && v > 0 {
+		limits.MemoryInGB = v
+	}
+	return limits
+}
+
+// Validate checks a container group for problems Azure would otherwise only report at
+// deploy time. Each issue's Key is the JSON path of the offending field.
+func Validate(group *ContainerGroup, limits Limits) []validation.Issue {
+	v := &validator{}
+	props := group.Properties
+
+	if group.Type != "" && !strings.EqualFold(group.Type, ResourceType) {
+		v.errorf("type", "expected %s, got %s", ResourceType, group.Type)
+	}
+	if group.Name == "" {
+		v.errorf("name", "container group name is empty")
+	}
+	if group.Location == "" {
+		v.errorf("location", "location is empty")
+	}
+	if len(props.Containers) == 0 {
+		v.errorf("properties.containers", "container group defines no containers")
+	}
+
+	volumes := make(map[string]bool, len(props.Volumes))
+	for i, volume := range props.Volumes {
+		if volumes[volume.Name] {
+			v.errorf(fmt.Sprintf("properties.volumes[%d].name", i), "duplicate volume name %q", volume.Name)
+		}
+		volumes[volume.Name] = true
+	}
+
+	names := make(map[string]bool, len(props.Containers))
+	exposed := make(map[string]bool)
+	mounted := make(map[string]bool)
+	var totalCPU, totalMemory float64
+	for i, container := range props.Containers {
+		path := fmt.Sprintf("properties.containers[%d]", i)
+		if container.Name == "" {
+			v.errorf(path+".name", "container name is empty")
+		} else if names[container.Name] {
+			v.errorf(path+".name", "duplicate container name %q", container.Name)
+		}
+		names[container.Name] = true
+
+		if container.Properties.Image == "" {
+			v.errorf(path+".properties.image", "container %q has no image", container.Name)
+		}
+
+		requests := container.Properties.Resources.Requests
+		if requests.CPU <= 0 {
+			v.errorf(path+".properties.resources.requests.cpu", "container %q must request CPU", container.Name)
+		}
+		if requests.MemoryInGB <= 0 {
+			v.errorf(path+".properties.resources.requests.memoryInGB",
+				"container %q must request memory", container.Name)
+		}
+		totalCPU += float64(requests.CPU)
+		totalMemory += float64(requests.MemoryInGB)
+
+		for _, port := range container.Properties.Ports {
+			exposed[portKey(port)] = true
+		}
+
+		for j, mount := range container.Properties.VolumeMounts {
+			mounted[mount.Name] = true
+			if !volumes[mount.Name] {
+				v.errorf(fmt.Sprintf("%s.properties.volumeMounts[%d].name", path, j),
+					"container %q mounts undefined volume %q", container.Name, mount.Name)
+			}
+		}
+
+		probes := []struct {
+			field string
+			probe *Probe
+		}{
+			{"livenessProbe", container.Properties.LivenessProbe},
+			{"readinessProbe", container.Properties.ReadinessProbe},
+		}
+		for _, p := range probes {
+			if p.probe != nil {
+				v.probe(path+".properties."+p.field, container, p.probe)
+			}
+		}
+
+		seenVars := make(map[string]bool)
+		for j, envVar := range container.Properties.EnvironmentVariables {
+			envPath := fmt.Sprintf("%s.properties.environmentVariables[%d]", path, j)
+			if seenVars[envVar.Name] {
+				v.warnf(envPath+".name", "container %q sets %s more than once", container.Name, envVar.Name)
+			}
+			seenVars[envVar.Name] = true
+			if envVar.Value == "" && envVar.SecureValue == "" {
+				v.errorf(envPath, "environment variable %s in container %q has an empty value",
+					envVar.Name, container.Name)
+			}
+		}
+	}
+
+	if totalCPU > limits.CPU {
+		v.errorf("properties.containers", "total CPU request %.2f exceeds the limit of %.2f", totalCPU, limits.CPU)
+	}
+	if totalMemory > limits.MemoryInGB {
+		v.errorf("properties.containers", "total memory request %.2f GB exceeds the limit of %.2f GB",
+			totalMemory, limits.MemoryInGB)
+	}
+
+	private := props.IPAddress != nil && strings.EqualFold(props.IPAddress.Type, "Private")
+	switch {
+	case private && props.IPAddress.DNSNameLabel != "":
+		v.errorf("properties.ipAddress.dnsNameLabel", "a private IP address cannot have a DNS name label")
+	case private && len(props.SubnetIDs) == 0:
+		v.errorf("properties.subnetIds", "a private IP address requires a subnet")
+	case !private && len(props.SubnetIDs) > 0 && props.IPAddress != nil:
+		v.errorf("properties.ipAddress.type", "a group in a subnet can only have a private IP address")
+	}
+	if props.DNSConfig != nil && len(props.SubnetIDs) == 0 {
+		v.errorf("properties.dnsConfig", "DNS configuration requires the group to be in a subnet")
+	}
+
+	if ip := props.IPAddress; ip != nil {
+		for i, port := range ip.Ports {
+			if !exposed[portKey(port)] {
+				v.errorf(fmt.Sprintf("properties.ipAddress.ports[%d]", i),
+					"port %d/%s is exposed on the group IP but no container exposes it", port.Port, protocol(port))
+			}
+		}
+	}
+
+	for i, credential := range props.ImageRegistryCredentials {
+		path := fmt.Sprintf("properties.imageRegistryCredentials[%d]", i)
+		switch {
+		case credential.Identity != "":
+			if group.Identity == nil || !hasIdentity(group.Identity.UserAssignedIdentities, credential.Identity) {
+				v.errorf(path+".identity", "registry %s is pulled with identity %s, which is not assigned to the group",
+					credential.Server, credential.Identity)
+			}
+		case credential.Username == "" || credential.Password == "":
+			v.errorf(path, "registry %s needs a username and password, or a managed identity", credential.Server)
+		}
+	}
+
+	for i, volume := range props.Volumes {
+		if !mounted[volume.Name] {
+			v.warnf(fmt.Sprintf("properties.volumes[%d]", i), "volume %q is not mounted by any container", volume.Name)
+		}
+	}
+
+	return v.issues
+}
+
+// probe checks a liveness or readiness probe of container
+func (v *validator) probe(path string, container Container, p *Probe) {
+	switch {
+	case (p.Exec == nil) == (p.HTTPGet == nil):
+		v.errorf(path, "probe of container %q must set exactly one of exec and httpGet", container.Name)
+	case p.Exec != nil && len(p.Exec.Command) == 0:
+		v.errorf(path+".exec.command", "exec probe of container %q has no command", container.Name)
+	case p.HTTPGet != nil:
+		get := p.HTTPGet
+		if get.Port < 1 || get.Port > 65535 {
+			v.errorf(path+".httpGet.port", "probe port %d of container %q is out of range", get.Port, container.Name)
+		} else if !slices.ContainsFunc(container.Properties.Ports, func(port Port) bool { return port.Port == get.Port }) {
+			v.warnf(path+".httpGet.port", "container %q is probed on port %d, which it does not expose",
+				container.Name, get.Port)
+		}
+		if get.Path != "" && !strings.HasPrefix(get.Path, "/") {
+			v.errorf(path+".httpGet.path", "probe path %q of container %q must start with /", get.Path, container.Name)
+		}
+	}
+
+	timing := []struct {
+		field string
+		value int
+	}{
+		{"initialDelaySeconds", p.InitialDelaySeconds},
+		{"periodSeconds", p.PeriodSeconds},
+		{"failureThreshold", p.FailureThreshold},
+		{"successThreshold", p.SuccessThreshold},
+		{"timeoutSeconds", p.TimeoutSeconds},
+	}
+	for _, t := range timing {
+		if t.value < 0 {
+			v.errorf(path+"."+t.field, "%s of container %q must not be negative", t.field, container.Name)
+		}
+	}
+	if p.PeriodSeconds > 0 && p.TimeoutSeconds > p.PeriodSeconds {
+		v.warnf(path+".timeoutSeconds", "probe of container %q times out after %ds but runs every %ds",
+			container.Name, p.TimeoutSeconds, p.PeriodSeconds)
+	}
+}
+
+type validator struct {
+	issues []validation.Issue
+}
+
+func (v *validator) errorf(key, format string, args ...any) {
+	v.add(validation.SeverityError, key, format, args...)
+}
+
+func (v *validator) warnf(key, format string, args ...any) {
+	v.add(validation.SeverityWarning, key, format, args...)
+}
+
+func (v *validator) add(severity validation.Severity, key, format string, args ...any) {
+	v.issues = append(v.issues, validation.Issue{
+		Rule:     RuleName,
+		Key:      key,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// hasIdentity reports whether id is among the assigned identities; ARM resource IDs are
+// case-insensitive
+func hasIdentity(assigned map[string]map[string]any, id string) bool {
+	for key := range assigned {
+		if strings.EqualFold(key, id) {
+			return true
+		}
+	}
+	return false
+}
+
+func protocol(p Port) string {
+	if p.Protocol == "" {
+		return "TCP"
+	}
+	return strings.ToUpper(p.Protocol)
+}
+
+func portKey(p Port) string {
+	return fmt.Sprintf("%d/%s", p.Port, protocol(p))
+}
@@ -0,0 +1,99 @@
+package aci
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/furiatona/azctl/internal/validation"
+)
+
+const validGroup = `{
+  "name": "app",
+  "location": "eastus",
+  "type": "Microsoft.ContainerInstance/containerGroups",
+  "properties": {
+    "osType": "Linux",
+    "ipAddress": {"type": "Public", "ports": [{"protocol": "TCP", "port": 8080}]},
+    "containers": [
+      {"name": "app", "properties": {
+        "image": "reg.azurecr.io/app:1",
+        "resources": {"requests": {"cpu": 1, "memoryInGB": "2"}},
+        "ports": [{"port": 8080}],
+        "environmentVariables": [{"name": "KEY", "secureValue": "s3cret"}],
+        "volumeMounts": [{"name": "logs", "mountPath": "/var/log/app"}]
+      }}
+    ],
+    "volumes": [{"name": "logs", "emptyDir": {}}]
+  }
+}`
+
+func TestValidateValidGroup(t *testing.T) {
+	group, err := Parse([]byte(validGroup))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if issues := Validate(group, DefaultLimits); len(issues) != 0 {
+		t.Errorf("expected no issues, got %v", issues)
+	}
+}
+
+func TestValidateReportsSemanticProblems(t *testing.T) {
+	group, err := Parse([]byte(validGroup))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	group.Properties.IPAddress.Ports = append(group.Properties.IPAddress.Ports, Port{Port: 443})
+	sidecar := group.Properties.Containers[0]
+	sidecar.Properties.Ports = nil
+	sidecar.Properties.Resources.Requests = ResourceSpec{CPU: 3.5, MemoryInGB: 15}
+	sidecar.Properties.VolumeMounts = []VolumeMount{{Name: "missing", MountPath: "/x"}}
+	sidecar.Properties.EnvironmentVariables = []EnvironmentVariable{{Name: "EMPTY"}}
+	group.Properties.Containers = append(group.Properties.Containers, sidecar)
+
+	issues := Validate(group, DefaultLimits)
+
+	wants := []string{
+		"duplicate container name",
+		"port 443/TCP is exposed on the group IP",
+		"total CPU request 4.50 exceeds",
+		"total memory request 17.00 GB exceeds",
+		`mounts undefined volume "missing"`,
+		"EMPTY in container \"app\" has an empty value",
+	}
+	for _, want := range wants {
+		found := false
+		for _, issue := range issues {
+			if strings.Contains(issue.Message, want) && issue.Severity == validation.SeverityError {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("expected error containing %q in %v", want, issues)
+		}
+	}
+}
+
+func TestValidateUnmountedVolumeIsWarning(t *testing.T) {
+	group, err := Parse([]byte(validGroup))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	group.Properties.Volumes = append(group.Properties.Volumes, Volume{Name: "unused"})
+
+	issues := Validate(group, DefaultLimits)
+	if len(issues) != 1 || issues[0].Severity != validation.SeverityWarning {
+		t.Errorf("expected one warning, got %v", issues)
+	}
+	if issues[0].Key != "properties.volumes[1]" {
+		t.Errorf("unexpected key %q", issues[0].Key)
+	}
+}
+
+func TestParseRejectsNonNumericQuantity(t *testing.T) {
+	doc := strings.Replace(validGroup, `"cpu": 1`, `"cpu": "one"`, 1)
+	if _, err := Parse([]byte(doc)); err == nil {
+		t.Error("expected error for non-numeric cpu")
+	}
+}
@@ -0,0 +1,288 @@
+// Package arm wraps a rendered container group in an ARM deployment template so it can be
+// deployed with `az deployment group create` (deployment history, what-if previews) and
+// passes secrets as securestring parameters instead of inlining them.
+package arm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/furiatona/azctl/internal/aci"
+	"github.com/furiatona/azctl/internal/logging"
+	"github.com/furiatona/azctl/internal/runx"
+)
+
+// Schema URLs for deployment templates and parameter files
+const (
+	TemplateSchema   = "https://schema.management.azure.com/schemas/2019-04-01/deploymentTemplate.json#"
+	ParametersSchema = "https://schema.management.azure.com/schemas/2019-04-01/deploymentParameters.json#"
+)
+
+// DefaultAPIVersion is used when the container group does not specify one
+const DefaultAPIVersion = "2023-05-01"
+
+// Deployment is an ARM deployment of a single container group
+type Deployment struct {
+	// Name is the deployment name shown in the resource group's deployment history
+	Name string
+	// Template is the deployment template; secrets are parameter references
+	Template []byte
+	// Parameters holds the securestring parameter values, keyed by parameter name
+	Parameters map[string]string
+}
+
+// Build wraps a container group document in a deployment template. Environment variable
+// secureValues, registry passwords, Azure Files account keys and secret volume entries
+// become securestring parameters. Other strings starting with [ are escaped so ARM does not
+// evaluate them as expressions.
+func Build(containerGroup []byte) (*Deployment, error) {
+	var resource map[string]any
+	if err := json.Unmarshal(containerGroup, &resource); err != nil {
+		return nil, fmt.Errorf("invalid container group: %w", err)
+	}
+	name, _ := resource["name"].(string)
+	if name == "" {
+		return nil, fmt.Errorf("container group has no name")
+	}
+	resource["type"] = aci.ResourceType
+	if v, _ := resource["apiVersion"].(string); v == "" {
+		resource["apiVersion"] = DefaultAPIVersion
+	}
+
+	escapeLiterals(resource)
+	b := &builder{params: make(map[string]string)}
+	if props, ok := resource["properties"].(map[string]any); ok {
+		b.extract(props)
+	}
+
+	parameters := make(map[string]any, len(b.params))
+	for param := range b.params {
+		parameters[param] = map[string]string{"type": "securestring"}
+	}
+	template, err := json.MarshalIndent(map[string]any{
+		"$schema":        TemplateSchema,
+		"contentVersion": "1.0.0.0",
+		"parameters":     parameters,
+		"resources":      []any{resource},
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode deployment template: %w", err)
+	}
+
+	return &Deployment{
+		Name:       deploymentName(name, time.Now()),
+		Template:   template,
+		Parameters: b.params,
+	}, nil
+}
+
+// ParametersFile returns the deployment parameters file holding the secret values
+func (d *Deployment) ParametersFile() ([]byte, error) {
+	values := make(map[string]any, len(d.Parameters))
+	for name, value := range d.Parameters {
+		values[name] = map[string]string{"value": value}
+	}
+	data, err := json.MarshalIndent(map[string]any{
+		"$schema":        ParametersSchema,
+		"contentVersion": "1.0.0.0",
+		"parameters":     values,
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode deployment parameters: %w", err)
+	}
+	return data, nil
+}
+
+// ParameterNames returns the securestring parameter names in sorted order
+func (d *Deployment) ParameterNames() []string {
+	names := make([]string, 0, len(d.Parameters))
+	for name := range d.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Deploy runs `az deployment group create` for the deployment
+func Deploy(ctx context.Context, resourceGroup string, d *Deployment) error {
+	return withFiles(d, func(templateFile, parametersFile string) error {
+		if err := runx.AZ(ctx, "deployment", "group", "create",
+			"--resource-group", resourceGroup,
+			"--name", d.Name,
+			"--template-file", templateFile,
+			"--parameters", "@"+parametersFile); err != nil {
+			return fmt.Errorf("ARM deployment %s failed: %w", d.Name, err)
+		}
+		return nil
+	})
+}
+
+// WhatIf previews the deployment with `az deployment group what-if`
+func WhatIf(ctx context.Context, resourceGroup string, d *Deployment) (*WhatIfResult, error) {
+	var result *WhatIfResult
+	err := withFiles(d, func(templateFile, parametersFile string) error {
+		out, err := runx.AZOutput(ctx, "deployment", "group", "what-if",
+			"--resource-group", resourceGroup,
+			"--name", d.Name,
+			"--template-file", templateFile,
+			"--parameters", "@"+parametersFile,
+			"--no-pretty-print", "--output", "json")
+		if err != nil {
+			return fmt.Errorf("failed to run what-if: %w", err)
+		}
+		result, err = ParseWhatIf([]byte(out))
+		return err
+	})
+	return result, err
+}
+
+// withFiles writes the template and parameters to private temp files for the az CLI
+func withFiles(d *Deployment, fn func(templateFile, parametersFile string) error) error {
+	parameters, err := d.ParametersFile()
+	if err != nil {
+		return err
+	}
+
+	var paths []string
+	defer func() {
+		for _, path := range paths {
+			if err := os.Remove(path); err != nil {
+				logging.Warnf("failed to remove temp file %s: %v", path, err)
+			}
+		}
+	}()
+	for _, content := range [][]byte{d.Template, parameters} {
+		f, err := os.CreateTemp("", "aci-arm-*.json") // CreateTemp uses 0600
+		if err != nil {
+			return fmt.Errorf("failed to create temp file: %w", err)
+		}
+		paths = append(paths, f.Name())
+		if _, err := f.Write(content); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("failed to write to temp file: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to close temp file: %w", err)
+		}
+	}
+	return fn(paths[0], paths[1])
+}
+
+type builder struct {
+	params map[string]string
+}
+
+// extract replaces secret values in the group properties with parameter references
+func (b *builder) extract(props map[string]any) {
+	for _, key := range []string{"containers", "initContainers"} {
+		for _, container := range objects(props[key]) {
+			containerName, _ := container["name"].(string)
+			containerProps, _ := container["properties"].(map[string]any)
+			for _, envVar := range objects(containerProps["environmentVariables"]) {
+				varName, _ := envVar["name"].(string)
+				b.secure(envVar, "secureValue", "env_"+containerName+"_"+varName)
+			}
+		}
+	}
+	for _, credential := range objects(props["imageRegistryCredentials"]) {
+		server, _ := credential["server"].(string)
+		b.secure(credential, "password", "registryPassword_"+server)
+	}
+	for _, volume := range objects(props["volumes"]) {
+		volumeName, _ := volume["name"].(string)
+		if azureFile, ok := volume["azureFile"].(map[string]any); ok {
+			b.secure(azureFile, "storageAccountKey", "storageAccountKey_"+volumeName)
+		}
+		if secret, ok := volume["secret"].(map[string]any); ok {
+			for file := range secret {
+				b.secure(secret, file, "secretVolume_"+volumeName+"_"+file)
+			}
+		}
+	}
+}
+
+var invalidParamChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// secure moves obj[field] into a parameter and references it from the template
+func (b *builder) secure(obj map[string]any, field, param string) {
+	value, ok := obj[field].(string)
+	if !ok || value == "" {
+		return
+	}
+	// Parameter values are never evaluated, so they keep their original text
+	value = unescape(value)
+	param = invalidParamChars.ReplaceAllString(param, "_")
+	for base, i := param, 2; ; i++ {
+		if existing, taken := b.params[param]; !taken || existing == value {
+			break
+		}
+		param = fmt.Sprintf("%s_%d", base, i)
+	}
+	b.params[param] = value
+	obj[field] = fmt.Sprintf("[parameters('%s')]", param)
+}
+
+// escapeLiterals escapes every string in v that ARM would evaluate as a template expression.
+// A value starting with [ (a JSON array in an env var, a shell test in a command) is only kept
+// literally when written as [[.
+func escapeLiterals(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if s, ok := item.(string); ok {
+				v[key] = escape(s)
+			} else {
+				escapeLiterals(item)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			if s, ok := item.(string); ok {
+				v[i] = escape(s)
+			} else {
+				escapeLiterals(item)
+			}
+		}
+	}
+}
+
+func escape(s string) string {
+	if strings.HasPrefix(s, "[") {
+		return "[" + s
+	}
+	return s
+}
+
+func unescape(s string) string {
+	if strings.HasPrefix(s, "[[") {
+		return s[1:]
+	}
+	return s
+}
+
+func objects(v any) []map[string]any {
+	items, _ := v.([]any)
+	result := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		if obj, ok := item.(map[string]any); ok {
+			result = append(result, obj)
+		}
+	}
+	return result
+}
+
+// deploymentName returns a unique, valid (at most 64 characters) deployment name
+func deploymentName(group string, at time.Time) string {
+	suffix := at.UTC().Format("20060102-150405")
+	name := invalidParamChars.ReplaceAllString(group, "-")
+	if limit := 64 - len("azctl--") - len(suffix); len(name) > limit {
+		name = name[:limit]
+	}
+	return fmt.Sprintf("azctl-%s-%s", name, suffix)
+}
@@ -0,0 +1,173 @@
+package arm
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/furiatona/azctl/internal/runx"
+)
+
+const containerGroup = `{
+  "name": "api",
+  "location": "westeurope",
+  "properties": {
+    "osType": "Linux",
+    "containers": [{"name": "app", "properties": {
+      "image": "registry.azurecr.io/api:v1",
+      "environmentVariables": [
+        {"name": "SUPABASE_URL", "value": "https://db.example.com"},
+        {"name": "SUPABASE_KEY", "secureValue": "super-secret"}
+      ]}}],
+    "volumes": [{"name": "logs", "azureFile": {"shareName": "logs", "storageAccountName": "acct",
+      "storageAccountKey": "account-key"}}],
+    "imageRegistryCredentials": [{"server": "registry.azurecr.io", "username": "u", "password": "registry-pw"}]
+  }
+}`
+
+func TestBuild(t *testing.T) {
+	d, err := Build([]byte(containerGroup))
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+
+	for _, secret := range []string{"super-secret", "account-key", "registry-pw"} {
+		if strings.Contains(string(d.Template), secret) {
+			t.Errorf("template contains secret %q", secret)
+		}
+	}
+	want := []string{"env_app_SUPABASE_KEY", "registryPassword_registry_azurecr_io", "storageAccountKey_logs"}
+	if got := d.ParameterNames(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("parameters: got %v want %v", got, want)
+	}
+
+	var template struct {
+		Parameters map[string]struct {
+			Type string `json:"type"`
+		} `json:"parameters"`
+		Resources []map[string]any `json:"resources"`
+	}
+	if err := json.Unmarshal(d.Template, &template); err != nil {
+		t.Fatal(err)
+	}
+	if template.Parameters["env_app_SUPABASE_KEY"].Type != "securestring" {
+		t.Errorf("expected securestring parameter, got %+v", template.Parameters)
+	}
+	resource := template.Resources[0]
+	if resource["type"] != "Microsoft.ContainerInstance/containerGroups" || resource["apiVersion"] != DefaultAPIVersion {
+		t.Errorf("unexpected resource header: %v %v", resource["type"], resource["apiVersion"])
+	}
+	if !strings.Contains(string(d.Template), `"secureValue": "[parameters('env_app_SUPABASE_KEY')]"`) {
+		t.Errorf("secureValue not replaced by a parameter reference:\n%s", d.Template)
+	}
+
+	params, err := d.ParametersFile()
+	if err != nil || !strings.Contains(string(params), `"value": "super-secret"`) {
+		t.Errorf("parameters file missing secret value: %s (err %v)", params, err)
+	}
+
+	if _, err := Build([]byte(`{"location":"westeurope"}`)); err == nil {
+		t.Error("expected error for a group without a name")
+	}
+}
+
+func TestBuildEscapesLiterals(t *testing.T) {
+	group := `{"name":"api","properties":{"containers":[{"name":"app","properties":{
+		"command":["sh","-c","[ -f /tmp/ready ] || exit 1"],
+		"environmentVariables":[{"name":"PORTS","value":"[8080,8081]"},{"name":"TOKEN","secureValue":"[abc"}]}}]}}`
+	d, err := Build([]byte(group))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"[[ -f /tmp/ready ] || exit 1"`, `"value": "[[8080,8081]"`,
+		`"secureValue": "[parameters('env_app_TOKEN')]"`} {
+		if !strings.Contains(string(d.Template), want) {
+			t.Errorf("template missing %s:\n%s", want, d.Template)
+		}
+	}
+	if d.Parameters["env_app_TOKEN"] != "[abc" {
+		t.Errorf("parameter values must not be escaped, got %q", d.Parameters["env_app_TOKEN"])
+	}
+}
+
+func TestDeploymentName(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	if got := deploymentName("api.v2", at); got != "azctl-api-v2-20240501-123000" {
+		t.Errorf("got %s", got)
+	}
+	if got := deploymentName(strings.Repeat("a", 80), at); len(got) != 64 {
+		t.Errorf("name not truncated to 64 characters: %d", len(got))
+	}
+}
+
+const whatIfOutput = `{
+  "status": "Succeeded",
+  "changes": [
+    {
+      "resourceId": "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerInstance/containerGroups/api",
+      "changeType": "Modify",
+      "delta": [
+        {"path": "properties.containers[0].properties.image", "propertyChangeType": "Modify",
+         "before": "registry.azurecr.io/api:v1", "after": "registry.azurecr.io/api:v2"},
+        {"path": "tags.env", "propertyChangeType": "Create", "after": "prod"}
+      ]
+    }
+  ]
+}`
+
+func TestDeployAndWhatIf(t *testing.T) {
+	fake := runx.NewFake().
+		On("deployment group what-if", whatIfOutput, nil).
+		On("deployment group create", "", nil)
+	defer runx.SetExecutor(fake)()
+
+	d, err := Build([]byte(containerGroup))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := WhatIf(context.Background(), "rg", d)
+	if err != nil {
+		t.Fatalf("what-if failed: %v", err)
+	}
+	if !result.HasChanges() {
+		t.Error("expected changes")
+	}
+
+	var plan strings.Builder
+	if err := result.Write(&plan); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"~ Microsoft.ContainerInstance/containerGroups/api (Modify)",
+		`~ properties.containers[0].properties.image: "registry.azurecr.io/api:v1" => "registry.azurecr.io/api:v2"`,
+		`+ tags.env: "prod"`,
+		"Resource changes: 0 to create, 1 to modify, 0 to delete, 0 unchanged.",
+	} {
+		if !strings.Contains(plan.String(), want) {
+			t.Errorf("plan missing %q:\n%s", want, plan.String())
+		}
+	}
+
+	if err := Deploy(context.Background(), "rg", d); err != nil {
+		t.Fatalf("deploy failed: %v", err)
+	}
+	calls := fake.Calls()
+	deploy := calls[len(calls)-1]
+	if !strings.HasPrefix(deploy, "deployment group create --resource-group rg --name "+d.Name) ||
+		!strings.Contains(deploy, "--parameters @") {
+		t.Errorf("unexpected deploy call: %s", deploy)
+	}
+	if strings.Contains(deploy, "super-secret") {
+		t.Errorf("secret passed on the command line: %s", deploy)
+	}
+}
+
+func TestParseWhatIfError(t *testing.T) {
+	_, err := ParseWhatIf([]byte(`{"status":"Failed","error":{"code":"InvalidTemplate","message":"bad"}}`))
+	if err == nil || !strings.Contains(err.Error(), "InvalidTemplate") {
+		t.Errorf("expected what-if error, got %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package arm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WhatIfResult is the JSON output of `az deployment group what-if --no-pretty-print`
+type WhatIfResult struct {
+	Status  string           `json:"status"`
+	Error   *WhatIfError     `json:"error,omitempty"`
+	Changes []ResourceChange `json:"changes"`
+}
+
+// WhatIfError is returned when the preview itself failed
+type WhatIfError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ResourceChange is the predicted change to one resource
+type ResourceChange struct {
+	ResourceID string           `json:"resourceId"`
+	ChangeType string           `json:"changeType"`
+	Delta      []PropertyChange `json:"delta"`
+}
+
+// PropertyChange is the predicted change to one property of a modified resource
+type PropertyChange struct {
+	Path               string           `json:"path"`
+	PropertyChangeType string           `json:"propertyChangeType"`
+	Before             any              `json:"before"`
+	After              any              `json:"after"`
+	Children           []PropertyChange `json:"children"`
+}
+
+// Resource and property change types reported by what-if
+const (
+	ChangeCreate   = "Create"
+	ChangeDelete   = "Delete"
+	ChangeModify   = "Modify"
+	ChangeDeploy   = "Deploy"
+	ChangeNoChange = "NoChange"
+	ChangeIgnore   = "Ignore"
+	ChangeArray    = "Array"
+)
+
+var changeSymbols = map[string]string{
+	ChangeCreate:   "+",
+	ChangeDelete:   "-",
+	ChangeModify:   "~",
+	ChangeDeploy:   "!",
+	ChangeNoChange: "=",
+	ChangeIgnore:   "*",
+	ChangeArray:    "~",
+}
+
+// ParseWhatIf decodes what-if output, returning an error if the preview failed
+func ParseWhatIf(data []byte) (*WhatIfResult, error) {
+	var result WhatIfResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse what-if output: %w", err)
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("what-if failed: %s: %s", result.Error.Code, result.Error.Message)
+	}
+	return &result, nil
+}
+
+// HasChanges reports whether any resource would be created, modified or deleted
+func (r *WhatIfResult) HasChanges() bool {
+	for _, change := range r.Changes {
+		switch change.ChangeType {
+		case ChangeCreate, ChangeDelete, ChangeModify, ChangeDeploy:
+			return true
+		}
+	}
+	return false
+}
+
+// Write prints a readable plan, e.g.
+//
+//	~ Microsoft.ContainerInstance/containerGroups/api (Modify)
+//	    ~ properties.containers[0].properties.image: "api:v1" => "api:v2"
+//
+//	Resource changes: 0 to create, 1 to modify, 0 to delete, 0 unchanged.
+func (r *WhatIfResult) Write(w io.Writer) error {
+	var b strings.Builder
+	counts := make(map[string]int)
+	for _, change := range r.Changes {
+		counts[change.ChangeType]++
+		fmt.Fprintf(&b, "%s %s (%s)\n", symbol(change.ChangeType), shortResourceID(change.ResourceID), change.ChangeType)
+		writeDelta(&b, change.Delta, "    ")
+	}
+	if len(r.Changes) > 0 {
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "Resource changes: %d to create, %d to modify, %d to delete, %d unchanged.\n",
+		counts[ChangeCreate], counts[ChangeModify]+counts[ChangeDeploy], counts[ChangeDelete],
+		counts[ChangeNoChange]+counts[ChangeIgnore])
+
+	_, err := io.WriteString(w, b.String())
+	return err //nolint:wrapcheck // writer errors need no extra context
+}
+
+func writeDelta(b *strings.Builder, delta []PropertyChange, indent string) {
+	for _, change := range delta {
+		prefix := fmt.Sprintf("%s%s %s", indent, symbol(change.PropertyChangeType), change.Path)
+		switch change.PropertyChangeType {
+		case ChangeCreate:
+			fmt.Fprintf(b, "%s: %s\n", prefix, formatValue(change.After))
+		case ChangeDelete:
+			fmt.Fprintf(b, "%s: %s\n", prefix, formatValue(change.Before))
+		case ChangeModify:
+			fmt.Fprintf(b, "%s: %s => %s\n", prefix, formatValue(change.Before), formatValue(change.After))
+		default:
+			fmt.Fprintf(b, "%s:\n", prefix)
+		}
+		writeDelta(b, change.Children, indent+"    ")
+	}
+}
+
+func symbol(changeType string) string {
+	if s, ok := changeSymbols[changeType]; ok {
+		return s
+	}
+	return "?"
+}
+
+// shortResourceID turns a full resource ID into provider/type/name
+func shortResourceID(id string) string {
+	if i := strings.Index(id, "/providers/"); i >= 0 {
+		return id[i+len("/providers/"):]
+	}
+	return id
+}
+
+func formatValue(v any) string {
+	if v == nil {
+		return "null"
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -2,13 +2,19 @@ package cli
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
+	"github.com/furiatona/azctl/internal/aci"
+	"github.com/furiatona/azctl/internal/arm"
 	"github.com/furiatona/azctl/internal/config"
+	"github.com/furiatona/azctl/internal/deploy"
+	"github.com/furiatona/azctl/internal/jsonpatch"
 	"github.com/furiatona/azctl/internal/logging"
+	"github.com/furiatona/azctl/internal/preflight"
 	"github.com/furiatona/azctl/internal/runx"
 	"github.com/furiatona/azctl/internal/templatex"
 	"github.com/furiatona/azctl/internal/validation"
@@ -25,125 +31,113 @@ const (
 	envTrue        = "true"
 )
 
+// ACI deployment modes
+const (
+	deployModeCLI = "cli"
+	deployModeARM = "arm"
+)
+
+// deployMode resolves --mode, falling back to ACI_DEPLOY_MODE and then to the cli mode
+func deployMode(cfg *config.Config, mode string) (string, error) {
+	if mode == "" {
+		mode = cfg.Get("ACI_DEPLOY_MODE")
+	}
+	switch mode {
+	case "":
+		return deployModeCLI, nil
+	case deployModeCLI, deployModeARM:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid --mode %q (expected %s or %s)", mode, deployModeCLI, deployModeARM)
+	}
+}
+
+// defaultACITemplate is the container group template used when --template is not set
+const defaultACITemplate = "deploy/manifests/aci.json"
+
+// aciFlags are the flags shared by aci and its subcommands
+type aciFlags struct {
+	resourceGroup string
+	templatePath  string
+	blueGreen     bool
+	switcher      string
+	networkMode   string
+}
+
+// aciTarget is a resolved, validated and rendered ACI deployment
+type aciTarget struct {
+	cfg           *config.Config
+	envName       string
+	resourceGroup string
+	templatePath  string
+	// groupName is the container group name from the rendered template
+	groupName string
+	manifest  *aciManifest
+	// blueGreen deploys <groupName>-blue and <groupName>-green alternately
+	blueGreen bool
+	switcher  string
+}
+
 func newACICmd() *cobra.Command {
 	var (
-		resourceGroup string
-		templatePath  string
-		dryRun        bool
+		flags        aciFlags
+		dryRun       bool
+		showSecrets  bool
+		runChecks    bool
+		outputFormat string
+		mode         string
+		whatIf       bool
+		force        bool
+		healthCheck  healthFlags
 	)
 
 	cmd := &cobra.Command{
 		Use:   "aci",
-		Short: "Deploy Azure Container Instance with sidecar using JSON template",
+		Short: "Deploy Azure Container Instance with sidecar using a JSON or YAML template",
 		RunE: func(cmd *cobra.Command, _ []string) error {
-			// Get environment from root command
-			envName, _ := cmd.Flags().GetString("env")
-
 			cfg := config.Current()
 
-			// Auto-detect environment in CI if not provided
-			if envName == "" && isCIEnvironment() {
-				detectedEnv := detectEnvironmentFromCI()
-				if detectedEnv != "" {
-					envName = detectedEnv
-					logging.Debugf("Auto-detected environment in CI: %s", envName)
-				}
+			var err error
+			if mode, err = deployMode(cfg, mode); err != nil {
+				return err
 			}
-
-			// Set environment name for Fluent-bit configuration
-			if envName != "" {
-				cfg.Set("ENV_NAME", envName)
-				logging.Debugf("Set ENV_NAME='%s' for Fluent-bit config", envName)
+			if whatIf && mode != deployModeARM {
+				return fmt.Errorf("--what-if requires --mode=%s", deployModeARM)
 			}
 
-			// Auto-detect IMAGE_NAME, IMAGE_TAG, CONTAINER_GROUP_NAME, and DNS_NAME_LABEL in CI if not set
-			if isCIEnvironment() {
-				if cfg.Get("IMAGE_NAME") == "" {
-					if detectedImageName := detectImageNameFromCI(); detectedImageName != "" {
-						cfg.Set("IMAGE_NAME", detectedImageName)
-						logging.Debugf("Auto-detected IMAGE_NAME from CI: %s", detectedImageName)
-					}
-				}
-				if cfg.Get("IMAGE_TAG") == "" {
-					if detectedImageTag := detectImageTagFromCI(); detectedImageTag != "" {
-						cfg.Set("IMAGE_TAG", detectedImageTag)
-						logging.Debugf("Auto-detected IMAGE_TAG from CI: %s", detectedImageTag)
-					}
-				}
-				if cfg.Get("CONTAINER_GROUP_NAME") == "" {
-					if detectedImageName := detectImageNameFromCI(); detectedImageName != "" {
-						cfg.Set("CONTAINER_GROUP_NAME", detectedImageName)
-						logging.Debugf("Auto-detected CONTAINER_GROUP_NAME from CI: %s", detectedImageName)
-					}
-				}
-				if cfg.Get("DNS_NAME_LABEL") == "" {
-					containerName := cfg.Get("CONTAINER_GROUP_NAME")
-					if containerName != "" && envName != "" {
-						dnsNameLabel := fmt.Sprintf("%s-%s", containerName, envName)
-						cfg.Set("DNS_NAME_LABEL", dnsNameLabel)
-						logging.Debugf("Auto-detected DNS_NAME_LABEL from CI: %s", dnsNameLabel)
-					}
-				}
-			}
-
-			if templatePath == "" {
-				templatePath = "deploy/manifests/aci.json"
+			if err := healthCheck.resolve(cmd, cfg); err != nil {
+				return err
 			}
-			if _, err := os.Stat(templatePath); err != nil {
-				// fallback to local azctl/aci.json if user provided reference in repo
-				if _, err2 := os.Stat("azctl/aci.json"); err2 == nil {
-					templatePath = "azctl/aci.json"
-				} else {
-					return fmt.Errorf("template not found: %s", templatePath)
-				}
+			strategy, err := deployStrategy(cfg, "ACI", deploy.StrategySkipIfUnchanged, force)
+			if err != nil {
+				return err
 			}
 
-			// Apply flag overrides
-			if resourceGroup == "" {
-				resourceGroup = cfg.Get("RESOURCE_GROUP")
+			target, err := flags.resolve(cmd)
+			if err != nil {
+				return err
 			}
+			envName, resourceGroup := target.envName, target.resourceGroup
 
-			// Map environment-specific resource groups to RESOURCE_GROUP
-			if resourceGroup == "" {
-				envResourceGroupKey := fmt.Sprintf("%s_RESOURCE_GROUP", strings.ToUpper(envName))
-				resourceGroup = cfg.Get(envResourceGroupKey)
-				if resourceGroup != "" {
-					cfg.Set("RESOURCE_GROUP", resourceGroup)
-					logging.Debugf("Mapped %s='%s' to RESOURCE_GROUP", envResourceGroupKey, resourceGroup)
-				}
+			rendered, err := target.manifest.Encode(outputFormat)
+			if err != nil {
+				return err
 			}
 
-			// Map ACR_REGISTRY to IMAGE_REGISTRY for template compatibility
-			if cfg.Get("IMAGE_REGISTRY") == "" {
-				acrRegistry := cfg.Get("ACR_REGISTRY")
-				if acrRegistry != "" {
-					cfg.Set("IMAGE_REGISTRY", acrRegistry)
-					logging.Debugf("Mapped ACR_REGISTRY='%s' to IMAGE_REGISTRY", acrRegistry)
+			if runChecks {
+				if err := runPreflight(cmd.Context(), preflight.ACIChecks(cfg, resourceGroup)); err != nil {
+					return fmt.Errorf("ACI preflight failed: %w", err)
 				}
 			}
 
-			// Set environment-based defaults if not provided
-			applyACIDefaults(cfg, envName)
-
-			// Validate all required ACI variables are present
-			if err := validation.RequiredVars(cfg, validation.ACIRequiredVars()); err != nil {
-				return fmt.Errorf("ACI deployment validation failed: %w", err)
-			}
-
-			// render template by replacing {{VAR}} placeholders with values from cfg
-			raw, err := os.ReadFile(templatePath) //nolint:gosec // templatePath is validated
-			if err != nil {
-				return fmt.Errorf("failed to read template file: %w", err)
-			}
-			rendered, err := templatex.RenderEnv(string(raw), cfg)
-			if err != nil {
-				return fmt.Errorf("failed to render template: %w", err)
+			var deployment *arm.Deployment
+			if mode == deployModeARM {
+				if deployment, err = arm.Build([]byte(target.manifest.JSON)); err != nil {
+					return fmt.Errorf("failed to build ARM deployment: %w", err)
+				}
 			}
-
-			// validate JSON
-			var js map[string]any
-			if err := json.Unmarshal([]byte(rendered), &js); err != nil {
-				return fmt.Errorf("rendered JSON invalid: %w", err)
+			if whatIf {
+				return runWhatIf(cmd, resourceGroup, deployment)
 			}
 
 			// Generate Fluent-bit configuration for logging integration
@@ -158,33 +152,344 @@ func newACICmd() *cobra.Command {
 					return fmt.Errorf("failed to create .azctl directory: %w", err)
 				}
 
-				// Write rendered JSON to .azctl/aci-dry-run.json
-				outputFile := ".azctl/aci-dry-run.json"
-				if err := os.WriteFile(outputFile, []byte(rendered), 0644); err != nil {
+				// Write the rendered container group to .azctl/aci-dry-run.json (or .yaml)
+				outputFile := ".azctl/aci-dry-run." + aci.FormatFromPath(target.templatePath)
+				if outputFormat != "" {
+					outputFile = ".azctl/aci-dry-run." + outputFormat
+				}
+				output := rendered
+				if !showSecrets {
+					if output, err = target.manifest.Redacted(outputFormat); err != nil {
+						return err
+					}
+				}
+				if err := writePrivateFile(outputFile, []byte(output)); err != nil {
 					return fmt.Errorf("failed to write dry-run output: %w", err)
 				}
 
-				logging.Infof("Dry run complete. Generated ACI JSON written to: %s", outputFile)
+				logging.Infof("Dry run complete. Generated container group written to: %s", outputFile)
+				if !showSecrets {
+					logging.Infof("Secrets are shown as %s; use --show-secrets to include them", aci.SecretPlaceholder)
+				}
+				if deployment != nil {
+					armFile := ".azctl/aci-dry-run.arm.json"
+					if err := writePrivateFile(armFile, deployment.Template); err != nil {
+						return fmt.Errorf("failed to write dry-run output: %w", err)
+					}
+					logging.Infof("ARM deployment template written to: %s (secure parameters: %s)",
+						armFile, strings.Join(deployment.ParameterNames(), ", "))
+				}
 				logging.Infof("Review the file and run without --dry-run to deploy")
 				return nil
 			}
 
-			// Handle different deployment strategies based on environment
-			if err := deployACI(cmd.Context(), resourceGroup, envName, rendered); err != nil {
-				return fmt.Errorf("ACI deployment failed: %w", err)
+			create := func(ctx context.Context) error {
+				return createContainerGroup(ctx, resourceGroup, rendered)
+			}
+			if mode == deployModeARM {
+				create = func(ctx context.Context) error {
+					return arm.Deploy(ctx, resourceGroup, deployment)
+				}
+			}
+			group := &aciDeployment{
+				target: target, definition: target.manifest.JSON, create: create, out: cmd.OutOrStdout(),
 			}
 
-			return nil
+			if target.blueGreen {
+				// Blue/green always creates a new group; only skip-if-unchanged applies to it
+				if strategy.Name() == deploy.StrategySkipIfUnchanged {
+					if changed, err := group.Changed(cmd.Context()); err == nil && !changed {
+						logging.Infof("✅ Container group %s is unchanged. Skipping deployment (use --force to redeploy)",
+							target.groupName)
+						return nil
+					}
+				}
+				return runBlueGreen(cmd.Context(), target, target.manifest.JSON, mode, &healthCheck, "")
+			}
+
+			logging.Infof("Deploy strategy: %s", strategy.Name())
+			deployed, err := strategy.Deploy(cmd.Context(), group)
+			if err != nil {
+				return fmt.Errorf("ACI deployment failed: %w", err)
+			}
+			if !deployed {
+				return nil
+			}
+			return finishACIDeploy(cmd.Context(), target, target.manifest.JSON, &healthCheck, "")
 		},
 	}
 
-	cmd.Flags().StringVar(&resourceGroup, "resource-group", "", "Resource group (env: AZURE_RESOURCE_GROUP)")
-	cmd.Flags().StringVar(&templatePath, "template", "", "Path to aci.json template")
+	cmd.PersistentFlags().StringVar(&flags.resourceGroup, "resource-group", "",
+		"Resource group (env: AZURE_RESOURCE_GROUP)")
+	cmd.PersistentFlags().StringVar(&flags.templatePath, "template", "", "Path to aci.json or aci.yaml template")
+	cmd.PersistentFlags().BoolVar(&flags.blueGreen, "blue-green", false,
+		"Deploy <name>-blue/<name>-green alongside the live group and switch traffic once healthy (env: ACI_BLUE_GREEN)")
+	cmd.PersistentFlags().StringVar(&flags.switcher, "traffic-switcher", "",
+		"How blue/green switches traffic: none, dns, appgw or frontdoor (env: TRAFFIC_SWITCHER)")
+	cmd.PersistentFlags().StringVar(&flags.networkMode, "network-mode", "",
+		"public (IP with a DNS name label) or private (IP in ACI_SUBNET_IDS) (env: ACI_NETWORK_MODE)")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false,
-		"Generate ACI JSON without deploying (outputs to .azctl/aci-dry-run.json)")
+		"Generate the container group without deploying (outputs to .azctl/aci-dry-run.json or .yaml)")
+	cmd.Flags().BoolVar(&showSecrets, "show-secrets", false,
+		"Write secret values to the --dry-run output instead of "+aci.SecretPlaceholder)
+	cmd.Flags().StringVar(&outputFormat, "output-format", "",
+		"Format of the generated container group: json or yaml (default: the template's format)")
+	cmd.Flags().BoolVar(&runChecks, "preflight", false, "Check live Azure resources before deploying")
+	cmd.Flags().StringVar(&mode, "mode", "",
+		"Deployment mode: cli (az container create) or arm (az deployment group create) (env: ACI_DEPLOY_MODE)")
+	cmd.Flags().BoolVar(&whatIf, "what-if", false,
+		"Preview the changes an ARM deployment would make (requires --mode=arm)")
+	cmd.Flags().BoolVar(&force, "force", false,
+		"Deploy even if the live container group is unchanged (with the skip-if-unchanged strategy)")
+	healthCheck.register(cmd)
+
+	cmd.AddCommand(newACIPlanCmd(&flags), newACIHistoryCmd(&flags), newACIRollbackCmd(&flags),
+		newACILogsCmd(&flags), newACIStatusCmd(&flags), newACIDestroyCmd(&flags))
 	return cmd
 }
 
+// locate resolves the template path, environment and resource group without rendering
+func (f *aciFlags) locate(cmd *cobra.Command) (*aciTarget, error) {
+	target := &aciTarget{
+		cfg:          config.Current(),
+		envName:      resolveEnvName(cmd),
+		templatePath: f.templatePath,
+	}
+
+	if target.templatePath == "" {
+		target.templatePath = defaultACITemplate
+	}
+	if _, err := os.Stat(target.templatePath); err != nil {
+		// fallback to local azctl/aci.json if user provided reference in repo
+		if _, err2 := os.Stat("azctl/aci.json"); err2 == nil {
+			target.templatePath = "azctl/aci.json"
+		} else {
+			return nil, fmt.Errorf("template not found: %s", target.templatePath)
+		}
+	}
+
+	if f.networkMode != "" {
+		target.cfg.Set("ACI_NETWORK_MODE", f.networkMode)
+	}
+	target.resourceGroup = prepareACIConfig(target.cfg, target.envName, f.resourceGroup)
+
+	target.blueGreen, target.switcher = f.blueGreen, f.switcher
+	if !cmd.Flags().Changed("blue-green") {
+		target.blueGreen = target.cfg.Get("ACI_BLUE_GREEN") == envTrue
+	}
+	if target.switcher == "" {
+		target.switcher = target.cfg.Get("TRAFFIC_SWITCHER")
+	}
+	return target, nil
+}
+
+// resolve resolves configuration, validates it and renders the container group template
+func (f *aciFlags) resolve(cmd *cobra.Command) (*aciTarget, error) {
+	target, err := f.locate(cmd)
+	if err != nil {
+		return nil, err
+	}
+	cfg, envName := target.cfg, target.envName
+
+	// Every key the templates reference is checked up front, so missing values are reported
+	// together instead of one `missing env` error at a time
+	templateIssues, err := templateKeyIssues(cfg, aciTemplatePaths(cfg, target.templatePath, envName)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the full ACI configuration before touching any resources
+	if err := runValidation(cfg, envName, validation.TargetACI, templateIssues...); err != nil {
+		return nil, fmt.Errorf("ACI deployment validation failed: %w", err)
+	}
+
+	target.manifest, err = renderACIManifest(cfg, target.templatePath, envName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Catch container group problems Azure would only report minutes into the deployment
+	group, err := validateContainerGroup(cfg, []byte(target.manifest.JSON), target.templatePath)
+	if err != nil {
+		return nil, fmt.Errorf("container group validation failed: %w", err)
+	}
+	target.groupName = group.Name
+	return target, nil
+}
+
+// prepareACIConfig resolves names, resource group and defaults for an ACI deployment
+// and returns the resource group to deploy into
+func prepareACIConfig(cfg *config.Config, envName, resourceGroup string) string {
+	// Set environment name for Fluent-bit configuration
+	if envName != "" {
+		cfg.Set("ENV_NAME", envName)
+		logging.Debugf("Set ENV_NAME='%s' for Fluent-bit config", envName)
+	}
+
+	// Auto-detect IMAGE_NAME, IMAGE_TAG, CONTAINER_GROUP_NAME, and DNS_NAME_LABEL in CI if not set
+	applyCIImageDefaults(cfg)
+	if isCIEnvironment() {
+		if cfg.Get("CONTAINER_GROUP_NAME") == "" {
+			if detectedImageName := detectImageNameFromCI(); detectedImageName != "" {
+				cfg.Set("CONTAINER_GROUP_NAME", detectedImageName)
+				logging.Debugf("Auto-detected CONTAINER_GROUP_NAME from CI: %s", detectedImageName)
+			}
+		}
+		if cfg.Get("DNS_NAME_LABEL") == "" {
+			containerName := cfg.Get("CONTAINER_GROUP_NAME")
+			if containerName != "" && envName != "" {
+				dnsNameLabel := fmt.Sprintf("%s-%s", containerName, envName)
+				cfg.Set("DNS_NAME_LABEL", dnsNameLabel)
+				logging.Debugf("Auto-detected DNS_NAME_LABEL from CI: %s", dnsNameLabel)
+			}
+		}
+	}
+
+	// Apply flag overrides
+	if resourceGroup == "" {
+		resourceGroup = cfg.Get("RESOURCE_GROUP")
+	} else {
+		cfg.Set("RESOURCE_GROUP", resourceGroup)
+	}
+
+	// Map environment-specific resource groups to RESOURCE_GROUP
+	if resourceGroup == "" {
+		envResourceGroupKey := fmt.Sprintf("%s_RESOURCE_GROUP", strings.ToUpper(envName))
+		resourceGroup = cfg.Get(envResourceGroupKey)
+		if resourceGroup != "" {
+			cfg.Set("RESOURCE_GROUP", resourceGroup)
+			logging.Debugf("Mapped %s='%s' to RESOURCE_GROUP", envResourceGroupKey, resourceGroup)
+		}
+	}
+
+	// Map ACR_REGISTRY to IMAGE_REGISTRY for template compatibility
+	if cfg.Get("IMAGE_REGISTRY") == "" {
+		acrRegistry := cfg.Get("ACR_REGISTRY")
+		if acrRegistry != "" {
+			cfg.Set("IMAGE_REGISTRY", acrRegistry)
+			logging.Debugf("Mapped ACR_REGISTRY='%s' to IMAGE_REGISTRY", acrRegistry)
+		}
+	}
+
+	// Set environment-based defaults if not provided
+	applyACIDefaults(cfg, envName)
+
+	return resourceGroup
+}
+
+// overlayPath returns the per-environment overlay for a template: aci.prod.json for
+// aci.json and env prod
+func overlayPath(templatePath, envName string) string {
+	ext := filepath.Ext(templatePath)
+	return fmt.Sprintf("%s.%s%s", strings.TrimSuffix(templatePath, ext), envName, ext)
+}
+
+// applyOverlay renders the environment's overlay, if one exists next to the template, and
+// applies it to the rendered base as an RFC 7386 merge patch (object) or RFC 6902 JSON
+// patch (array). The overlay uses the template's format.
+func applyOverlay(cfg *config.Config, templatePath, envName, rendered string) (string, error) {
+	if envName == "" {
+		return rendered, nil
+	}
+	path := overlayPath(templatePath, envName)
+	raw, err := os.ReadFile(path) //nolint:gosec // overlay path is derived from the template path
+	if errors.Is(err, os.ErrNotExist) {
+		return rendered, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read overlay file: %w", err)
+	}
+
+	overlay, _, err := renderManifestFile(cfg, path, string(raw))
+	if err != nil {
+		return "", fmt.Errorf("failed to render overlay: %w", err)
+	}
+	merged, err := jsonpatch.Apply([]byte(rendered), []byte(overlay))
+	if err != nil {
+		return "", fmt.Errorf("failed to apply overlay %s: %w", path, err)
+	}
+
+	logging.Infof("🧩 Applied %s overlay: %s", envName, path)
+	return string(merged), nil
+}
+
+// TemplateRuleName is reported as the rule for keys referenced by a template but not set
+const TemplateRuleName = "Template Variables"
+
+// aciTemplatePaths returns the templates an ACI deployment renders: the container group
+// template, its overlay for the environment and the logging provider's Fluent-bit template
+func aciTemplatePaths(cfg *config.Config, templatePath, envName string) []string {
+	paths := []string{templatePath, overlayPath(templatePath, envName)}
+	if fluentBit := logging.NewManager().TemplatePath(cfg); fluentBit != "" {
+		paths = append(paths, fluentBit)
+	}
+	return paths
+}
+
+// templateKeyIssues reports configuration keys referenced by the given templates that have no
+// value: required keys as errors and keys used only in conditional blocks as warnings.
+// Paths that do not exist are skipped.
+func templateKeyIssues(cfg *config.Config, paths ...string) ([]validation.Issue, error) {
+	var issues []validation.Issue
+	for _, path := range paths {
+		raw, err := os.ReadFile(path) //nolint:gosec // template paths come from flags or defaults
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template file: %w", err)
+		}
+		refs, err := templatex.References(string(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		for _, ref := range templatex.Missing(refs, cfg) {
+			issue := validation.Issue{Rule: TemplateRuleName, Key: ref.Key, Source: path, File: path, Line: ref.Line}
+			switch {
+			case ref.Required:
+				issue.Severity = validation.SeverityError
+				issue.Message = "required by the template but not set"
+			case !ref.HasDefault:
+				issue.Severity = validation.SeverityWarning
+				issue.Message = "used in a conditional block of the template but not set"
+			default:
+				continue
+			}
+			issues = append(issues, issue)
+		}
+	}
+	return issues, nil
+}
+
+// runWhatIf previews an ARM deployment and prints the plan
+func runWhatIf(cmd *cobra.Command, resourceGroup string, deployment *arm.Deployment) error {
+	logging.Infof("🔍 Previewing ARM deployment %s in %s...", deployment.Name, resourceGroup)
+	result, err := arm.WhatIf(cmd.Context(), resourceGroup, deployment)
+	if err != nil {
+		return fmt.Errorf("ACI preview failed: %w", err)
+	}
+	if err := result.Write(cmd.OutOrStdout()); err != nil {
+		return fmt.Errorf("failed to write what-if plan: %w", err)
+	}
+	return nil
+}
+
+// validateContainerGroup runs semantic checks against the rendered container group
+// and returns the parsed group
+func validateContainerGroup(cfg *config.Config, rendered []byte, templatePath string) (*aci.ContainerGroup, error) {
+	group, err := aci.Parse(rendered)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse container group: %w", err)
+	}
+
+	report := &validation.Report{Issues: aci.Validate(group, aci.LimitsFromConfig(cfg))}
+	for i := range report.Issues {
+		report.Issues[i].Source = templatePath
+	}
+	return group, reportValidation(report)
+}
+
 // applyACIDefaults sets reasonable defaults for ACI deployment if not already configured
 func applyACIDefaults(cfg *config.Config, envName string) {
 	defaults := map[string]string{
@@ -226,53 +531,11 @@ func applyACIDefaults(cfg *config.Config, envName string) {
 	}
 }
 
-// deployACI handles different deployment strategies based on environment
-func deployACI(ctx context.Context, resourceGroup, envName, rendered string) error {
-	// For dev and staging: check if container group exists, delete it, then create new one
-	if envName == "dev" || envName == "development" || envName == "staging" {
-		cfg := config.Current()
-		containerGroupName := cfg.Get("CONTAINER_GROUP_NAME")
-		if containerGroupName == "" {
-			containerGroupName = cfg.Get("IMAGE_NAME") // fallback to image name
-		}
-
-		logging.Infof("🔍 Environment: %s - Checking for existing container group: %s", envName, containerGroupName)
-
-		// Check if container group exists
-		exists, err := checkContainerGroupExists(ctx, resourceGroup, containerGroupName)
-		if err != nil {
-			logging.Warnf("Failed to check if container group exists: %v", err)
-		} else if exists {
-			logging.Infof("🗑️  Container group %s exists. Deleting it...", containerGroupName)
-			if err := deleteContainerGroup(ctx, resourceGroup, containerGroupName); err != nil {
-				return fmt.Errorf("failed to delete existing container group: %w", err)
-			}
-			logging.Infof("✅ Container group %s deleted successfully", containerGroupName)
-		} else {
-			logging.Infof("📝 Container group %s does not exist. Proceeding with creation...", containerGroupName)
-		}
-	}
-
-	// Create new container group
-	logging.Infof("🚀 Creating new container group...")
-	return createContainerGroup(ctx, resourceGroup, rendered)
-}
-
-// checkContainerGroupExists checks if a container group exists in the specified resource group
+// checkContainerGroupExists checks if a container group exists in the specified resource group.
+// Failures other than a missing group (login, network, throttling) are returned.
 func checkContainerGroupExists(ctx context.Context, resourceGroup, containerGroupName string) (bool, error) {
-	args := []string{
-		"container", "show",
-		"--resource-group", resourceGroup,
-		"--name", containerGroupName,
-		"--output", "json",
-	}
-
-	_, err := runx.AZOutput(ctx, args...)
-	if err != nil {
-		// If the command fails, the container group likely doesn't exist
-		return false, nil
-	}
-	return true, nil
+	group, err := fetchContainerGroup(ctx, resourceGroup, containerGroupName)
+	return group != nil, err
 }
 
 // deleteContainerGroup deletes an existing container group
@@ -293,7 +556,11 @@ func deleteContainerGroup(ctx context.Context, resourceGroup, containerGroupName
 // createContainerGroup creates a new container group from JSON
 func createContainerGroup(ctx context.Context, resourceGroup, rendered string) error {
 	// Write to temp file for az cli
-	f, err := os.CreateTemp("", "aci-*.json")
+	pattern := "aci-*.json"
+	if !strings.HasPrefix(strings.TrimSpace(rendered), "{") {
+		pattern = "aci-*.yaml"
+	}
+	f, err := os.CreateTemp("", pattern)
 	if err != nil {
 		return fmt.Errorf("failed to create temp file: %w", err)
 	}
@@ -314,3 +581,15 @@ func createContainerGroup(ctx context.Context, resourceGroup, rendered string) e
 	}
 	return nil
 }
+
+// writePrivateFile writes data readable only by the owner. os.WriteFile keeps the mode of an
+// existing file, so a dry-run file left world-readable by an earlier version is tightened too.
+func writePrivateFile(path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		return fmt.Errorf("failed to restrict permissions of %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/furiatona/azctl/internal/aci"
+	"github.com/furiatona/azctl/internal/arm"
+	"github.com/furiatona/azctl/internal/logging"
+	"github.com/furiatona/azctl/internal/traffic"
+)
+
+// Blue/green colors; each deployment creates <CONTAINER_GROUP_NAME>-<color>
+const (
+	colorBlue  = "blue"
+	colorGreen = "green"
+)
+
+// blueGreenState describes the container groups of a blue/green deployment
+type blueGreenState struct {
+	// live is the color currently serving traffic, or "" before the first deployment
+	live string
+	// next is the color the deployment creates
+	next string
+	// legacy is set when a group without a color suffix exists, i.e. the first blue/green
+	// deployment of a group that was deployed in place before
+	legacy bool
+}
+
+// findColors determines which color is live by checking which groups exist
+func findColors(ctx context.Context, resourceGroup, name string) (*blueGreenState, error) {
+	blue, err := fetchContainerGroup(ctx, resourceGroup, name+"-"+colorBlue)
+	if err != nil {
+		return nil, err
+	}
+	green, err := fetchContainerGroup(ctx, resourceGroup, name+"-"+colorGreen)
+	if err != nil {
+		return nil, err
+	}
+	legacy, err := fetchContainerGroup(ctx, resourceGroup, name)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &blueGreenState{next: colorBlue, legacy: legacy != nil}
+	switch {
+	case blue != nil && green != nil:
+		return nil, fmt.Errorf("both %s-%s and %s-%s exist; delete the one not serving traffic and retry",
+			name, colorBlue, name, colorGreen)
+	case blue != nil:
+		state.live, state.next = colorBlue, colorGreen
+	case green != nil:
+		state.live, state.next = colorGreen, colorBlue
+	}
+	return state, nil
+}
+
+// runBlueGreen deploys a container group definition blue/green in the given deployment mode
+// and records it in the history
+func runBlueGreen(ctx context.Context, target *aciTarget, definition, mode string, hc *healthFlags, note string) error {
+	switcher, err := blueGreenSwitcher(target)
+	if err != nil {
+		return err
+	}
+	deploy := func(ctx context.Context, doc string) error {
+		if mode != deployModeARM {
+			return createContainerGroup(ctx, target.resourceGroup, doc)
+		}
+		deployment, err := arm.Build([]byte(doc))
+		if err != nil {
+			return fmt.Errorf("failed to build ARM deployment: %w", err)
+		}
+		return arm.Deploy(ctx, target.resourceGroup, deployment) //nolint:wrapcheck // arm errors name the deployment
+	}
+	if err := deployBlueGreen(ctx, target, definition, switcher, deploy, hc); err != nil {
+		return fmt.Errorf("ACI blue/green deployment failed: %w", err)
+	}
+	recordDeployment(ctx, target, definition, note)
+	return nil
+}
+
+// blueGreenSwitcher returns the configured traffic switcher. Each color has its own DNS name
+// label, so without a switcher the group's FQDN changes on every deployment; that has to be
+// asked for with TRAFFIC_SWITCHER=none.
+func blueGreenSwitcher(target *aciTarget) (traffic.Switcher, error) {
+	switch {
+	case target.switcher == "":
+		return nil, fmt.Errorf("blue/green deployments need a traffic switcher (%s, %s or %s); "+
+			"set TRAFFIC_SWITCHER=%s to let the group's DNS name change on every deployment",
+			traffic.SwitcherDNS, traffic.SwitcherAppGW, traffic.SwitcherFrontDoor, traffic.SwitcherNone)
+	case strings.EqualFold(target.switcher, traffic.SwitcherNone):
+		logging.Warnf("No traffic switcher: clients must follow the new group's DNS name, %s-<color>",
+			target.groupName)
+	}
+	return traffic.New(target.switcher, target.cfg, target.resourceGroup) //nolint:wrapcheck // errors name the settings
+}
+
+// deployBlueGreen creates the next color alongside the live group, waits for it to be
+// healthy, switches traffic to it and only then deletes the previous group. If the new group
+// fails to deploy, is unhealthy or traffic cannot be switched, the new group is deleted and the
+// live one keeps serving.
+func deployBlueGreen(ctx context.Context, target *aciTarget, definition string, switcher traffic.Switcher,
+	deploy func(ctx context.Context, doc string) error, hc *healthFlags,
+) error {
+	state, err := findColors(ctx, target.resourceGroup, target.groupName)
+	if err != nil {
+		return err
+	}
+	doc, err := aci.WithSuffix([]byte(definition), state.next)
+	if err != nil {
+		return fmt.Errorf("failed to prepare %s group: %w", state.next, err)
+	}
+	group, err := aci.Parse(doc)
+	if err != nil {
+		return fmt.Errorf("failed to parse container group: %w", err)
+	}
+	newName := group.Name
+
+	abort := func(cause error) error {
+		logging.Warnf("Deleting %s; the live group keeps serving traffic", newName)
+		if err := deleteContainerGroup(ctx, target.resourceGroup, newName); err != nil {
+			logging.Warnf("Failed to delete %s: %v", newName, err)
+		}
+		return cause
+	}
+
+	logging.Infof("🔵 Deploying %s alongside the live group...", newName)
+	if err := deploy(ctx, string(doc)); err != nil {
+		err = fmt.Errorf("failed to create %s: %w", newName, err)
+		// A create can fail after the group exists (e.g. provisioning Failed); left behind, it
+		// would block every later deployment
+		created, lookupErr := fetchContainerGroup(ctx, target.resourceGroup, newName)
+		if lookupErr != nil || created != nil {
+			return abort(err)
+		}
+		return err
+	}
+
+	if hc.skip {
+		logging.Warnf("Health check skipped; switching traffic to %s without verifying it", newName)
+		reportPrivateIP(ctx, target.cfg, target.resourceGroup, newName)
+	} else {
+		dnsLabel := ""
+		if group.Properties.IPAddress != nil {
+			dnsLabel = group.Properties.IPAddress.DNSNameLabel
+		}
+		if err := verifyACIHealth(ctx, target.cfg, target.resourceGroup, newName, dnsLabel, hc); err != nil {
+			return abort(err)
+		}
+	}
+
+	endpoint, err := fetchEndpoint(ctx, target.resourceGroup, newName)
+	if err != nil {
+		return abort(err)
+	}
+	logging.Infof("🔀 Switching %s to %s...", switcher.Name(), newName)
+	if err := switcher.Switch(ctx, *endpoint); err != nil {
+		return abort(fmt.Errorf("traffic switch failed: %w", err))
+	}
+	logging.Infof("✅ Traffic switched to %s", newName)
+
+	var previous []string
+	if state.live != "" {
+		previous = append(previous, target.groupName+"-"+state.live)
+	}
+	if state.legacy {
+		previous = append(previous, target.groupName)
+	}
+	for _, name := range previous {
+		logging.Infof("🗑️  Deleting previous container group %s...", name)
+		if err := deleteContainerGroup(ctx, target.resourceGroup, name); err != nil {
+			return fmt.Errorf("traffic switched to %s but deleting %s failed: %w", newName, name, err)
+		}
+	}
+	return nil
+}
+
+// fetchEndpoint returns the public address of a container group
+func fetchEndpoint(ctx context.Context, resourceGroup, name string) (*traffic.Endpoint, error) {
+	live, err := fetchContainerGroup(ctx, resourceGroup, name)
+	if err != nil {
+		return nil, err
+	}
+	if live == nil {
+		return nil, fmt.Errorf("container group %s not found", name)
+	}
+	var group struct {
+		IPAddress *struct {
+			IP   string `json:"ip"`
+			FQDN string `json:"fqdn"`
+		} `json:"ipAddress"`
+	}
+	if err := json.Unmarshal(live, &group); err != nil {
+		return nil, fmt.Errorf("failed to parse container group %s: %w", name, err)
+	}
+	endpoint := &traffic.Endpoint{Name: name}
+	if group.IPAddress != nil {
+		endpoint.IP, endpoint.FQDN = group.IPAddress.IP, group.IPAddress.FQDN
+	}
+	return endpoint, nil
+}
@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/furiatona/azctl/internal/logging"
+	"github.com/furiatona/azctl/internal/runx"
+	"github.com/furiatona/azctl/internal/traffic"
+)
+
+// teardownStep is one resource removed by azctl aci destroy
+type teardownStep struct {
+	description string
+	run         func(ctx context.Context) error
+}
+
+func newACIDestroyCmd(flags *aciFlags) *cobra.Command {
+	var (
+		yes         bool
+		purgeLogs   bool
+		confirmName string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "destroy",
+		Short: "Delete the container group and the resources azctl created for it",
+		Long: `Delete the container group (both colors of a blue/green deployment), the Fluent-bit
+configuration uploaded for it and the DNS record of the dns traffic switcher. With --purge-logs,
+the files in the log share are deleted too. The deployment history is kept.
+
+The resources are listed and must be confirmed, or --yes passed. In production the container
+group name must be typed, or passed with --confirm-name alongside --yes.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			target, err := flags.locateGroup(cmd)
+			if err != nil {
+				return err
+			}
+			if target.groupName == "" {
+				return fmt.Errorf("container group name unknown: set CONTAINER_GROUP_NAME or fix the template")
+			}
+
+			steps, err := planTeardown(cmd.Context(), target, purgeLogs)
+			if err != nil {
+				return err
+			}
+			if len(steps) == 0 {
+				logging.Infof("Nothing to destroy for %s in %s", target.groupName, target.resourceGroup)
+				return nil
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "The following resources will be deleted from %s:\n", target.resourceGroup)
+			for _, step := range steps {
+				fmt.Fprintf(out, "  - %s\n", step.description)
+			}
+			if err := confirmTeardown(cmd.InOrStdin(), out, target, yes, confirmName); err != nil {
+				return err
+			}
+
+			for _, step := range steps {
+				logging.Infof("🗑️  Deleting %s...", step.description)
+				if err := step.run(cmd.Context()); err != nil {
+					return fmt.Errorf("failed to delete %s: %w", step.description, err)
+				}
+			}
+			logging.Infof("✅ Destroyed %s", target.groupName)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Do not ask for confirmation")
+	cmd.Flags().BoolVar(&purgeLogs, "purge-logs", false, "Also delete the files in the log share (LOG_STORAGE_NAME)")
+	cmd.Flags().StringVar(&confirmName, "confirm-name", "",
+		"Container group name, required with --yes in production")
+	return cmd
+}
+
+// planTeardown lists the resources of the container group that exist
+func planTeardown(ctx context.Context, target *aciTarget, purgeLogs bool) ([]teardownStep, error) {
+	var steps []teardownStep
+	rg := target.resourceGroup
+
+	names := []string{target.groupName, target.groupName + "-" + colorBlue, target.groupName + "-" + colorGreen}
+	for _, name := range names {
+		live, err := fetchContainerGroup(ctx, rg, name)
+		if err != nil {
+			return nil, err
+		}
+		if live != nil {
+			steps = append(steps, teardownStep{
+				description: "container group " + name,
+				run:         func(ctx context.Context) error { return deleteContainerGroup(ctx, rg, name) },
+			})
+		}
+	}
+
+	cfg := target.cfg
+	account, key := cfg.Get("LOG_STORAGE_ACCOUNT"), cfg.Get("LOG_STORAGE_KEY")
+	if share, imageName := cfg.Get("FLUENTBIT_CONFIG"), cfg.Get("IMAGE_NAME"); share != "" && imageName != "" {
+		file := []string{"--account-name", account, "--account-key", key,
+			"--share-name", share, "--path", imageName + ".conf"}
+		exists, err := runx.AZOutput(ctx,
+			append([]string{"storage", "file", "exists", "--query", "exists", "--output", "tsv"}, file...)...)
+		if err != nil {
+			logging.Debugf("Could not check the Fluent-bit config in %s: %v", share, err)
+		} else if strings.TrimSpace(exists) == envTrue {
+			steps = append(steps, teardownStep{
+				description: fmt.Sprintf("Fluent-bit config %s/%s.conf in storage account %s", share, imageName, account),
+				run: func(ctx context.Context) error {
+					return runx.AZ(ctx, append([]string{"storage", "file", "delete"}, file...)...)
+				},
+			})
+		}
+	}
+	if share := cfg.Get("LOG_STORAGE_NAME"); purgeLogs && share != "" {
+		steps = append(steps, teardownStep{
+			description: fmt.Sprintf("all files in log share %s in storage account %s", share, account),
+			run: func(ctx context.Context) error {
+				return runx.AZ(ctx, "storage", "file", "delete-batch",
+					"--account-name", account, "--account-key", key, "--source", share)
+			},
+		})
+	}
+
+	if target.switcher != "" {
+		switcher, err := traffic.New(target.switcher, cfg, rg)
+		if err != nil {
+			logging.Warnf("Keeping the traffic switcher's resources: %v", err)
+		} else if remover, ok := switcher.(traffic.Remover); ok {
+			steps = append(steps, teardownStep{description: switcher.Name(), run: remover.Remove})
+		}
+	}
+	return steps, nil
+}
+
+// confirmTeardown asks for confirmation unless yes is set. Production requires the container
+// group name, typed or passed in confirmName.
+func confirmTeardown(in io.Reader, out io.Writer, target *aciTarget, yes bool, confirmName string) error {
+	prod := target.envName == envProd || target.envName == envProduction
+	if yes {
+		if prod && confirmName != target.groupName {
+			return fmt.Errorf("refusing to destroy production without --confirm-name=%s", target.groupName)
+		}
+		return nil
+	}
+
+	expected := "yes"
+	if prod {
+		expected = target.groupName
+		fmt.Fprintf(out, "This is production. Type the container group name (%s) to confirm: ", expected)
+	} else {
+		fmt.Fprint(out, "Type 'yes' to confirm: ")
+	}
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && answer == "" {
+		return fmt.Errorf("destroy cancelled: no confirmation (use --yes in scripts)")
+	}
+	if strings.TrimSpace(answer) != expected {
+		return fmt.Errorf("destroy cancelled")
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/furiatona/azctl/internal/aci"
+	"github.com/furiatona/azctl/internal/config"
+	"github.com/furiatona/azctl/internal/health"
+	"github.com/furiatona/azctl/internal/logging"
+)
+
+// healthFlags configure post-deploy verification of a container group
+type healthFlags struct {
+	skip    bool
+	path    string
+	status  int
+	timeout time.Duration
+}
+
+func (f *healthFlags) register(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&f.skip, "skip-health-check", false,
+		"Do not wait for the containers to be running after deploying")
+	cmd.Flags().StringVar(&f.path, "health-path", "",
+		"HTTP path to probe once the containers are running, e.g. /healthz (env: ACI_HEALTH_PATH)")
+	cmd.Flags().IntVar(&f.status, "health-status", health.DefaultStatus,
+		"HTTP status the health probe expects (env: ACI_HEALTH_STATUS)")
+	cmd.Flags().DurationVar(&f.timeout, "health-timeout", health.DefaultTimeout,
+		"How long to wait for the containers and the health probe (env: ACI_HEALTH_TIMEOUT)")
+}
+
+// resolve fills unset flags from configuration
+func (f *healthFlags) resolve(cmd *cobra.Command, cfg *config.Config) error {
+	if !cmd.Flags().Changed("health-path") {
+		f.path = cfg.Get("ACI_HEALTH_PATH")
+	}
+	if value := cfg.Get("ACI_HEALTH_STATUS"); value != "" && !cmd.Flags().Changed("health-status") {
+		status, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_HEALTH_STATUS %q: %w", value, err)
+		}
+		f.status = status
+	}
+	if value := cfg.Get("ACI_HEALTH_TIMEOUT"); value != "" && !cmd.Flags().Changed("health-timeout") {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid ACI_HEALTH_TIMEOUT %q: %w", value, err)
+		}
+		f.timeout = timeout
+	}
+	return nil
+}
+
+// verifyACIHealth waits for every container in the group to be running and, when a health
+// path is configured, probes the application through the group's public DNS name label, or
+// its private IP in a virtual network
+func verifyACIHealth(ctx context.Context, cfg *config.Config, resourceGroup, name, dnsLabel string, f *healthFlags,
+) error {
+	// --health-timeout bounds the wait for the containers and the probe together
+	deadline := time.Now().Add(f.timeout)
+	logging.Infof("🩺 Waiting for containers in %s to be running...", name)
+	status, err := health.WaitForRunning(ctx, resourceGroup, name, f.timeout)
+	if err != nil {
+		return fmt.Errorf("container group %s is unhealthy: %w", name, err)
+	}
+	logging.Infof("✅ Containers running: %s", status.Summary())
+
+	private := aci.NetworkFromConfig(cfg) != nil
+	if private {
+		logging.Infof("🌐 %s has private IP %s", name, orDash(status.IP))
+	}
+	if f.path == "" {
+		return nil
+	}
+
+	var url string
+	switch {
+	case private && status.IP != "":
+		// Only reachable when azctl runs inside the virtual network or a peered one
+		url = health.HostURL(status.IP, cfg.Get("ACI_PORT"), f.path)
+	case private:
+		logging.Warnf("%s has no private IP yet; skipping the HTTP health probe", name)
+		return nil
+	case dnsLabel == "":
+		logging.Warnf("DNS_NAME_LABEL is not set; skipping the HTTP health probe")
+		return nil
+	default:
+		url = health.ProbeURL(dnsLabel, cfg.Get("LOCATION"), cfg.Get("ACI_PORT"), f.path)
+	}
+	logging.Infof("🩺 Probing %s (expecting %d)...", url, f.status)
+	if err := health.Probe(ctx, url, f.status, max(time.Until(deadline), 0)); err != nil {
+		return fmt.Errorf("container group %s is unhealthy: %w", name, err)
+	}
+	logging.Infof("✅ Health probe passed: %s", url)
+	return nil
+}
+
+// reportPrivateIP logs the private IP of a group deployed into a virtual network when the
+// health check, which reports it otherwise, is skipped
+func reportPrivateIP(ctx context.Context, cfg *config.Config, resourceGroup, name string) {
+	if aci.NetworkFromConfig(cfg) == nil {
+		return
+	}
+	live, err := fetchContainerGroup(ctx, resourceGroup, name)
+	if err != nil || live == nil {
+		logging.Warnf("Could not read the private IP of %s: %v", name, err)
+		return
+	}
+	status, err := health.ParseStatus(live)
+	if err != nil {
+		logging.Warnf("Could not read the private IP of %s: %v", name, err)
+		return
+	}
+	logging.Infof("🌐 %s has private IP %s", name, orDash(status.IP))
+}
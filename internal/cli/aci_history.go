@@ -0,0 +1,291 @@
+package cli
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/furiatona/azctl/internal/aci"
+	"github.com/furiatona/azctl/internal/arm"
+	"github.com/furiatona/azctl/internal/config"
+	"github.com/furiatona/azctl/internal/deploy"
+	"github.com/furiatona/azctl/internal/history"
+	"github.com/furiatona/azctl/internal/logging"
+)
+
+// historyStore returns the store deployments are recorded in (env: AZCTL_HISTORY_DIR)
+func historyStore(cfg *config.Config) history.Store {
+	return history.NewLocalStore(cfg.Get("AZCTL_HISTORY_DIR"))
+}
+
+// finishACIDeploy verifies a deployed container group and records it as a new revision
+func finishACIDeploy(ctx context.Context, target *aciTarget, definition string, hc *healthFlags, note string) error {
+	if hc.skip {
+		reportPrivateIP(ctx, target.cfg, target.resourceGroup, target.groupName)
+	} else {
+		dnsLabel := target.cfg.Get("DNS_NAME_LABEL")
+		if err := verifyACIHealth(ctx, target.cfg, target.resourceGroup, target.groupName, dnsLabel, hc); err != nil {
+			return err
+		}
+	}
+	recordDeployment(ctx, target, definition, note)
+	return nil
+}
+
+// recordDeployment records a deployed container group. The deployment already succeeded,
+// so a history failure is only logged.
+func recordDeployment(ctx context.Context, target *aciTarget, definition, note string) {
+	rev, err := recordRevision(ctx, historyStore(target.cfg), target, definition, note)
+	if err != nil {
+		logging.Warnf("Failed to record deployment history: %v", err)
+		return
+	}
+	logging.Infof("📚 Recorded %s revision %d", target.groupName, rev.Number)
+}
+
+// recordRevision stores a deployed container group with its secrets stripped
+func recordRevision(ctx context.Context, store history.Store, target *aciTarget, definition, note string,
+) (*history.Revision, error) {
+	stripped, err := aci.StripSecrets([]byte(definition))
+	if err != nil {
+		return nil, fmt.Errorf("failed to strip secrets: %w", err)
+	}
+	group, err := aci.Parse(stripped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse container group: %w", err)
+	}
+
+	// Only the stripped definition is hashed so the hash reveals nothing about secret values.
+	// It still changes when a secret is rotated through aci.SecretsTag, which is keyed with
+	// ACI_SECRETS_HASH_KEY.
+	sum := sha256.Sum256(stripped)
+	rev := &history.Revision{
+		ResourceGroup: target.resourceGroup,
+		Name:          target.groupName,
+		Environment:   target.envName,
+		ConfigHash:    hex.EncodeToString(sum[:6]),
+		Commit:        detectGitCommit(ctx),
+		DeployedAt:    time.Now().UTC(),
+		Note:          note,
+		Definition:    stripped,
+	}
+	if len(group.Properties.Containers) > 0 {
+		rev.Image = group.Properties.Containers[0].Properties.Image
+	}
+	if err := store.Save(ctx, rev); err != nil {
+		return nil, fmt.Errorf("failed to save revision: %w", err)
+	}
+	return rev, nil
+}
+
+// locateGroup resolves an existing container group without validating the configuration
+// for a deployment. The current template is rendered when possible, both for the group name
+// and for secrets a rollback re-resolves.
+func (f *aciFlags) locateGroup(cmd *cobra.Command) (*aciTarget, error) {
+	target, err := f.locate(cmd)
+	if err != nil {
+		return nil, err
+	}
+	target.groupName = target.cfg.Get("CONTAINER_GROUP_NAME")
+
+	manifest, err := renderACIManifest(target.cfg, target.templatePath, target.envName)
+	if err != nil {
+		logging.Warnf("Could not render the current template: %v", err)
+		return target, nil
+	}
+	target.manifest = manifest
+	if group, err := aci.Parse([]byte(manifest.JSON)); err == nil && group.Name != "" {
+		target.groupName = group.Name
+	}
+	return target, nil
+}
+
+func newACIHistoryCmd(flags *aciFlags) *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List recorded deployments of the container group",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			target, err := flags.locateGroup(cmd)
+			if err != nil {
+				return err
+			}
+			if target.groupName == "" {
+				return fmt.Errorf("container group name unknown: set CONTAINER_GROUP_NAME or fix the template")
+			}
+			revisions, err := historyStore(target.cfg).List(cmd.Context(), target.resourceGroup, target.groupName)
+			if err != nil {
+				return fmt.Errorf("failed to read history: %w", err)
+			}
+
+			switch output {
+			case "json":
+				data, err := json.MarshalIndent(revisions, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to encode history: %w", err)
+				}
+				_, err = fmt.Fprintln(cmd.OutOrStdout(), string(data))
+				return err //nolint:wrapcheck // writer errors need no extra context
+			case "table":
+				if len(revisions) == 0 {
+					logging.Infof("No recorded deployments of %s in %s", target.groupName, target.resourceGroup)
+					return nil
+				}
+				return writeHistory(cmd.OutOrStdout(), revisions)
+			default:
+				return fmt.Errorf("unsupported output format: %s (supported: table, json)", output)
+			}
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format (table, json)")
+	return cmd
+}
+
+// writeHistory prints revisions as a table, newest first
+func writeHistory(w io.Writer, revisions []history.Revision) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "REVISION\tDEPLOYED\tIMAGE\tCOMMIT\tCONFIG\tNOTE")
+	for i := len(revisions) - 1; i >= 0; i-- {
+		rev := revisions[i]
+		commit := rev.Commit
+		if len(commit) > 7 {
+			commit = commit[:7]
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", rev.Number, rev.DeployedAt.Local().Format("2006-01-02 15:04:05"),
+			rev.Image, commit, rev.ConfigHash, rev.Note)
+	}
+	return tw.Flush() //nolint:wrapcheck // writer errors need no extra context
+}
+
+func newACIRollbackCmd(flags *aciFlags) *cobra.Command {
+	var (
+		to          int
+		mode        string
+		healthCheck healthFlags
+	)
+
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "Redeploy a previously recorded revision of the container group",
+		Long: `Redeploy a recorded revision, by default the one before the latest. Secrets are not
+stored in the history; they are re-resolved from the current configuration.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			target, err := flags.locateGroup(cmd)
+			if err != nil {
+				return err
+			}
+			if mode, err = deployMode(target.cfg, mode); err != nil {
+				return err
+			}
+			if err := healthCheck.resolve(cmd, target.cfg); err != nil {
+				return err
+			}
+			rev, err := selectRevision(cmd.Context(), historyStore(target.cfg), target, to)
+			if err != nil {
+				return err
+			}
+
+			definition, err := restoreRevision(target, rev)
+			if err != nil {
+				return err
+			}
+			if _, err := validateContainerGroup(target.cfg, []byte(definition),
+				fmt.Sprintf("revision %d", rev.Number)); err != nil {
+				return fmt.Errorf("container group validation failed: %w", err)
+			}
+
+			logging.Infof("⏪ Rolling back %s to revision %d (%s, deployed %s)",
+				target.groupName, rev.Number, rev.Image, rev.DeployedAt.Local().Format(time.RFC3339))
+			note := fmt.Sprintf("rollback to %d", rev.Number)
+			if target.blueGreen {
+				return runBlueGreen(cmd.Context(), target, definition, mode, &healthCheck, note)
+			}
+
+			// A rollback always deploys, so skip-if-unchanged behaves like in-place
+			strategy, err := deployStrategy(target.cfg, "ACI", deploy.StrategyInPlace, true)
+			if err != nil {
+				return err
+			}
+			create := func(ctx context.Context) error {
+				return createContainerGroup(ctx, target.resourceGroup, definition)
+			}
+			if mode == deployModeARM {
+				deployment, err := arm.Build([]byte(definition))
+				if err != nil {
+					return fmt.Errorf("failed to build ARM deployment: %w", err)
+				}
+				create = func(ctx context.Context) error {
+					return arm.Deploy(ctx, target.resourceGroup, deployment)
+				}
+			}
+			if _, err := strategy.Deploy(cmd.Context(), &aciDeployment{
+				target: target, definition: definition, create: create, out: cmd.OutOrStdout(),
+			}); err != nil {
+				return fmt.Errorf("ACI rollback failed: %w", err)
+			}
+			return finishACIDeploy(cmd.Context(), target, definition, &healthCheck, note)
+		},
+	}
+
+	cmd.Flags().IntVar(&to, "to", 0, "Revision to redeploy (default: the revision before the latest)")
+	cmd.Flags().StringVar(&mode, "mode", "",
+		"Deployment mode: cli (az container create) or arm (az deployment group create) (env: ACI_DEPLOY_MODE)")
+	healthCheck.register(cmd)
+	return cmd
+}
+
+// selectRevision returns revision to, or the one before the latest when to is 0
+func selectRevision(ctx context.Context, store history.Store, target *aciTarget, to int) (*history.Revision, error) {
+	if target.groupName == "" {
+		return nil, fmt.Errorf("container group name unknown: set CONTAINER_GROUP_NAME or fix the template")
+	}
+	if to > 0 {
+		rev, err := store.Get(ctx, target.resourceGroup, target.groupName, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read revision: %w", err)
+		}
+		return rev, nil
+	}
+
+	revisions, err := store.List(ctx, target.resourceGroup, target.groupName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	if len(revisions) < 2 {
+		return nil, fmt.Errorf("no previous revision of %s to roll back to (%d recorded)",
+			target.groupName, len(revisions))
+	}
+	return &revisions[len(revisions)-2], nil
+}
+
+// restoreRevision fills a stored revision's secrets from the current template and
+// configuration, and re-stamps the secrets hash for the values now in use
+func restoreRevision(target *aciTarget, rev *history.Revision) (string, error) {
+	var current []byte
+	if target.manifest != nil {
+		current = []byte(target.manifest.JSON)
+	}
+	lookup := func(key string) (string, bool) {
+		value := target.cfg.Get(key)
+		return value, value != ""
+	}
+	restored, err := aci.RestoreSecrets(rev.Definition, current, lookup)
+	if err != nil {
+		return "", fmt.Errorf("failed to restore revision %d: %w", rev.Number, err)
+	}
+
+	m := &aciManifest{JSON: string(restored), format: aci.FormatJSON}
+	if err := m.stampSecrets(target.cfg); err != nil {
+		return "", err
+	}
+	return m.JSON, nil
+}
@@ -0,0 +1,236 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/furiatona/azctl/internal/aci"
+	"github.com/furiatona/azctl/internal/runx"
+)
+
+// containerLogsAPIVersion is the ARM API version used to read container logs with timestamps,
+// which `az container logs` does not expose
+const containerLogsAPIVersion = "2023-05-01"
+
+// logPollInterval is how often --follow polls for new output
+var logPollInterval = 2 * time.Second
+
+// logsOptions holds the aci logs flags
+type logsOptions struct {
+	containers []string
+	follow     bool
+	since      time.Duration
+	output     string
+}
+
+func newACILogsCmd(flags *aciFlags) *cobra.Command {
+	var opts logsOptions
+
+	cmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Show the output of the container group's containers",
+		Long: `Print the output of every container in the group (or those selected with --container),
+interleaved by time and prefixed with the container name. With --follow, new output is printed
+until interrupted.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if opts.output != "text" && opts.output != "json" {
+				return fmt.Errorf("unsupported output format: %s (supported: text, json)", opts.output)
+			}
+			target, err := flags.locateGroup(cmd)
+			if err != nil {
+				return err
+			}
+			groupID, containers, err := liveContainers(cmd.Context(), target)
+			if err != nil {
+				return err
+			}
+			if containers, err = selectContainers(containers, opts.containers); err != nil {
+				return err
+			}
+
+			ctx := cmd.Context()
+			if opts.follow {
+				var stop context.CancelFunc
+				ctx, stop = signal.NotifyContext(ctx, os.Interrupt)
+				defer stop()
+			}
+			return streamLogs(ctx, cmd.OutOrStdout(), groupID, containers, &opts)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&opts.containers, "container", nil,
+		"Containers to show, e.g. app or fluentbit (default: all)")
+	cmd.Flags().BoolVarP(&opts.follow, "follow", "f", false, "Keep printing new output until interrupted")
+	cmd.Flags().DurationVar(&opts.since, "since", 0, "Only show output newer than this, e.g. 10m")
+	cmd.Flags().StringVarP(&opts.output, "output", "o", "text", "Output format (text, json lines)")
+	return cmd
+}
+
+// liveGroupName returns the name of the deployed group; for blue/green deployments that is
+// the live color
+func liveGroupName(ctx context.Context, target *aciTarget) (string, error) {
+	if target.groupName == "" {
+		return "", fmt.Errorf("container group name unknown: set CONTAINER_GROUP_NAME or fix the template")
+	}
+	if !target.blueGreen {
+		return target.groupName, nil
+	}
+	state, err := findColors(ctx, target.resourceGroup, target.groupName)
+	if err != nil {
+		return "", err
+	}
+	if state.live == "" {
+		return target.groupName, nil
+	}
+	return target.groupName + "-" + state.live, nil
+}
+
+// liveContainers returns the resource ID and container names of the deployed group
+func liveContainers(ctx context.Context, target *aciTarget) (string, []string, error) {
+	name, err := liveGroupName(ctx, target)
+	if err != nil {
+		return "", nil, err
+	}
+	live, err := fetchContainerGroup(ctx, target.resourceGroup, name)
+	if err != nil {
+		return "", nil, err
+	}
+	if live == nil {
+		return "", nil, fmt.Errorf("container group %s not found in %s", name, target.resourceGroup)
+	}
+	var group struct {
+		ID         string `json:"id"`
+		Containers []struct {
+			Name string `json:"name"`
+		} `json:"containers"`
+	}
+	if err := json.Unmarshal(live, &group); err != nil {
+		return "", nil, fmt.Errorf("failed to parse container group %s: %w", name, err)
+	}
+	containers := make([]string, 0, len(group.Containers))
+	for _, c := range group.Containers {
+		containers = append(containers, c.Name)
+	}
+	return group.ID, containers, nil
+}
+
+// selectContainers checks the requested containers exist; none requested selects all
+func selectContainers(available, requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return available, nil
+	}
+	for _, name := range requested {
+		if !slices.Contains(available, name) {
+			return nil, fmt.Errorf("container %q not found (available: %s)", name, strings.Join(available, ", "))
+		}
+	}
+	return requested, nil
+}
+
+// streamLogs prints the containers' output, then keeps polling for new lines with --follow
+func streamLogs(ctx context.Context, w io.Writer, groupID string, containers []string, opts *logsOptions) error {
+	var since time.Time
+	if opts.since > 0 {
+		since = time.Now().Add(-opts.since)
+	}
+	width := 0
+	for _, name := range containers {
+		width = max(width, len(name))
+	}
+
+	// cursors track the last line of each container already written
+	cursors := make(map[string]*aci.LogCursor, len(containers))
+	for _, name := range containers {
+		cursors[name] = &aci.LogCursor{}
+	}
+	for {
+		var batch []aci.LogLine
+		for _, name := range containers {
+			content, err := fetchContainerLogs(ctx, groupID, name)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+			lines := cursors[name].Next(aci.ParseLogs(name, content))
+			batch = append(batch, newerThan(lines, since)...)
+		}
+
+		for _, line := range aci.MergeLogs(batch) {
+			if err := writeLogLine(w, line, opts.output, width); err != nil {
+				return err
+			}
+		}
+		if !opts.follow {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(logPollInterval):
+		}
+	}
+}
+
+// newerThan drops lines older than since; lines without a timestamp go with the line before
+func newerThan(lines []aci.LogLine, since time.Time) []aci.LogLine {
+	if since.IsZero() {
+		return lines
+	}
+	kept := lines[:0:0]
+	keep := false
+	for _, line := range lines {
+		if !line.Time.IsZero() {
+			keep = !line.Time.Before(since)
+		}
+		if keep {
+			kept = append(kept, line)
+		}
+	}
+	return kept
+}
+
+// fetchContainerLogs reads a container's output with timestamps through the ARM API
+func fetchContainerLogs(ctx context.Context, groupID, container string) (string, error) {
+	logsURL := fmt.Sprintf("%s/containers/%s/logs?api-version=%s&timestamps=true",
+		groupID, url.PathEscape(container), containerLogsAPIVersion)
+	out, err := runx.AZOutput(ctx, "rest", "--method", "get", "--url", logsURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to read logs of container %s: %w", container, err)
+	}
+	var logs struct {
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal([]byte(out), &logs); err != nil {
+		return "", fmt.Errorf("failed to parse logs of container %s: %w", container, err)
+	}
+	return logs.Content, nil
+}
+
+// writeLogLine writes a line as "[container] message" or as a JSON object
+func writeLogLine(w io.Writer, line aci.LogLine, output string, width int) error {
+	if output == "json" {
+		record := struct {
+			Time      string `json:"time,omitempty"`
+			Container string `json:"container"`
+			Message   string `json:"message"`
+		}{Container: line.Container, Message: line.Message}
+		if !line.Time.IsZero() {
+			record.Time = line.Time.Format(time.RFC3339Nano)
+		}
+		return json.NewEncoder(w).Encode(record) //nolint:wrapcheck // writer errors need no extra context
+	}
+	_, err := fmt.Fprintf(w, "%-*s %s\n", width+2, "["+line.Container+"]", line.Message)
+	return err //nolint:wrapcheck // writer errors need no extra context
+}
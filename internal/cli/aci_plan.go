@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/furiatona/azctl/internal/aci"
+	"github.com/furiatona/azctl/internal/logging"
+	"github.com/furiatona/azctl/internal/runx"
+)
+
+func newACIPlanCmd(flags *aciFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "plan",
+		Short: "Show what deploying would change in the live container group",
+		Long: `Render the container group template and compare it field by field with the live
+container group. Secrets are masked, fields Azure fills in are ignored, and reordered
+containers, volumes, environment variables and ports are not reported as changes.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			target, err := flags.resolve(cmd)
+			if err != nil {
+				return err
+			}
+			plan, err := planACI(cmd.Context(), target, target.manifest.JSON)
+			if err != nil {
+				return err
+			}
+			if err := plan.Write(cmd.OutOrStdout()); err != nil {
+				return fmt.Errorf("failed to write plan: %w", err)
+			}
+			return nil
+		},
+	}
+}
+
+// planACI compares a container group definition with the live group; for blue/green
+// deployments that is the live color
+func planACI(ctx context.Context, target *aciTarget, definition string) (*aci.Plan, error) {
+	name, desired := target.groupName, []byte(definition)
+	if target.blueGreen {
+		state, err := findColors(ctx, target.resourceGroup, target.groupName)
+		if err != nil {
+			return nil, err
+		}
+		if state.live == "" {
+			return aci.Diff(desired, nil) //nolint:wrapcheck // Diff errors describe the document
+		}
+		if desired, err = aci.WithSuffix(desired, state.live); err != nil {
+			return nil, fmt.Errorf("failed to prepare %s group: %w", state.live, err)
+		}
+		name += "-" + state.live
+	}
+
+	live, err := fetchContainerGroup(ctx, target.resourceGroup, name)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := aci.Diff(desired, live)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare container groups: %w", err)
+	}
+	return plan, nil
+}
+
+// fetchContainerGroup returns the live container group, or nil if it or its resource group
+// does not exist. Any other failure is returned, so it is never mistaken for a missing group.
+func fetchContainerGroup(ctx context.Context, resourceGroup, name string) ([]byte, error) {
+	if name == "" {
+		return nil, fmt.Errorf("container group template has no name")
+	}
+	out, err := runx.AZOutput(ctx, "container", "show",
+		"--resource-group", resourceGroup, "--name", name, "--output", "json")
+	if runx.IsNotFound(err) {
+		logging.Debugf("Container group %s not found: %v", name, err)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up container group %s: %w", name, err)
+	}
+	return []byte(out), nil
+}
@@ -0,0 +1,187 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/furiatona/azctl/internal/aci"
+	"github.com/furiatona/azctl/internal/config"
+	"github.com/furiatona/azctl/internal/envvars"
+	"github.com/furiatona/azctl/internal/logging"
+	"github.com/furiatona/azctl/internal/templatex"
+)
+
+// aciManifest is a rendered container group template
+type aciManifest struct {
+	// JSON is the rendered group with the environment overlay applied; it is what gets
+	// validated, and is the source for any other output format
+	JSON string
+	// format is the template's format
+	format string
+	// source is the rendered template in its own format, kept while no overlay changed it
+	// so YAML comments and layout survive pass-through
+	source string
+}
+
+// renderACIManifest renders a JSON or YAML container group template and applies the
+// environment's overlay
+func renderACIManifest(cfg *config.Config, templatePath, envName string) (*aciManifest, error) {
+	raw, err := os.ReadFile(templatePath) //nolint:gosec // templatePath is validated
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template file: %w", err)
+	}
+
+	m := &aciManifest{format: aci.FormatFromPath(templatePath)}
+	m.JSON, m.source, err = renderManifestFile(cfg, templatePath, string(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply the per-environment overlay (e.g. aci.prod.json) on top of the base template
+	merged, err := applyOverlay(cfg, templatePath, envName, m.JSON)
+	if err != nil {
+		return nil, err
+	}
+	if merged != m.JSON {
+		m.JSON = merged
+		m.source = ""
+	}
+
+	probes, err := aci.ProbesFromConfig(cfg)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // ProbesFromConfig names the setting
+	}
+	withProbes, added, err := aci.ApplyProbes([]byte(m.JSON), probes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply probes: %w", err)
+	}
+	if string(withProbes) != m.JSON {
+		if len(added) > 0 {
+			logging.Infof("🩺 Adding %s", strings.Join(added, ", "))
+		}
+		m.JSON = string(withProbes)
+		m.source = ""
+	}
+
+	if network := aci.NetworkFromConfig(cfg); network != nil {
+		private, err := aci.UsePrivateNetwork([]byte(m.JSON), network)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply private network (ACI_SUBNET_IDS): %w", err)
+		}
+		logging.Infof("🔌 Deploying into subnet %s with a private IP", strings.Join(network.SubnetIDs, ", "))
+		m.JSON = string(private)
+		m.source = ""
+	}
+
+	if pullsWithIdentity(cfg) {
+		if m.JSON, err = useRegistryIdentity(cfg, m.JSON); err != nil {
+			return nil, err
+		}
+		m.source = ""
+	}
+
+	// Templates may set secrets as plain values, e.g. "value": "{{ env "SUPABASE_KEY" }}"
+	secured, moved, err := aci.SecureEnvironment([]byte(m.JSON), envvars.ClassifierFromConfig(cfg).IsSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to secure environment variables: %w", err)
+	}
+	if len(moved) > 0 {
+		logging.Infof("🔒 Passing %s as secure environment variables", strings.Join(moved, ", "))
+		m.JSON = string(secured)
+		m.source = ""
+	}
+
+	if err := m.stampSecrets(cfg); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// stampSecrets records an HMAC of the group's secrets, keyed with ACI_SECRETS_HASH_KEY, in
+// aci.SecretsTag. Azure never returns secret values, so the tag is how a plan notices a
+// rotated secret. Without a key no tag is written.
+func (m *aciManifest) stampSecrets(cfg *config.Config) error {
+	key := cfg.Get("ACI_SECRETS_HASH_KEY")
+	if key == "" {
+		logging.Debugf("ACI_SECRETS_HASH_KEY is not set; plans will not detect rotated secrets")
+		return nil
+	}
+	hash, err := aci.SecretsHash([]byte(m.JSON), []byte(key))
+	if err != nil || hash == "" {
+		return err //nolint:wrapcheck // SecretsHash errors already describe the document
+	}
+	stamped, err := aci.SetTag([]byte(m.JSON), aci.FormatJSON, aci.SecretsTag, hash)
+	if err != nil {
+		return fmt.Errorf("failed to tag container group: %w", err)
+	}
+	m.JSON = string(stamped)
+
+	// Edit YAML sources in place to keep their comments; JSON is re-encoded from m.JSON
+	if m.format != aci.FormatYAML || m.source == "" {
+		m.source = ""
+		return nil
+	}
+	source, err := aci.SetTag([]byte(m.source), aci.FormatYAML, aci.SecretsTag, hash)
+	if err != nil {
+		return fmt.Errorf("failed to tag container group: %w", err)
+	}
+	m.source = string(source)
+	return nil
+}
+
+// renderManifestFile renders a template and returns it as JSON and in its own format.
+// JSON templates escape each value for its JSON context; YAML templates reject values that
+// change the document structure and are then checked by converting them to JSON.
+func renderManifestFile(cfg *config.Config, path, content string) (jsonDoc, source string, err error) {
+	if aci.FormatFromPath(path) == aci.FormatJSON {
+		rendered, err := templatex.RenderJSON(content, cfg)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to render template %s: %w", path, err)
+		}
+		return rendered, rendered, nil
+	}
+
+	rendered, err := templatex.RenderYAML(content, cfg)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render template %s: %w", path, err)
+	}
+	converted, err := aci.YAMLToJSON([]byte(rendered))
+	if err != nil {
+		return "", "", fmt.Errorf("rendered template %s: %w", path, err)
+	}
+	return string(converted), rendered, nil
+}
+
+// Redacted returns the manifest in the given format with every secret replaced by
+// aci.SecretPlaceholder
+func (m *aciManifest) Redacted(format string) (string, error) {
+	if format == "" {
+		format = m.format
+	}
+	stripped, err := aci.StripSecrets([]byte(m.JSON))
+	if err != nil {
+		return "", fmt.Errorf("failed to redact container group: %w", err)
+	}
+	out, err := aci.Convert(stripped, format)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert container group: %w", err)
+	}
+	return string(out), nil
+}
+
+// Encode returns the manifest in the given format, passing the rendered template through
+// unchanged when it is already in that format
+func (m *aciManifest) Encode(format string) (string, error) {
+	if format == "" {
+		format = m.format
+	}
+	if format == m.format && m.source != "" {
+		return m.source, nil
+	}
+	out, err := aci.Convert([]byte(m.JSON), format)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert container group: %w", err)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,268 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/furiatona/azctl/internal/config"
+	"github.com/furiatona/azctl/internal/health"
+	"github.com/furiatona/azctl/internal/logging"
+	"github.com/furiatona/azctl/internal/runx"
+)
+
+// Exit codes of azctl aci status, for monitoring scripts
+const (
+	exitStatusDegraded = 2
+	exitStatusNotFound = 3
+)
+
+// maxStatusEvents is how many of the most recent events are reported
+const maxStatusEvents = 10
+
+// groupStatus is the output of azctl aci status
+type groupStatus struct {
+	Name          string `json:"name"`
+	ResourceGroup string `json:"resourceGroup"`
+	*health.Status
+	Image   *imageStatus `json:"image,omitempty"`
+	Healthy bool         `json:"healthy"`
+	Problem string       `json:"problem,omitempty"`
+}
+
+// imageStatus compares the app image running in the group with the configured one
+type imageStatus struct {
+	Configured       string `json:"configured"`
+	ConfiguredDigest string `json:"configuredDigest,omitempty"`
+	Running          string `json:"running"`
+	RunningDigest    string `json:"runningDigest,omitempty"`
+	UpToDate         bool   `json:"upToDate"`
+}
+
+func newACIStatusCmd(flags *aciFlags) *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the runtime state of the deployed container group",
+		Long: fmt.Sprintf(`Show the provisioning state, the state of every container, recent events, the
+group's address and whether the app image running matches the configured IMAGE_TAG.
+
+Exits with code %d when the group is degraded and %d when it does not exist.`,
+			exitStatusDegraded, exitStatusNotFound),
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if output != "table" && output != "json" {
+				return fmt.Errorf("unsupported output format: %s (supported: table, json)", output)
+			}
+			target, err := flags.locateGroup(cmd)
+			if err != nil {
+				return err
+			}
+			status, err := aciStatus(cmd.Context(), target)
+			if err != nil {
+				return err
+			}
+
+			if output == "json" {
+				data, err := json.MarshalIndent(status, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to encode status: %w", err)
+				}
+				if _, err := fmt.Fprintln(cmd.OutOrStdout(), string(data)); err != nil {
+					return err //nolint:wrapcheck // writer errors need no extra context
+				}
+			} else if err := writeStatus(cmd.OutOrStdout(), status); err != nil {
+				return err
+			}
+
+			if !status.Healthy {
+				return &ExitError{Code: exitStatusDegraded,
+					Err: fmt.Errorf("container group %s is degraded: %s", status.Name, status.Problem)}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format (table, json)")
+	return cmd
+}
+
+// aciStatus reads the runtime state of the deployed container group
+func aciStatus(ctx context.Context, target *aciTarget) (*groupStatus, error) {
+	name, err := liveGroupName(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	live, err := fetchContainerGroup(ctx, target.resourceGroup, name)
+	if err != nil {
+		return nil, err
+	}
+	if live == nil {
+		return nil, &ExitError{Code: exitStatusNotFound,
+			Err: fmt.Errorf("container group %s not found in %s", name, target.resourceGroup)}
+	}
+	status, err := health.ParseStatus(live)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read status of %s: %w", name, err)
+	}
+
+	sort.SliceStable(status.Events, func(i, j int) bool {
+		return status.Events[i].LastSeen.After(status.Events[j].LastSeen)
+	})
+	if len(status.Events) > maxStatusEvents {
+		status.Events = status.Events[:maxStatusEvents]
+	}
+
+	report := &groupStatus{Name: name, ResourceGroup: target.resourceGroup, Status: status, Healthy: true}
+	switch {
+	case status.Failure() != nil:
+		report.Problem = status.Failure().Error()
+	case !strings.EqualFold(status.ProvisioningState, "Succeeded"):
+		report.Problem = "provisioning state is " + status.ProvisioningState
+	case !status.Running():
+		report.Problem = "containers not running (" + status.Summary() + ")"
+	}
+	report.Healthy = report.Problem == ""
+	report.Image = compareImages(ctx, target.cfg, status)
+	return report, nil
+}
+
+// compareImages finds the app container, the one running IMAGE_NAME or named app, and compares
+// its image with ACR_REGISTRY/IMAGE_NAME:IMAGE_TAG. Digests are looked up in ACR, so a tag that
+// was pushed again after the deployment is reported as out of date.
+func compareImages(ctx context.Context, cfg *config.Config, status *health.Status) *imageStatus {
+	registry := strings.TrimSuffix(cfg.Get("ACR_REGISTRY"), ".azurecr.io")
+	imageName, imageTag := cfg.Get("IMAGE_NAME"), cfg.Get("IMAGE_TAG")
+	if registry == "" || imageName == "" || imageTag == "" {
+		return nil
+	}
+
+	var app *health.ContainerStatus
+	for i, c := range status.Containers {
+		repository, _, _ := strings.Cut(c.Image[strings.Index(c.Image, "/")+1:], ":")
+		if repository == imageName {
+			app = &status.Containers[i]
+			break
+		}
+		if c.Name == "app" {
+			app = &status.Containers[i]
+		}
+	}
+	if app == nil {
+		return nil
+	}
+
+	image := &imageStatus{
+		Configured: fmt.Sprintf("%s.azurecr.io/%s:%s", registry, imageName, imageTag),
+		Running:    app.Image,
+	}
+	image.ConfiguredDigest = acrDigest(ctx, image.Configured)
+	image.RunningDigest = acrDigest(ctx, image.Running)
+	if image.ConfiguredDigest != "" && image.RunningDigest != "" {
+		image.UpToDate = image.ConfiguredDigest == image.RunningDigest
+	} else {
+		image.UpToDate = image.Configured == image.Running
+	}
+	return image
+}
+
+// acrDigest returns the digest of an ACR image reference, or "" if it cannot be resolved
+func acrDigest(ctx context.Context, image string) string {
+	if _, digest, ok := strings.Cut(image, "@"); ok {
+		return digest
+	}
+	host, repository, ok := strings.Cut(image, "/")
+	if !ok || !strings.HasSuffix(host, ".azurecr.io") {
+		return ""
+	}
+	out, err := runx.AZOutput(ctx, "acr", "repository", "show", "--name", strings.TrimSuffix(host, ".azurecr.io"),
+		"--image", repository, "--query", "digest", "--output", "tsv")
+	if err != nil {
+		logging.Debugf("Could not resolve the digest of %s: %v", image, err)
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+// writeStatus prints the status as a summary followed by container and event tables
+func writeStatus(w io.Writer, s *groupStatus) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Container group:\t%s (%s)\n", s.Name, s.ResourceGroup)
+	fmt.Fprintf(tw, "State:\t%s (provisioning: %s)\n", orDash(s.State), orDash(s.ProvisioningState))
+	if s.IP != "" || s.FQDN != "" {
+		ports := make([]string, 0, len(s.Ports))
+		for _, p := range s.Ports {
+			ports = append(ports, strconv.Itoa(p))
+		}
+		fmt.Fprintf(tw, "Address:\t%s %s (ports: %s)\n", s.IP, s.FQDN, orDash(strings.Join(ports, ", ")))
+	}
+	if s.Image != nil {
+		state := "up to date"
+		if !s.Image.UpToDate {
+			state = "out of date, configured " + s.Image.Configured
+		}
+		fmt.Fprintf(tw, "Image:\t%s %s (%s)\n", s.Image.Running, shortDigest(s.Image.RunningDigest), state)
+	}
+	if !s.Healthy {
+		fmt.Fprintf(tw, "Problem:\t%s\n", s.Problem)
+	}
+
+	fmt.Fprintln(tw, "\nCONTAINER\tSTATE\tRESTARTS\tEXIT\tPREVIOUS")
+	for _, c := range s.Containers {
+		previous := c.PreviousState
+		if c.PreviousExitCode != nil {
+			previous += fmt.Sprintf(" (exit %d)", *c.PreviousExitCode)
+		}
+		state := orDash(c.State)
+		if c.DetailStatus != "" {
+			state += ": " + c.DetailStatus
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", c.Name, state, c.RestartCount, exitCodeString(c.ExitCode), orDash(previous))
+	}
+	if err := tw.Flush(); err != nil {
+		return err //nolint:wrapcheck // writer errors need no extra context
+	}
+	if len(s.Events) == 0 {
+		return nil
+	}
+
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nLAST SEEN\tCONTAINER\tTYPE\tEVENT\tCOUNT\tMESSAGE")
+	for _, e := range s.Events {
+		seen := "-"
+		if !e.LastSeen.IsZero() {
+			seen = e.LastSeen.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n", seen, orDash(e.Container), e.Type, e.Name, e.Count, e.Message)
+	}
+	return tw.Flush() //nolint:wrapcheck // writer errors need no extra context
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func exitCodeString(code *int) string {
+	if code == nil {
+		return "-"
+	}
+	return strconv.Itoa(*code)
+}
+
+// shortDigest abbreviates sha256:<64 hex> to sha256:<12 hex>
+func shortDigest(digest string) string {
+	if len(digest) > len("sha256:")+12 {
+		return digest[:len("sha256:")+12]
+	}
+	return digest
+}
@@ -0,0 +1,809 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/furiatona/azctl/internal/aci"
+	"github.com/furiatona/azctl/internal/config"
+	"github.com/furiatona/azctl/internal/health"
+	"github.com/furiatona/azctl/internal/history"
+	"github.com/furiatona/azctl/internal/runx"
+	"github.com/furiatona/azctl/internal/traffic"
+	"github.com/furiatona/azctl/internal/validation"
+)
+
+// errGroupNotFound is how az container show fails for a group that does not exist
+var errGroupNotFound = errors.New("az command failed: exit status 3: (ResourceNotFound) " +
+	"The Resource 'Microsoft.ContainerInstance/containerGroups/api' under resource group 'rg' was not found.")
+
+func TestWritePrivateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aci-dry-run.json")
+	if err := os.WriteFile(path, []byte("{}"), 0o644); err != nil { //nolint:gosec // the mode under test
+		t.Fatal(err)
+	}
+	if err := writePrivateFile(path, []byte(`{"secureValue":"s3cret"}`)); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("expected mode 0600, got %o", mode)
+	}
+}
+
+func TestOverlayPath(t *testing.T) {
+	if got := overlayPath("deploy/manifests/aci.json", "prod"); got != "deploy/manifests/aci.prod.json" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestApplyOverlay(t *testing.T) {
+	dir := t.TempDir()
+	templatePath := filepath.Join(dir, "aci.json")
+	base := `{"name":"api","properties":{"restartPolicy":"Always","containers":[{"name":"app"}]}}`
+
+	cfg := config.New()
+	cfg.Set("SIDECAR_IMAGE", "myregistry.azurecr.io/otel:1.0")
+
+	// No overlay for dev: the base is returned unchanged
+	got, err := applyOverlay(cfg, templatePath, "dev", base)
+	if err != nil || got != base {
+		t.Fatalf("expected unchanged base, got %q (err %v)", got, err)
+	}
+
+	// Merge patch for staging
+	mergePatch := `{"properties":{"restartPolicy":"OnFailure"}}`
+	if err := os.WriteFile(filepath.Join(dir, "aci.staging.json"), []byte(mergePatch), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err = applyOverlay(cfg, templatePath, "staging", base)
+	if err != nil {
+		t.Fatalf("merge overlay failed: %v", err)
+	}
+	group, err := aci.Parse([]byte(got))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group.Properties.RestartPolicy != "OnFailure" || len(group.Properties.Containers) != 1 {
+		t.Errorf("unexpected merged group: %+v", group.Properties)
+	}
+
+	// JSON patch for prod, rendered against the configuration
+	jsonPatch := `[{"op":"add","path":"/properties/containers/-",` +
+		`"value":{"name":"otel","properties":{"image":"{{ env "SIDECAR_IMAGE" }}"}}}]`
+	if err := os.WriteFile(filepath.Join(dir, "aci.prod.json"), []byte(jsonPatch), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err = applyOverlay(cfg, templatePath, "prod", base)
+	if err != nil {
+		t.Fatalf("JSON patch overlay failed: %v", err)
+	}
+	group, err = aci.Parse([]byte(got))
+	if err != nil {
+		t.Fatal(err)
+	}
+	containers := group.Properties.Containers
+	if len(containers) != 2 || containers[1].Properties.Image != "myregistry.azurecr.io/otel:1.0" {
+		t.Errorf("unexpected patched containers: %+v", containers)
+	}
+
+	// A failing patch names the overlay file
+	if err := os.WriteFile(filepath.Join(dir, "aci.prod.json"),
+		[]byte(`[{"op":"remove","path":"/missing"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := applyOverlay(cfg, templatePath, "prod", base); err == nil ||
+		!strings.Contains(err.Error(), "aci.prod.json") {
+		t.Errorf("expected error naming the overlay, got %v", err)
+	}
+}
+
+func TestTemplateKeyIssues(t *testing.T) {
+	dir := t.TempDir()
+	templatePath := filepath.Join(dir, "aci.json")
+	template := `{
+  "name": "{{ env "CONTAINER_GROUP_NAME" }}",
+  "port": {{ env "PORT" }},
+  "tier": "{{ envOr "TIER" "basic" }}"{{ if hasKey "SENTRY" }},
+  "sentry": "{{ env "SENTRY_DSN" }}"{{ end }}
+}`
+	if err := os.WriteFile(templatePath, []byte(template), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.New()
+	cfg.Set("CONTAINER_GROUP_NAME", "api")
+
+	issues, err := templateKeyIssues(cfg, templatePath, overlayPath(templatePath, "prod"))
+	if err != nil {
+		t.Fatalf("templateKeyIssues failed: %v", err)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("expected 2 issues, got %+v", issues)
+	}
+	if issues[0].Key != "PORT" || issues[0].Severity != validation.SeverityError || issues[0].Line != 3 {
+		t.Errorf("unexpected required issue: %+v", issues[0])
+	}
+	if issues[1].Key != "SENTRY_DSN" || issues[1].Severity != validation.SeverityWarning {
+		t.Errorf("unexpected conditional issue: %+v", issues[1])
+	}
+}
+
+func TestRenderACIManifestYAML(t *testing.T) {
+	dir := t.TempDir()
+	templatePath := filepath.Join(dir, "aci.yaml")
+	template := `# API container group
+name: {{ env "CONTAINER_GROUP_NAME" }}
+location: westeurope
+properties:
+  restartPolicy: Always
+  containers:
+    - name: app
+      properties:
+        image: {{ env "IMAGE" | toJson }}
+`
+	if err := os.WriteFile(templatePath, []byte(template), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "aci.prod.yaml"),
+		[]byte("properties:\n  restartPolicy: OnFailure\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.New()
+	cfg.Set("CONTAINER_GROUP_NAME", "api")
+	cfg.Set("IMAGE", "registry.azurecr.io/api:v1")
+
+	// Without an overlay the rendered YAML passes through, comments included
+	m, err := renderACIManifest(cfg, templatePath, "dev")
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	out, err := m.Encode("")
+	if err != nil || !strings.HasPrefix(out, "# API container group") {
+		t.Errorf("expected pass-through YAML, got %q (err %v)", out, err)
+	}
+	if out, err := m.Encode("json"); err != nil || !strings.HasPrefix(out, "{") {
+		t.Errorf("expected JSON output, got %q (err %v)", out, err)
+	}
+
+	// The prod overlay is applied to the YAML template
+	m, err = renderACIManifest(cfg, templatePath, "prod")
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	group, err := aci.Parse([]byte(m.JSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group.Properties.RestartPolicy != "OnFailure" || group.Properties.Containers[0].Properties.Image == "" {
+		t.Errorf("unexpected group: %+v", group.Properties)
+	}
+	if out, err := m.Encode(""); err != nil || !strings.Contains(out, "restartPolicy: OnFailure") {
+		t.Errorf("expected merged YAML, got %q (err %v)", out, err)
+	}
+}
+
+func TestRenderACIManifestYAMLRejectsStructuralValues(t *testing.T) {
+	templatePath := filepath.Join(t.TempDir(), "aci.yaml")
+	template := "name: api\nproperties:\n  osType: {{ env \"OS_TYPE\" }}\n"
+	if err := os.WriteFile(templatePath, []byte(template), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.New()
+	cfg.Set("OS_TYPE", "Linux # comment\nsku: Confidential")
+	_, err := renderACIManifest(cfg, templatePath, "dev")
+	if err == nil || !strings.Contains(err.Error(), "template line 3") || !strings.Contains(err.Error(), "OS_TYPE") {
+		t.Errorf("expected error naming line 3 and OS_TYPE, got %v", err)
+	}
+}
+
+func TestRenderACIManifestSecrets(t *testing.T) {
+	templatePath := filepath.Join(t.TempDir(), "aci.json")
+	template := `{"name":"api","location":"westeurope","properties":{"osType":"Linux","containers":[
+		{"name":"app","properties":{"image":"api:v1","environmentVariables":[
+			{"name":"SUPABASE_URL","value":"{{ env "SUPABASE_URL" }}"},
+			{"name":"SUPABASE_KEY","value":"{{ env "SUPABASE_KEY" }}"},
+			{"name":"INTERNAL_ID","value":"{{ env "INTERNAL_ID" }}"}]}}],
+		"imageRegistryCredentials":[{"server":"reg.azurecr.io","username":"reg","password":"registry-pw"}]}}`
+	if err := os.WriteFile(templatePath, []byte(template), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.New()
+	cfg.Set("SUPABASE_URL", "https://db.example.com")
+	cfg.Set("SUPABASE_KEY", "service-role")
+	cfg.Set("INTERNAL_ID", "tenant-42")
+	cfg.Set("ACI_SECRET_KEYS", "INTERNAL_ID")
+
+	m, err := renderACIManifest(cfg, templatePath, "dev")
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	group, err := aci.Parse([]byte(m.JSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := group.Properties.Containers[0].Properties.EnvironmentVariables
+	if vars[0].Value == "" || vars[1].SecureValue != "service-role" || vars[2].SecureValue != "tenant-42" {
+		t.Errorf("unexpected variables: %+v", vars)
+	}
+
+	redacted, err := m.Redacted("yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"service-role", "tenant-42", "registry-pw"} {
+		if strings.Contains(redacted, secret) {
+			t.Errorf("redacted output contains %q:\n%s", secret, redacted)
+		}
+	}
+	if !strings.Contains(redacted, "https://db.example.com") {
+		t.Errorf("expected plain values to be kept:\n%s", redacted)
+	}
+}
+
+func TestRenderACIManifestPrivateNetwork(t *testing.T) {
+	const subnet = "/subscriptions/s/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/aci"
+	templatePath := filepath.Join(t.TempDir(), "aci.json")
+	template := `{"name":"api","location":"westeurope","properties":{"osType":"Linux",
+		"ipAddress":{"type":"Public","dnsNameLabel":"{{ envOr "DNS_NAME_LABEL" "" }}","ports":[{"port":8080}]},
+		"containers":[{"name":"app","properties":{"image":"api:v1","ports":[{"port":8080}]}}]}}`
+	if err := os.WriteFile(templatePath, []byte(template), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.New()
+	cfg.Set("DNS_NAME_LABEL", "api-dev")
+	cfg.Set("ACI_NETWORK_MODE", "private")
+	cfg.Set("ACI_SUBNET_IDS", subnet)
+
+	m, err := renderACIManifest(cfg, templatePath, "dev")
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	group, err := aci.Parse([]byte(m.JSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ip := group.Properties.IPAddress
+	if ip.Type != "Private" || ip.DNSNameLabel != "" || len(group.Properties.SubnetIDs) != 1 {
+		t.Errorf("expected a private group in %s, got %s", subnet, m.JSON)
+	}
+	if group.Properties.DNSConfig != nil {
+		t.Errorf("DNS config must only be set with ACI_DNS_SERVERS: %+v", group.Properties.DNSConfig)
+	}
+}
+
+func TestPlanACI(t *testing.T) {
+	desired := `{"name":"api","location":"westeurope","properties":{"osType":"Linux","containers":[` +
+		`{"name":"app","properties":{"image":"api:v2",` +
+		`"environmentVariables":[{"name":"DB_PASSWORD","secureValue":"s3cret"}]}}]}}`
+	m := &aciManifest{JSON: desired, format: aci.FormatJSON}
+	cfg := config.New()
+	if err := m.stampSecrets(cfg); err != nil || strings.Contains(m.JSON, aci.SecretsTag) {
+		t.Fatalf("expected no secrets tag without ACI_SECRETS_HASH_KEY, got %s (err %v)", m.JSON, err)
+	}
+	cfg.Set("ACI_SECRETS_HASH_KEY", "hash-key")
+	if err := m.stampSecrets(cfg); err != nil {
+		t.Fatal(err)
+	}
+	target := &aciTarget{resourceGroup: "rg", groupName: "api", manifest: m}
+
+	// Flattened az container show output without the secret value
+	live := `{"name":"api","location":"West Europe","osType":"linux","provisioningState":"Succeeded",` +
+		`"tags":{"azctl-secrets":"stale"},"containers":[` +
+		`{"name":"app","image":"api:v1","environmentVariables":[{"name":"DB_PASSWORD","secureValue":null}]}]}`
+	fake := runx.NewFake().On("container show", live, nil)
+	defer runx.SetExecutor(fake)()
+
+	plan, err := planACI(context.Background(), target, m.JSON)
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	var paths []string
+	for _, c := range plan.Changes {
+		paths = append(paths, c.Path)
+	}
+	want := []string{"properties.containers[app].properties.image", "tags.azctl-secrets"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("changes = %v, want %v", paths, want)
+	}
+	if !fake.Called("container show --resource-group rg --name api") {
+		t.Errorf("live group not fetched: %v", fake.Calls())
+	}
+
+	// A group that does not exist is planned for creation
+	fake.On("container show", "", errGroupNotFound)
+	plan, err = planACI(context.Background(), target, m.JSON)
+	if err != nil || !plan.Create {
+		t.Errorf("expected create plan, got %+v (err %v)", plan, err)
+	}
+
+	// Any other failure is not mistaken for a missing group
+	fake.On("container show", "", errors.New("az command failed: exit status 1: AADSTS700082: token expired"))
+	if plan, err = planACI(context.Background(), target, m.JSON); err == nil {
+		t.Errorf("expected the lookup error, got %+v", plan)
+	}
+}
+
+func TestHealthFlagsResolve(t *testing.T) {
+	var f healthFlags
+	cmd := &cobra.Command{}
+	f.register(cmd)
+	if err := cmd.Flags().Parse([]string{"--health-status", "204"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.New()
+	cfg.Set("ACI_HEALTH_PATH", "/healthz")
+	cfg.Set("ACI_HEALTH_STATUS", "200")
+	cfg.Set("ACI_HEALTH_TIMEOUT", "90s")
+	if err := f.resolve(cmd, cfg); err != nil {
+		t.Fatal(err)
+	}
+	// Flags win over configuration
+	if f.path != "/healthz" || f.status != 204 || f.timeout != 90*time.Second {
+		t.Errorf("unexpected health flags: %+v", f)
+	}
+
+	cfg.Set("ACI_HEALTH_TIMEOUT", "soon")
+	if err := f.resolve(cmd, cfg); err == nil {
+		t.Error("expected an invalid ACI_HEALTH_TIMEOUT error")
+	}
+}
+
+func TestRevisionRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store := history.NewLocalStore(t.TempDir())
+	cfg := config.New()
+	cfg.Set("ACI_SECRETS_HASH_KEY", "hash-key")
+	target := &aciTarget{cfg: cfg, resourceGroup: "rg", groupName: "api", envName: "prod"}
+
+	deployed := func(image, password string) string {
+		return `{"name":"api","properties":{"containers":[{"name":"app","properties":{"image":"` + image +
+			`","environmentVariables":[{"name":"DB_PASSWORD","secureValue":"` + password + `"}]}}]}}`
+	}
+	for _, image := range []string{"api:v1", "api:v2"} {
+		if _, err := recordRevision(ctx, store, target, deployed(image, "old-secret"), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Secrets never reach the store
+	revisions, err := store.List(ctx, "rg", "api")
+	if err != nil || len(revisions) != 2 {
+		t.Fatalf("expected 2 revisions, got %d (err %v)", len(revisions), err)
+	}
+	if strings.Contains(string(revisions[0].Definition), "old-secret") || revisions[1].Image != "api:v2" {
+		t.Errorf("unexpected revision: %+v", revisions[0])
+	}
+
+	// Rolling back picks the previous revision and resolves secrets from the current render
+	rev, err := selectRevision(ctx, store, target, 0)
+	if err != nil || rev.Number != 1 {
+		t.Fatalf("expected revision 1, got %+v (err %v)", rev, err)
+	}
+	target.manifest = &aciManifest{JSON: deployed("api:v3", "rotated"), format: aci.FormatJSON}
+	definition, err := restoreRevision(target, rev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	group, err := aci.Parse([]byte(definition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	container := group.Properties.Containers[0].Properties
+	if container.Image != "api:v1" || container.EnvironmentVariables[0].SecureValue != "rotated" {
+		t.Errorf("unexpected restored container: %+v", container)
+	}
+	if group.Tags[aci.SecretsTag] == "" {
+		t.Error("expected the secrets hash to be stamped")
+	}
+
+	if _, err := selectRevision(ctx, store, target, 7); err == nil {
+		t.Error("expected an error for a missing revision")
+	}
+
+	// The config hash does not depend on secret values, only on the keyed secrets tag
+	hashOf := func(definition string) string {
+		rev, err := recordRevision(ctx, store, target, definition, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rev.ConfigHash
+	}
+	tagged := func(password, tag string) string {
+		return strings.Replace(deployed("api:v1", password), `"name":"api",`,
+			`"name":"api","tags":{"`+aci.SecretsTag+`":"`+tag+`"},`, 1)
+	}
+	if hashOf(deployed("api:v1", "guess-1")) != hashOf(deployed("api:v1", "guess-2")) {
+		t.Error("expected the config hash to ignore secret values")
+	}
+	if hashOf(tagged("old-secret", "aaaa")) == hashOf(tagged("rotated", "bbbb")) {
+		t.Error("expected a rotated secret to change the config hash through the secrets tag")
+	}
+}
+
+func TestDeployMode(t *testing.T) {
+	cfg := config.New()
+	if mode, err := deployMode(cfg, ""); err != nil || mode != deployModeCLI {
+		t.Errorf("default mode = %q (err %v)", mode, err)
+	}
+	cfg.Set("ACI_DEPLOY_MODE", deployModeARM)
+	if mode, err := deployMode(cfg, ""); err != nil || mode != deployModeARM {
+		t.Errorf("ACI_DEPLOY_MODE=arm gave %q (err %v)", mode, err)
+	}
+	if mode, err := deployMode(cfg, deployModeCLI); err != nil || mode != deployModeCLI {
+		t.Errorf("--mode=cli gave %q (err %v)", mode, err)
+	}
+	if _, err := deployMode(cfg, "bicep"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
+
+// fakeACI is an executor holding container groups in memory, so groups created during a
+// test show up in later `az container show` calls
+type fakeACI struct {
+	*runx.Fake
+	groups map[string]string
+	// state is the container state of created groups
+	state string
+}
+
+func (f *fakeACI) Output(ctx context.Context, args ...string) (string, error) {
+	_, _ = f.Fake.Output(ctx, args...) // record the call
+	flag := func(name string) string {
+		for i := range args[:len(args)-1] {
+			if args[i] == name {
+				return args[i+1]
+			}
+		}
+		return ""
+	}
+	switch strings.Join(args[:2], " ") {
+	case "container show":
+		if group, ok := f.groups[flag("--name")]; ok {
+			return group, nil
+		}
+		return "", errGroupNotFound
+	case "container create":
+		data, err := os.ReadFile(flag("--file"))
+		if err != nil {
+			return "", err
+		}
+		group, err := aci.Parse(data)
+		if err != nil {
+			return "", err
+		}
+		f.groups[group.Name] = fmt.Sprintf(`{"name":%q,"ipAddress":{"ip":"20.0.0.1","fqdn":"%s.azurecontainer.io"},`+
+			`"containers":[{"name":"app","instanceView":{"currentState":{"state":%q}}}]}`, group.Name, group.Name, f.state)
+	case "container delete":
+		delete(f.groups, flag("--name"))
+	}
+	return "", nil
+}
+
+func (f *fakeACI) Run(ctx context.Context, args ...string) error {
+	_, err := f.Output(ctx, args...)
+	return err
+}
+
+type recordingSwitcher struct{ targets []traffic.Endpoint }
+
+func (s *recordingSwitcher) Name() string { return "test switcher" }
+
+func (s *recordingSwitcher) Switch(_ context.Context, target traffic.Endpoint) error {
+	s.targets = append(s.targets, target)
+	return nil
+}
+
+func TestDeployBlueGreen(t *testing.T) {
+	defer func(d time.Duration) { health.PollInterval = d }(health.PollInterval)
+	health.PollInterval = time.Millisecond
+
+	definition := `{"name":"api","location":"westeurope","properties":{"osType":"Linux",` +
+		`"ipAddress":{"type":"Public","dnsNameLabel":"api","ports":[]},"containers":[{"name":"app"}]}}`
+	target := &aciTarget{cfg: config.New(), resourceGroup: "rg", groupName: "api"}
+	deploy := func(ctx context.Context, doc string) error { return createContainerGroup(ctx, "rg", doc) }
+	hc := &healthFlags{timeout: time.Second}
+
+	// Blue is live: green is created, traffic moves to it, then blue is deleted
+	cloud := &fakeACI{Fake: runx.NewFake(), groups: map[string]string{"api-blue": `{"name":"api-blue"}`}, state: "Running"}
+	defer runx.SetExecutor(cloud)()
+	switcher := &recordingSwitcher{}
+	if err := deployBlueGreen(context.Background(), target, definition, switcher, deploy, hc); err != nil {
+		t.Fatalf("blue/green failed: %v", err)
+	}
+	if len(switcher.targets) != 1 || switcher.targets[0].FQDN != "api-green.azurecontainer.io" {
+		t.Errorf("unexpected switch: %+v", switcher.targets)
+	}
+	if _, ok := cloud.groups["api-blue"]; ok {
+		t.Error("expected the previous group to be deleted")
+	}
+	if _, ok := cloud.groups["api-green"]; !ok {
+		t.Error("expected the new group to be kept")
+	}
+
+	// An unhealthy group is deleted before traffic moves
+	cloud.state = "Terminated"
+	cloud.groups = map[string]string{"api-green": `{"name":"api-green"}`}
+	switcher = &recordingSwitcher{}
+	err := deployBlueGreen(context.Background(), target, definition, switcher, deploy, &healthFlags{timeout: 0})
+	if err == nil || len(switcher.targets) != 0 {
+		t.Fatalf("expected an unhealthy deployment to stop before switching, got %v", err)
+	}
+	if _, ok := cloud.groups["api-blue"]; ok {
+		t.Error("expected the unhealthy group to be deleted")
+	}
+	if _, ok := cloud.groups["api-green"]; !ok {
+		t.Error("expected the live group to keep serving")
+	}
+	// A create that fails after the group exists deletes it, so later runs are not blocked
+	cloud.state = "Running"
+	failAfterCreate := func(ctx context.Context, doc string) error {
+		if err := createContainerGroup(ctx, "rg", doc); err != nil {
+			return err
+		}
+		return errors.New("provisioning state Failed")
+	}
+	err = deployBlueGreen(context.Background(), target, definition, &recordingSwitcher{}, failAfterCreate, hc)
+	if err == nil || !strings.Contains(err.Error(), "provisioning state Failed") {
+		t.Fatalf("expected the create error, got %v", err)
+	}
+	if _, ok := cloud.groups["api-blue"]; ok {
+		t.Error("expected the partly created group to be deleted")
+	}
+
+	// A create that fails before the group exists deletes nothing
+	cloud.Fake = runx.NewFake()
+	failBeforeCreate := func(context.Context, string) error { return errors.New("quota exceeded") }
+	err = deployBlueGreen(context.Background(), target, definition, &recordingSwitcher{}, failBeforeCreate, hc)
+	if err == nil || cloud.Called("container delete") {
+		t.Errorf("expected an error without a delete, got %v (calls %v)", err, cloud.Calls())
+	}
+	if _, ok := cloud.groups["api-green"]; !ok {
+		t.Error("expected the live group to keep serving")
+	}
+}
+
+// slowExecutor delays every command, like a slow az call
+type slowExecutor struct {
+	runx.Executor
+	delay time.Duration
+}
+
+func (e slowExecutor) Output(ctx context.Context, args ...string) (string, error) {
+	time.Sleep(e.delay)
+	return e.Executor.Output(ctx, args...)
+}
+
+func TestVerifyACIHealthSharesTimeout(t *testing.T) {
+	defer func(d time.Duration) { health.PollInterval = d }(health.PollInterval)
+	health.PollInterval = time.Millisecond
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	fake := runx.NewFake().On("container show", `{"name":"api","ipAddress":{"ip":"127.0.0.1"},`+
+		`"containers":[{"name":"app","instanceView":{"currentState":{"state":"Running"}}}]}`, nil)
+	defer runx.SetExecutor(slowExecutor{Executor: fake, delay: 400 * time.Millisecond})()
+
+	cfg := config.New()
+	cfg.Set("ACI_NETWORK_MODE", aci.NetworkPrivate)
+	cfg.Set("ACI_PORT", server.URL[strings.LastIndex(server.URL, ":")+1:])
+	f := &healthFlags{path: "/healthz", status: http.StatusOK, timeout: 500 * time.Millisecond}
+
+	// The probe only gets what is left of --health-timeout after the containers are running
+	start := time.Now()
+	err := verifyACIHealth(context.Background(), cfg, "rg", "api", "", f)
+	if err == nil || !strings.Contains(err.Error(), "returned 503") {
+		t.Fatalf("expected the probe to fail, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 800*time.Millisecond {
+		t.Errorf("health check took %s, longer than --health-timeout allows", elapsed)
+	}
+}
+
+func TestBlueGreenSwitcher(t *testing.T) {
+	target := &aciTarget{cfg: config.New(), resourceGroup: "rg", groupName: "api"}
+	if _, err := blueGreenSwitcher(target); err == nil || !strings.Contains(err.Error(), "TRAFFIC_SWITCHER=none") {
+		t.Errorf("expected blue/green without a switcher to be refused, got %v", err)
+	}
+	target.switcher = "none"
+	if s, err := blueGreenSwitcher(target); err != nil || s.Name() != "no traffic switch" {
+		t.Errorf("expected an explicit none to be accepted, got %v (err %v)", s, err)
+	}
+}
+
+func TestStreamLogs(t *testing.T) {
+	now := time.Now().UTC()
+	stamp := func(ago time.Duration) string { return now.Add(-ago).Format(time.RFC3339Nano) }
+	id := "/subscriptions/s/resourceGroups/rg/providers/Microsoft.ContainerInstance/containerGroups/api"
+	fake := runx.NewFake().
+		On("container show", `{"id":"`+id+`","containers":[{"name":"app"},{"name":"fluentbit"}]}`, nil).
+		On("rest --method get --url "+id+"/containers/app/",
+			`{"content":"`+stamp(time.Hour)+` old\n`+stamp(3*time.Second)+` ready\n"}`, nil).
+		On("rest --method get --url "+id+"/containers/fluentbit/",
+			`{"content":"`+stamp(5*time.Second)+` [info] started\n"}`, nil)
+	defer runx.SetExecutor(fake)()
+
+	target := &aciTarget{resourceGroup: "rg", groupName: "api"}
+	groupID, containers, err := liveContainers(context.Background(), target)
+	if err != nil || groupID != id || strings.Join(containers, ",") != "app,fluentbit" {
+		t.Fatalf("unexpected group %q %v (err %v)", groupID, containers, err)
+	}
+	if _, err := selectContainers(containers, []string{"worker"}); err == nil {
+		t.Error("expected an unknown container to be rejected")
+	}
+
+	var out strings.Builder
+	if err := streamLogs(context.Background(), &out, groupID, containers, &logsOptions{since: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+	want := "[fluentbit] [info] started\n[app]       ready\n"
+	if out.String() != want {
+		t.Errorf("logs = %q, want %q", out.String(), want)
+	}
+
+	// Following prints each line once, however often the logs are polled
+	defer func(d time.Duration) { logPollInterval = d }(logPollInterval)
+	logPollInterval = time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	out.Reset()
+	opts := &logsOptions{containers: []string{"app"}, follow: true, output: "json"}
+	if err := streamLogs(ctx, &out, groupID, []string{"app"}, opts); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 2 ||
+		!strings.Contains(lines[1], `"container":"app","message":"ready"`) {
+		t.Errorf("unexpected followed logs: %q", out.String())
+	}
+}
+
+func TestACIStatus(t *testing.T) {
+	cfg := config.New()
+	cfg.Set("ACR_REGISTRY", "myacr")
+	cfg.Set("IMAGE_NAME", "api")
+	cfg.Set("IMAGE_TAG", "v2")
+	target := &aciTarget{cfg: cfg, resourceGroup: "rg", groupName: "api"}
+	group := `{"name":"api","provisioningState":"Succeeded","instanceView":{"state":"Running"},` +
+		`"ipAddress":{"ip":"20.1.2.3","fqdn":"api.westeurope.azurecontainer.io","ports":[{"port":8080}]},` +
+		`"containers":[{"name":"app","image":"myacr.azurecr.io/api:v1","instanceView":{"restartCount":1,` +
+		`"currentState":{"state":"%s"},"previousState":{"state":"Terminated","exitCode":137},` +
+		`"events":[{"type":"Warning","name":"Killing","message":"OOM","count":1,` +
+		`"lastTimestamp":"2024-05-01T10:00:00Z"}]}}]}`
+	fake := runx.NewFake().
+		On("container show", fmt.Sprintf(group, "Running"), nil).
+		On("acr repository show --name myacr --image api:v1", "sha256:1111111111111111\n", nil).
+		On("acr repository show --name myacr --image api:v2", "sha256:2222222222222222\n", nil)
+	defer runx.SetExecutor(fake)()
+
+	status, err := aciStatus(context.Background(), target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image := status.Image
+	if !status.Healthy || image == nil || image.UpToDate || image.RunningDigest != "sha256:1111111111111111" {
+		t.Errorf("unexpected status: %+v %+v", status, image)
+	}
+	var out strings.Builder
+	if err := writeStatus(&out, status); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"20.1.2.3", "out of date, configured myacr.azurecr.io/api:v2", "Terminated (exit 137)", "OOM",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("status output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	fake.On("container show", fmt.Sprintf(group, "Waiting"), nil)
+	if status, err := aciStatus(context.Background(), target); err != nil || status.Healthy {
+		t.Errorf("expected a degraded group, got %+v (err %v)", status, err)
+	}
+
+	fake.On("container show", "", errGroupNotFound)
+	if _, err := aciStatus(context.Background(), target); ExitCode(err) != exitStatusNotFound {
+		t.Errorf("expected exit code %d for a missing group, got %v", exitStatusNotFound, err)
+	}
+	// A failed lookup, e.g. an expired login, is an azctl failure rather than a missing group
+	fake.On("container show", "", errors.New("az command failed: exit status 1: Please run 'az login'"))
+	if _, err := aciStatus(context.Background(), target); ExitCode(err) != 1 {
+		t.Errorf("expected exit code 1 for a failed lookup, got %v", err)
+	}
+}
+
+func TestPlanTeardown(t *testing.T) {
+	cfg := config.New()
+	for key, value := range map[string]string{
+		"LOG_STORAGE_ACCOUNT": "logs", "LOG_STORAGE_KEY": "key", "FLUENTBIT_CONFIG": "fluentbit-config",
+		"LOG_STORAGE_NAME": "applogs", "IMAGE_NAME": "api",
+		"TRAFFIC_DNS_ZONE": "example.com", "TRAFFIC_DNS_RECORD": "api",
+	} {
+		cfg.Set(key, value)
+	}
+	target := &aciTarget{cfg: cfg, envName: "dev", resourceGroup: "rg", groupName: "api", switcher: "dns"}
+	fake := runx.NewFake().
+		On("container show", "", errGroupNotFound).
+		On("container show --resource-group rg --name api-green", `{"name":"api-green"}`, nil).
+		On("storage file exists", "true\n", nil).
+		On("container delete", "", nil).
+		On("storage file", "", nil).
+		On("network dns", "", nil)
+	defer runx.SetExecutor(fake)()
+
+	steps, err := planTeardown(context.Background(), target, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, step := range steps {
+		got = append(got, step.description)
+		if err := step.run(context.Background()); err != nil {
+			t.Fatalf("%s: %v", step.description, err)
+		}
+	}
+	want := []string{
+		"container group api-green",
+		"Fluent-bit config fluentbit-config/api.conf in storage account logs",
+		"all files in log share applogs in storage account logs",
+		"DNS record api.example.com",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("steps:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	for _, call := range []string{
+		"container delete --resource-group rg --name api-green",
+		"storage file delete --account-name logs --account-key key --share-name fluentbit-config --path api.conf",
+		"storage file delete-batch --account-name logs --account-key key --source applogs",
+		"network dns record-set cname delete",
+	} {
+		if !fake.Called(call) {
+			t.Errorf("expected %q, got %v", call, fake.Calls())
+		}
+	}
+}
+
+func TestConfirmTeardown(t *testing.T) {
+	dev := &aciTarget{envName: "dev", groupName: "api"}
+	prod := &aciTarget{envName: "prod", groupName: "api"}
+	tests := []struct {
+		target      *aciTarget
+		input       string
+		yes         bool
+		confirmName string
+		ok          bool
+	}{
+		{dev, "yes\n", false, "", true},
+		{dev, "y\n", false, "", false},
+		{dev, "", false, "", false},
+		{dev, "", true, "", true},
+		{prod, "yes\n", false, "", false},
+		{prod, "api\n", false, "", true},
+		{prod, "", true, "", false},
+		{prod, "", true, "api", true},
+	}
+	for i, tt := range tests {
+		err := confirmTeardown(strings.NewReader(tt.input), io.Discard, tt.target, tt.yes, tt.confirmName)
+		if (err == nil) != tt.ok {
+			t.Errorf("case %d (%s, %q, yes=%v): err = %v", i, tt.target.envName, tt.input, tt.yes, err)
+		}
+	}
+}
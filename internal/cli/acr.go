@@ -3,11 +3,13 @@ package cli
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/furiatona/azctl/internal/config"
 	"github.com/furiatona/azctl/internal/logging"
 	"github.com/furiatona/azctl/internal/runx"
+	"github.com/furiatona/azctl/internal/validation"
 
 	"github.com/spf13/cobra"
 )
@@ -44,17 +46,100 @@ func findACRResourceGroup(ctx context.Context, registryName string) string {
 }
 
 // collectBuildArgs collects NEXT_PUBLIC_* variables for build arguments
-func collectBuildArgs(cfg *config.Config) []string {
-	var buildArgs []string
+func collectBuildArgs(cfg *config.Config) map[string]string {
+	buildArgs := make(map[string]string)
 	for key, value := range cfg.GetAll() {
 		if strings.HasPrefix(key, "NEXT_PUBLIC_") {
-			buildArgs = append(buildArgs, "--build-arg", fmt.Sprintf("%s=%s", key, value))
-			logging.Debugf("Adding build arg: %s='%s'", key, value)
+			buildArgs[key] = value
+			logging.Debugf("Adding build arg: %s", key)
 		}
 	}
 	return buildArgs
 }
 
+// buildArgFlags formats build arguments as --build-arg flags in a stable order
+func buildArgFlags(buildArgs map[string]string) []string {
+	keys := make([]string, 0, len(buildArgs))
+	for key := range buildArgs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	flags := make([]string, 0, len(keys)*2)
+	for _, key := range keys {
+		flags = append(flags, "--build-arg", fmt.Sprintf("%s=%s", key, buildArgs[key]))
+	}
+	return flags
+}
+
+// buildSecretKeys returns the keys passed as BuildKit secrets via --secret or BUILD_SECRETS
+func buildSecretKeys(cfg *config.Config, flagKeys []string) []string {
+	keys := append([]string(nil), flagKeys...)
+	keys = append(keys, config.SplitList(cfg.Get("BUILD_SECRETS"))...)
+
+	seen := make(map[string]bool, len(keys))
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		key = strings.ToUpper(strings.TrimSpace(key))
+		if key != "" && !seen[key] {
+			seen[key] = true
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+// checkBuildArgSecrets scans build arguments for values that look like secrets.
+// BUILD_ARG_SECRETS selects the policy: warn (default), fail or off.
+func checkBuildArgSecrets(cfg *config.Config, buildArgs map[string]string, allowFlags []string) error {
+	severity := validation.SeverityWarning
+	switch policy := strings.ToLower(cfg.Get("BUILD_ARG_SECRETS")); policy {
+	case "", "warn":
+	case "fail":
+		severity = validation.SeverityError
+	case "off":
+		return nil
+	default:
+		return fmt.Errorf("invalid BUILD_ARG_SECRETS value: %s (expected warn, fail or off)", policy)
+	}
+
+	allowlist := append(config.SplitList(cfg.Get("BUILD_ARG_ALLOWLIST")), allowFlags...)
+	report := &validation.Report{Issues: validation.ScanBuildArgs(buildArgs, allowlist, severity)}
+	return reportValidation(report)
+}
+
+// buildWithBuildKit builds and pushes the image with a local Docker BuildKit so secrets can be
+// mounted with --secret instead of becoming build args (az acr build has no secret mounts)
+func buildWithBuildKit(ctx context.Context, cfg *config.Config, registry, fullImageName, file, contextPath string,
+	buildArgs map[string]string, secretKeys []string) error {
+	if err := runx.AZ(ctx, "acr", "login", "--name", registry); err != nil {
+		return fmt.Errorf("failed to log in to ACR: %w", err)
+	}
+
+	args := []string{"buildx", "build", "--push", "--tag", fullImageName}
+	if file != "" {
+		args = append(args, "--file", file)
+	}
+	args = append(args, buildArgFlags(buildArgs)...)
+
+	env := make([]string, 0, len(secretKeys))
+	for _, key := range secretKeys {
+		value := cfg.Get(key)
+		if value == "" {
+			return fmt.Errorf("build secret %s has no value", key)
+		}
+		env = append(env, fmt.Sprintf("%s=%s", key, value))
+		args = append(args, "--secret", fmt.Sprintf("id=%s,env=%s", key, key))
+	}
+	args = append(args, contextPath)
+
+	logging.Infof("Building with BuildKit secrets: %s", strings.Join(secretKeys, ", "))
+	if err := runx.Docker(ctx, env, args...); err != nil {
+		return fmt.Errorf("failed to build and push image: %w", err)
+	}
+	return nil
+}
+
 func newACRCmd() *cobra.Command {
 	var (
 		registry      string
@@ -64,26 +149,16 @@ func newACRCmd() *cobra.Command {
 		contextPath   string
 		file          string
 		force         bool
+		secrets       []string
+		allowArgs     []string
 	)
 
 	cmd := &cobra.Command{
 		Use:   "acr",
 		Short: "Build and push Docker image to Azure Container Registry",
 		RunE: func(cmd *cobra.Command, _ []string) error {
-			// Get environment from root command
-			envName, _ := cmd.Flags().GetString("env")
-
 			cfg := config.Current()
 
-			// Auto-detect environment in CI if not provided
-			if envName == "" && isCIEnvironment() {
-				detectedEnv := detectEnvironmentFromCI()
-				if detectedEnv != "" {
-					envName = detectedEnv
-					logging.Debugf("Auto-detected environment in CI: %s", envName)
-				}
-			}
-
 			// Apply flag overrides to config
 			if registry != "" {
 				cfg.Set("ACR_REGISTRY", registry)
@@ -98,35 +173,13 @@ func newACRCmd() *cobra.Command {
 				cfg.Set("IMAGE_TAG", imageTag)
 			}
 
-			// Auto-detect IMAGE_NAME and IMAGE_TAG in CI if not set
-			if isCIEnvironment() {
-				if cfg.Get("IMAGE_NAME") == "" {
-					if detectedImageName := detectImageNameFromCI(); detectedImageName != "" {
-						cfg.Set("IMAGE_NAME", detectedImageName)
-						logging.Debugf("Auto-detected IMAGE_NAME from CI: %s", detectedImageName)
-					}
-				}
-				if cfg.Get("IMAGE_TAG") == "" {
-					if detectedImageTag := detectImageTagFromCI(); detectedImageTag != "" {
-						cfg.Set("IMAGE_TAG", detectedImageTag)
-						logging.Debugf("Auto-detected IMAGE_TAG from CI: %s", detectedImageTag)
-					}
-				}
-			}
+			applyCIImageDefaults(cfg)
 
-			// Validate required variables
-			requiredVars := []string{"IMAGE_NAME", "IMAGE_TAG"}
-			for _, varName := range requiredVars {
-				if cfg.Get(varName) == "" {
-					return fmt.Errorf("missing required variable: %s", varName)
-				}
+			// Validate the full ACR configuration before building anything
+			if err := runValidation(cfg, resolveEnvName(cmd), validation.TargetACR); err != nil {
+				return fmt.Errorf("ACR validation failed: %w", err)
 			}
-
-			// Check for registry (ACR_REGISTRY)
 			registry = cfg.Get("ACR_REGISTRY")
-			if registry == "" {
-				return fmt.Errorf("missing required variable: ACR_REGISTRY")
-			}
 
 			// Get ACR resource group
 			acrResourceGroup := cfg.Get("ACR_RESOURCE_GROUP")
@@ -168,6 +221,30 @@ func newACRCmd() *cobra.Command {
 
 			logging.Infof("Building and pushing image: %s", fullImageName)
 
+			// Keys passed as BuildKit secrets must never become build args
+			buildArgs := collectBuildArgs(cfg)
+			secretKeys := buildSecretKeys(cfg, secrets)
+			for _, key := range secretKeys {
+				delete(buildArgs, key)
+			}
+			if err := checkBuildArgSecrets(cfg, buildArgs, allowArgs); err != nil {
+				return fmt.Errorf("build argument check failed: %w", err)
+			}
+
+			// Add context path (defaults to ".")
+			if contextPath == "" {
+				contextPath = "."
+			}
+
+			if len(secretKeys) > 0 {
+				err := buildWithBuildKit(cmd.Context(), cfg, registry, fullImageName, file, contextPath, buildArgs, secretKeys)
+				if err != nil {
+					return err
+				}
+				logging.Infof("Successfully built and pushed image: %s", fullImageName)
+				return nil
+			}
+
 			// Use az acr build command
 			args := []string{
 				"acr", "build",
@@ -182,16 +259,11 @@ func newACRCmd() *cobra.Command {
 			}
 
 			// Add build arguments if any NEXT_PUBLIC_ variables are set
-			buildArgs := collectBuildArgs(cfg)
 			if len(buildArgs) > 0 {
-				logging.Debugf("Adding build arguments: %v", buildArgs)
-				args = append(args, buildArgs...)
+				logging.Debugf("Adding %d build arguments", len(buildArgs))
+				args = append(args, buildArgFlags(buildArgs)...)
 			}
 
-			// Add context path (defaults to ".")
-			if contextPath == "" {
-				contextPath = "."
-			}
 			args = append(args, contextPath)
 
 			if err := runx.AZ(cmd.Context(), args...); err != nil {
@@ -210,5 +282,9 @@ func newACRCmd() *cobra.Command {
 	cmd.Flags().StringVar(&contextPath, "context", ".", "Build context path")
 	cmd.Flags().StringVar(&file, "file", "", "Dockerfile path")
 	cmd.Flags().BoolVar(&force, "force", false, "Force rebuild and push even if image tag already exists")
+	cmd.Flags().StringArrayVar(&secrets, "secret", nil,
+		"Config key to pass as a BuildKit secret (builds locally with docker buildx; env: BUILD_SECRETS)")
+	cmd.Flags().StringArrayVar(&allowArgs, "allow-build-arg", nil,
+		"Build arg allowed to look like a secret (env: BUILD_ARG_ALLOWLIST)")
 	return cmd
 }
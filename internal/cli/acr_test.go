@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/furiatona/azctl/internal/config"
+)
+
+func TestBuildArgFlagsSorted(t *testing.T) {
+	flags := buildArgFlags(map[string]string{"NEXT_PUBLIC_B": "2", "NEXT_PUBLIC_A": "1"})
+	want := []string{"--build-arg", "NEXT_PUBLIC_A=1", "--build-arg", "NEXT_PUBLIC_B=2"}
+	if !reflect.DeepEqual(flags, want) {
+		t.Errorf("got %v want %v", flags, want)
+	}
+}
+
+func TestBuildSecretKeys(t *testing.T) {
+	cfg := config.New()
+	cfg.Set("BUILD_SECRETS", "npm_token, SENTRY_AUTH_TOKEN")
+
+	keys := buildSecretKeys(cfg, []string{"NPM_TOKEN", "GITHUB_TOKEN"})
+	want := []string{"NPM_TOKEN", "GITHUB_TOKEN", "SENTRY_AUTH_TOKEN"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("got %v want %v", keys, want)
+	}
+}
+
+func TestCheckBuildArgSecretsPolicy(t *testing.T) {
+	buildArgs := map[string]string{"NEXT_PUBLIC_AI": "sk-abcdefghijklmnopqrstuvwxyz123456"}
+
+	cfg := config.New()
+	if err := checkBuildArgSecrets(cfg, buildArgs, nil); err != nil {
+		t.Errorf("default policy should only warn: %v", err)
+	}
+
+	cfg.Set("BUILD_ARG_SECRETS", "fail")
+	err := checkBuildArgSecrets(cfg, buildArgs, nil)
+	if err == nil || !strings.Contains(err.Error(), "NEXT_PUBLIC_AI") {
+		t.Errorf("fail policy should reject secret build arg: %v", err)
+	}
+
+	if err := checkBuildArgSecrets(cfg, buildArgs, []string{"NEXT_PUBLIC_AI"}); err != nil {
+		t.Errorf("allowlisted build arg should pass: %v", err)
+	}
+
+	cfg.Set("BUILD_ARG_SECRETS", "sometimes")
+	if err := checkBuildArgSecrets(cfg, buildArgs, nil); err == nil {
+		t.Error("expected error for invalid policy")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/furiatona/azctl/internal/config"
@@ -28,12 +29,15 @@ func TestACRCommandValidation(t *testing.T) {
 	if err == nil {
 		t.Error("expected error for missing required variables")
 	}
-	// Check that the error contains the expected message about missing required variables
-	if err.Error() != "failed to execute command: missing required variable: IMAGE_NAME" &&
-		err.Error() != "failed to execute command: missing required variable: IMAGE_TAG" &&
-		err.Error() != "failed to execute command: missing required variable: ACR_REGISTRY" {
+	// Check that the error reports every missing required variable at once
+	if !strings.HasPrefix(err.Error(), "failed to execute command: ACR validation failed") {
 		t.Errorf("unexpected error message: %v", err)
 	}
+	for _, key := range []string{"IMAGE_NAME", "IMAGE_TAG", "ACR_REGISTRY"} {
+		if os.Getenv(key) == "" && !strings.Contains(err.Error(), key) {
+			t.Errorf("error should mention missing %s: %v", key, err)
+		}
+	}
 }
 
 func TestCIEnvironmentDetection(t *testing.T) {
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/furiatona/azctl/internal/config"
+	"github.com/furiatona/azctl/internal/logging"
+	"github.com/furiatona/azctl/internal/preflight"
+	"github.com/furiatona/azctl/internal/validation"
+
+	"github.com/spf13/cobra"
+)
+
+func newPreflightCmd() *cobra.Command {
+	var (
+		target         string
+		resourceGroup  string
+		appServicePlan string
+		format         string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "preflight",
+		Short: "Check live Azure resources before deploying",
+		Long: `Check live Azure resources a deployment depends on without changing anything.
+
+Verifies that the resource group exists in LOCATION, the image tag exists in ACR,
+the caller holds the RBAC actions the deployment needs and, depending on the target,
+that the App Service plan is a Linux plan (webapp) or that the storage account, file
+shares and DNS name label are usable (aci). All failures are reported together.
+
+The same checks run before deploying when --preflight is passed to aci or webapp.
+
+Examples:
+  azctl preflight --target aci --env dev
+  azctl preflight --target webapp --env prod --format json`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			envName := resolveEnvName(cmd)
+			cfg := config.Current()
+
+			var checks []preflight.Check
+			switch target {
+			case validation.TargetACI:
+				rg := prepareACIConfig(cfg, envName, resourceGroup)
+				checks = preflight.ACIChecks(cfg, rg)
+			case validation.TargetWebApp:
+				rg := prepareWebAppConfig(cfg, resourceGroup)
+				if appServicePlan == "" {
+					appServicePlan = getAppServicePlan(cfg, envName)
+				}
+				checks = preflight.WebAppChecks(cfg, rg, appServicePlan)
+			default:
+				return fmt.Errorf("unknown preflight target: %s (expected %s or %s)",
+					target, validation.TargetACI, validation.TargetWebApp)
+			}
+
+			report := preflight.Run(cmd.Context(), checks)
+			if err := report.Write(cmd.OutOrStdout(), format, Version); err != nil {
+				return fmt.Errorf("failed to write preflight report: %w", err)
+			}
+			if errs := report.Errors(); len(errs) > 0 {
+				return fmt.Errorf("%d preflight check(s) failed", len(errs))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&target, "target", "", "Deployment target to check: aci or webapp")
+	cmd.Flags().StringVar(&resourceGroup, "resource-group", "", "Resource group (env: RESOURCE_GROUP)")
+	cmd.Flags().StringVar(&appServicePlan, "plan", "",
+		"App Service Plan for webapp (env: APP_SERVICE_PLAN or <env>_APP_SERVICE_PLAN)")
+	cmd.Flags().StringVar(&format, "format", validation.FormatText, "Output format: text, json, sarif, github")
+	_ = cmd.MarkFlagRequired("target")
+	return cmd
+}
+
+// runPreflight runs preflight checks and returns an error listing every failed check
+func runPreflight(ctx context.Context, checks []preflight.Check) error {
+	logging.Infof("🔎 Running %d preflight checks...", len(checks))
+	if err := reportValidation(preflight.Run(ctx, checks)); err != nil {
+		return err
+	}
+	logging.Infof("✅ Preflight checks passed")
+	return nil
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/furiatona/azctl/internal/aci"
+	"github.com/furiatona/azctl/internal/config"
+	"github.com/furiatona/azctl/internal/logging"
+	"github.com/furiatona/azctl/internal/runx"
+)
+
+// acrAuthIdentity is the ACR_AUTH mode that pulls images with a managed identity instead of
+// the registry's admin user
+const acrAuthIdentity = "identity"
+
+// pullsWithIdentity reports whether images are pulled from ACR with a managed identity
+func pullsWithIdentity(cfg *config.Config) bool {
+	return strings.EqualFold(cfg.Get("ACR_AUTH"), acrAuthIdentity)
+}
+
+// useRegistryIdentity assigns ACR_IDENTITY_ID to the container group and pulls from
+// ACR_REGISTRY with it
+func useRegistryIdentity(cfg *config.Config, doc string) (string, error) {
+	identityID := cfg.Get("ACR_IDENTITY_ID")
+	if identityID == "" {
+		return "", fmt.Errorf("ACR_AUTH=%s requires ACR_IDENTITY_ID, the user-assigned identity that pulls images",
+			acrAuthIdentity)
+	}
+	server := strings.TrimSuffix(cfg.Get("ACR_REGISTRY"), ".azurecr.io") + ".azurecr.io"
+	out, err := aci.UseRegistryIdentity([]byte(doc), server, identityID)
+	if err != nil {
+		return "", fmt.Errorf("failed to set registry identity: %w", err)
+	}
+	logging.Infof("🪪 Pulling images from %s with managed identity %s", server, identityName(identityID))
+	return string(out), nil
+}
+
+// setWebAppRegistryIdentity makes the Web App pull its image with a managed identity: the
+// user-assigned ACR_IDENTITY_ID, or the app's system-assigned identity when it is unset.
+// With ACR_ASSIGN_PULL_ROLE=true the identity is granted AcrPull on the registry.
+func setWebAppRegistryIdentity(ctx context.Context, resourceGroup, webAppName string, cfg *config.Config) error {
+	app := []string{"--name", webAppName, "--resource-group", resourceGroup}
+	siteConfig := map[string]any{"acrUseManagedIdentityCreds": true}
+
+	var principalID string
+	if identityID := cfg.Get("ACR_IDENTITY_ID"); identityID != "" {
+		assign := append([]string{"webapp", "identity", "assign", "--identities", identityID}, app...)
+		if err := runx.AZ(ctx, assign...); err != nil {
+			return fmt.Errorf("failed to assign identity %s: %w", identityName(identityID), err)
+		}
+		out, err := runx.AZOutput(ctx, "identity", "show", "--ids", identityID,
+			"--query", "[clientId, principalId]", "--output", "tsv")
+		if err != nil {
+			return fmt.Errorf("failed to read identity %s: %w", identityName(identityID), err)
+		}
+		ids := strings.Fields(out)
+		if len(ids) != 2 {
+			return fmt.Errorf("unexpected output reading identity %s: %q", identityName(identityID), out)
+		}
+		siteConfig["acrUserManagedIdentityID"] = ids[0]
+		principalID = ids[1]
+	} else {
+		out, err := runx.AZOutput(ctx, append([]string{"webapp", "identity", "assign",
+			"--query", "principalId", "--output", "tsv"}, app...)...)
+		if err != nil {
+			return fmt.Errorf("failed to assign a system identity: %w", err)
+		}
+		principalID = strings.TrimSpace(out)
+	}
+
+	generic, err := json.Marshal(siteConfig)
+	if err != nil {
+		return fmt.Errorf("failed to encode site config: %w", err)
+	}
+	if err := runx.AZ(ctx, append([]string{"webapp", "config", "set",
+		"--generic-configurations", string(generic)}, app...)...); err != nil {
+		return fmt.Errorf("failed to enable managed identity image pulls: %w", err)
+	}
+	// Admin credentials left from earlier deployments would still be used for pulls
+	if err := runx.AZ(ctx, append([]string{"webapp", "config", "appsettings", "delete", "--setting-names",
+		"DOCKER_REGISTRY_SERVER_USERNAME", "DOCKER_REGISTRY_SERVER_PASSWORD"}, app...)...); err != nil {
+		return fmt.Errorf("failed to remove registry credentials: %w", err)
+	}
+
+	if strings.EqualFold(cfg.Get("ACR_ASSIGN_PULL_ROLE"), envTrue) {
+		if err := assignAcrPull(ctx, cfg.Get("ACR_REGISTRY"), principalID); err != nil {
+			return err
+		}
+	}
+	logging.Infof("✅ Web App '%s' pulls images with a managed identity", webAppName)
+	return nil
+}
+
+// assignAcrPull grants a managed identity the AcrPull role on the registry
+func assignAcrPull(ctx context.Context, registry, principalID string) error {
+	registryID, err := runx.AZOutput(ctx, "acr", "show", "--name", registry, "--query", "id", "--output", "tsv")
+	if err != nil {
+		return fmt.Errorf("failed to look up registry %s: %w", registry, err)
+	}
+	if err := runx.AZ(ctx, "role", "assignment", "create", "--assignee-object-id", principalID,
+		"--assignee-principal-type", "ServicePrincipal", "--role", "AcrPull",
+		"--scope", strings.TrimSpace(registryID)); err != nil {
+		return fmt.Errorf("failed to grant AcrPull on %s: %w", registry, err)
+	}
+	logging.Infof("🔑 Granted AcrPull on %s", registry)
+	return nil
+}
+
+// identityName returns the last segment of a managed identity resource ID
+func identityName(id string) string {
+	return id[strings.LastIndex(id, "/")+1:]
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/furiatona/azctl/internal/aci"
+	"github.com/furiatona/azctl/internal/config"
+	"github.com/furiatona/azctl/internal/runx"
+)
+
+const pullIdentity = "/subscriptions/s/resourceGroups/rg/providers/" +
+	"Microsoft.ManagedIdentity/userAssignedIdentities/pull"
+
+func TestRenderACIManifestRegistryIdentity(t *testing.T) {
+	templatePath := filepath.Join(t.TempDir(), "aci.json")
+	template := `{"name":"api","location":"westeurope","properties":{"osType":"Linux","containers":[
+		{"name":"app","properties":{"image":"{{ env "ACR_REGISTRY" }}.azurecr.io/api:v1"}}],
+		"imageRegistryCredentials":[{"server":"{{ env "ACR_REGISTRY" }}.azurecr.io",
+			"username":"{{ envOr "ACR_USERNAME" "" }}","password":"{{ envOr "ACR_PASSWORD" "" }}"}]}}`
+	if err := os.WriteFile(templatePath, []byte(template), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.New()
+	cfg.Set("ACR_REGISTRY", "reg")
+	cfg.Set("ACR_AUTH", "identity")
+
+	if _, err := renderACIManifest(cfg, templatePath, "dev"); err == nil ||
+		!strings.Contains(err.Error(), "ACR_IDENTITY_ID") {
+		t.Errorf("expected an error naming ACR_IDENTITY_ID, got %v", err)
+	}
+
+	cfg.Set("ACR_IDENTITY_ID", pullIdentity)
+	m, err := renderACIManifest(cfg, templatePath, "dev")
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	group, err := aci.Parse([]byte(m.JSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	credential := group.Properties.ImageRegistryCredentials[0]
+	if credential.Identity != pullIdentity || credential.Username != "" || credential.Password != "" {
+		t.Errorf("unexpected registry credential: %+v", credential)
+	}
+	if group.Identity == nil || group.Identity.UserAssignedIdentities[pullIdentity] == nil {
+		t.Errorf("identity not assigned to the group: %+v", group.Identity)
+	}
+
+	// A registry given with its suffix still replaces the template's credential
+	cfg.Set("ACR_REGISTRY", "reg.azurecr.io")
+	suffixed := strings.ReplaceAll(template, `{{ env "ACR_REGISTRY" }}.azurecr.io`, "reg.azurecr.io")
+	if err := os.WriteFile(templatePath, []byte(suffixed), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if m, err = renderACIManifest(cfg, templatePath, "dev"); err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	if group, err = aci.Parse([]byte(m.JSON)); err != nil {
+		t.Fatal(err)
+	}
+	if credentials := group.Properties.ImageRegistryCredentials; len(credentials) != 1 ||
+		credentials[0].Server != "reg.azurecr.io" || credentials[0].Identity != pullIdentity {
+		t.Errorf("unexpected registry credentials: %+v", credentials)
+	}
+}
+
+func TestSetWebAppRegistryIdentity(t *testing.T) {
+	cfg := config.New()
+	cfg.Set("ACR_REGISTRY", "myacr")
+	cfg.Set("ACR_AUTH", "identity")
+	cfg.Set("ACR_ASSIGN_PULL_ROLE", "true")
+
+	tests := []struct {
+		identityID string
+		commands   string
+		siteConfig string
+	}{
+		{"", "webapp identity,config set,config appsettings,acr show,role assignment",
+			`{"acrUseManagedIdentityCreds":true}`},
+		{pullIdentity, "webapp identity,identity show,config set,config appsettings,acr show,role assignment",
+			`{"acrUseManagedIdentityCreds":true,"acrUserManagedIdentityID":"client-1"}`},
+	}
+	for _, tt := range tests {
+		cfg.Set("ACR_IDENTITY_ID", tt.identityID)
+		fake := runx.NewFake().
+			On("webapp identity assign", "principal-1\n", nil).
+			On("identity show", "client-1\tprincipal-1\n", nil).
+			On("acr show", "/subscriptions/s/resourceGroups/rg/providers/Microsoft.ContainerRegistry/registries/myacr\n", nil).
+			On("webapp config", "", nil).
+			On("role assignment create", "", nil)
+		restore := runx.SetExecutor(fake)
+		err := setWebAppRegistryIdentity(context.Background(), "rg", "web", cfg)
+		restore()
+		if err != nil {
+			t.Fatalf("identity %q: %v", tt.identityID, err)
+		}
+		if azCommands(fake) != tt.commands {
+			t.Errorf("identity %q: commands %q, want %q", tt.identityID, azCommands(fake), tt.commands)
+		}
+		calls := strings.Join(fake.Calls(), "\n")
+		if !strings.Contains(calls, tt.siteConfig) || !strings.Contains(calls, "--assignee-object-id principal-1") {
+			t.Errorf("identity %q: unexpected calls:\n%s", tt.identityID, calls)
+		}
+	}
+
+	// Admin credentials are not needed to compare settings
+	settings, err := webAppRegistrySettings(cfg)
+	if err != nil || len(settings) != 1 {
+		t.Errorf("expected only the registry URL, got %v, %v", settings, err)
+	}
+}
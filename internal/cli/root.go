@@ -2,6 +2,7 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -26,6 +27,26 @@ func SetVersionInfo(version, buildTime, gitCommit string) {
 	GitCommit = gitCommit
 }
 
+// ExitError is an error that should end the process with a specific exit code, so scripts can
+// tell failures apart
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string { return e.Err.Error() }
+
+func (e *ExitError) Unwrap() error { return e.Err }
+
+// ExitCode returns the process exit code for an error returned by Execute
+func ExitCode(err error) int {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return 1
+}
+
 func Execute(ctx context.Context, args []string) error {
 	root := &cobra.Command{
 		Use:   "azctl",
@@ -44,6 +65,8 @@ func Execute(ctx context.Context, args []string) error {
 	root.PersistentFlags().Bool("verbose", false, "Enable verbose logging")
 	root.PersistentFlags().String("log-level", "info", "Log level (debug, info, warn, error)")
 	root.PersistentFlags().String("log-format", "text", "Log format (text, json)")
+	root.PersistentFlags().StringArray("policy", nil,
+		"Validation policy file (repeatable; env: AZCTL_POLICY_FILE, default: .azctl/policy.yaml)")
 
 	// Initialize config/logging before running any subcommand
 	root.PersistentPreRunE = func(cmd *cobra.Command, _ []string) error {
@@ -66,22 +89,22 @@ func Execute(ctx context.Context, args []string) error {
 		// Initialize logx package with verbose flag for Azure App Configuration logging
 		logx.Init(verbose)
 
-		envfile, _ := cmd.Flags().GetString("envfile")
 		env, _ := cmd.Flags().GetString("env")
 		if strings.EqualFold(env, "production") {
 			env = "prod"
 			// Update flag value so downstream commands see normalized env
 			_ = cmd.Flags().Set("env", env)
 		}
-
-		// If environment is specified, use environment-specific .env file
-		if env != "" && envfile == ".env" {
-			envfile = fmt.Sprintf(".env.%s", env)
-		}
+		envfile := resolveEnvFile(cmd)
 
 		if err := config.Init(cmd.Context(), envfile, env); err != nil {
 			return fmt.Errorf("init config: %w", err)
 		}
+
+		// Policy files from flags take precedence over AZCTL_POLICY_FILE
+		if policies, _ := cmd.Flags().GetStringArray("policy"); len(policies) > 0 {
+			config.Current().Set("AZCTL_POLICY_FILE", strings.Join(policies, ","))
+		}
 		return nil
 	}
 
@@ -90,6 +113,8 @@ func Execute(ctx context.Context, args []string) error {
 	root.AddCommand(newACICmd())
 	root.AddCommand(newWebAppCmd())
 	root.AddCommand(newAppConfigCmd())
+	root.AddCommand(newValidateCmd())
+	root.AddCommand(newPreflightCmd())
 
 	root.SetArgs(args)
 	err := root.ExecuteContext(ctx)
@@ -98,3 +123,14 @@ func Execute(ctx context.Context, args []string) error {
 	}
 	return nil
 }
+
+// resolveEnvFile returns the .env file configuration is loaded from: --envfile, or
+// .env.<env> when --env is set and --envfile is left at its default
+func resolveEnvFile(cmd *cobra.Command) string {
+	envfile, _ := cmd.Flags().GetString("envfile")
+	env, _ := cmd.Flags().GetString("env")
+	if env != "" && envfile == ".env" {
+		envfile = fmt.Sprintf(".env.%s", env)
+	}
+	return envfile
+}
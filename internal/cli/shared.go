@@ -1,10 +1,48 @@
 package cli
 
 import (
+	"context"
 	"os"
+	"os/exec"
 	"strings"
+
+	"github.com/furiatona/azctl/internal/config"
+	"github.com/furiatona/azctl/internal/logging"
+
+	"github.com/spf13/cobra"
 )
 
+// resolveEnvName returns the --env flag value, falling back to CI auto-detection
+func resolveEnvName(cmd *cobra.Command) string {
+	envName, _ := cmd.Flags().GetString("env")
+	if envName == "" && isCIEnvironment() {
+		if detectedEnv := detectEnvironmentFromCI(); detectedEnv != "" {
+			envName = detectedEnv
+			logging.Debugf("Auto-detected environment in CI: %s", envName)
+		}
+	}
+	return envName
+}
+
+// applyCIImageDefaults auto-detects IMAGE_NAME and IMAGE_TAG in CI if not set
+func applyCIImageDefaults(cfg *config.Config) {
+	if !isCIEnvironment() {
+		return
+	}
+	if cfg.Get("IMAGE_NAME") == "" {
+		if detectedImageName := detectImageNameFromCI(); detectedImageName != "" {
+			cfg.Set("IMAGE_NAME", detectedImageName)
+			logging.Debugf("Auto-detected IMAGE_NAME from CI: %s", detectedImageName)
+		}
+	}
+	if cfg.Get("IMAGE_TAG") == "" {
+		if detectedImageTag := detectImageTagFromCI(); detectedImageTag != "" {
+			cfg.Set("IMAGE_TAG", detectedImageTag)
+			logging.Debugf("Auto-detected IMAGE_TAG from CI: %s", detectedImageTag)
+		}
+	}
+}
+
 // isCIEnvironment detects if we're running in a CI environment
 func isCIEnvironment() bool {
 	// Check for common CI environment variables
@@ -116,3 +154,17 @@ func detectImageTagFromCI() string {
 
 	return ""
 }
+
+// detectGitCommit returns the commit being deployed, from CI variables or the local checkout
+func detectGitCommit(ctx context.Context) string {
+	for _, key := range []string{"GITHUB_SHA", "BUILD_SOURCEVERSION", "CI_COMMIT_SHA"} {
+		if sha := os.Getenv(key); sha != "" {
+			return sha
+		}
+	}
+	out, err := exec.CommandContext(ctx, "git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
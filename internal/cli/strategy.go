@@ -0,0 +1,187 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/furiatona/azctl/internal/config"
+	"github.com/furiatona/azctl/internal/deploy"
+	"github.com/furiatona/azctl/internal/logging"
+	"github.com/furiatona/azctl/internal/runx"
+)
+
+// deployStrategy returns the configured strategy for a command; force turns
+// skip-if-unchanged into in-place
+func deployStrategy(cfg *config.Config, prefix, fallback string, force bool) (deploy.Strategy, error) {
+	strategy, err := deploy.FromConfig(cfg, prefix, fallback)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // FromConfig names the setting
+	}
+	if force && strategy.Name() == deploy.StrategySkipIfUnchanged {
+		strategy = deploy.InPlace{}
+	}
+	return strategy, nil
+}
+
+// aciDeployment is a container group deployment as a deploy.Target
+type aciDeployment struct {
+	target *aciTarget
+	// definition is the container group as JSON, used to compare with the live group
+	definition string
+	create     func(ctx context.Context) error
+	// out receives the plan when the group changed
+	out io.Writer
+}
+
+func (d *aciDeployment) Name() string { return "container group " + d.target.groupName }
+
+func (d *aciDeployment) Exists(ctx context.Context) (bool, error) {
+	return checkContainerGroupExists(ctx, d.target.resourceGroup, d.target.groupName)
+}
+
+func (d *aciDeployment) Changed(ctx context.Context) (bool, error) {
+	plan, err := planACI(ctx, d.target, d.definition)
+	if err != nil {
+		return false, err
+	}
+	if plan.HasChanges() {
+		if err := plan.Write(d.out); err != nil {
+			return true, fmt.Errorf("failed to write plan: %w", err)
+		}
+	}
+	return plan.HasChanges(), nil
+}
+
+func (d *aciDeployment) Delete(ctx context.Context) error {
+	return deleteContainerGroup(ctx, d.target.resourceGroup, d.target.groupName)
+}
+
+func (d *aciDeployment) Apply(ctx context.Context) error {
+	logging.Infof("🚀 Deploying container group %s...", d.target.groupName)
+	return d.create(ctx)
+}
+
+// webAppDeployment is a Web App deployment as a deploy.Target
+type webAppDeployment struct {
+	cfg            *config.Config
+	resourceGroup  string
+	name           string
+	appServicePlan string
+	image          string
+}
+
+func (d *webAppDeployment) Name() string { return "Web App " + d.name }
+
+func (d *webAppDeployment) Exists(ctx context.Context) (bool, error) {
+	return checkWebAppExists(ctx, d.resourceGroup, d.name)
+}
+
+// Changed compares the live container image and app settings with the ones to deploy
+func (d *webAppDeployment) Changed(ctx context.Context) (bool, error) {
+	if exists, err := d.Exists(ctx); err != nil || !exists {
+		return true, err
+	}
+
+	image, _, err := resolveWebAppImage(d.cfg, d.image)
+	if err != nil {
+		return true, err
+	}
+	fxVersion, err := runx.AZOutput(ctx, "webapp", "config", "show", "--name", d.name,
+		"--resource-group", d.resourceGroup, "--query", "linuxFxVersion", "-o", "tsv")
+	if err != nil {
+		return true, fmt.Errorf("failed to read container image: %w", err)
+	}
+	if strings.TrimSpace(fxVersion) != "DOCKER|"+image {
+		logging.Infof("Container image changes: %s => %s", strings.TrimSpace(fxVersion), "DOCKER|"+image)
+		return true, nil
+	}
+
+	if pullsWithIdentity(d.cfg) {
+		useIdentity, err := runx.AZOutput(ctx, "webapp", "config", "show", "--name", d.name,
+			"--resource-group", d.resourceGroup, "--query", "acrUseManagedIdentityCreds", "-o", "tsv")
+		if err != nil {
+			return true, fmt.Errorf("failed to read registry authentication: %w", err)
+		}
+		if strings.TrimSpace(useIdentity) != envTrue {
+			logging.Infof("Registry authentication changes to managed identity")
+			return true, nil
+		}
+	}
+
+	out, err := runx.AZOutput(ctx, "webapp", "config", "appsettings", "list", "--name", d.name,
+		"--resource-group", d.resourceGroup, "-o", "json")
+	if err != nil {
+		return true, fmt.Errorf("failed to read app settings: %w", err)
+	}
+	var live []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal([]byte(out), &live); err != nil {
+		return true, fmt.Errorf("failed to parse app settings: %w", err)
+	}
+	liveValues := make(map[string]string, len(live))
+	for _, setting := range live {
+		liveValues[setting.Name] = setting.Value
+	}
+
+	registrySettings, err := webAppRegistrySettings(d.cfg)
+	if err != nil {
+		return true, err
+	}
+	for _, setting := range append(webAppSettings(d.cfg), registrySettings...) {
+		key, value, _ := strings.Cut(setting, "=")
+		if current, ok := liveValues[key]; !ok || current != value {
+			logging.Infof("App setting %s changes", key)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Validate fails without APP_SERVICE_PLAN, which a deleted Web App needs to be created again
+func (d *webAppDeployment) Validate(context.Context) error {
+	if d.appServicePlan == "" {
+		return fmt.Errorf("recreating Web App '%s' requires APP_SERVICE_PLAN", d.name)
+	}
+	return nil
+}
+
+func (d *webAppDeployment) Delete(ctx context.Context) error {
+	if err := runx.AZ(ctx, "webapp", "delete", "--name", d.name, "--resource-group", d.resourceGroup,
+		"--keep-empty-plan"); err != nil {
+		return fmt.Errorf("failed to delete webapp: %w", err)
+	}
+	return nil
+}
+
+// Apply creates the Web App if needed and updates its container and settings
+func (d *webAppDeployment) Apply(ctx context.Context) error {
+	exists, err := d.Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check WebApp existence: %w", err)
+	}
+
+	if exists {
+		// Update existing WebApp
+		logging.Infof("Updating existing Web App '%s'...", d.name)
+		return updateWebApp(ctx, d.resourceGroup, d.name, d.cfg, d.image)
+	}
+
+	// Create new WebApp
+	if d.appServicePlan == "" {
+		return fmt.Errorf("WebApp '%s' does not exist and APP_SERVICE_PLAN not provided. "+
+			"Please either:\n1. Set APP_SERVICE_PLAN environment variable to create new web apps, or\n"+
+			"2. Create the web app manually first, or\n"+
+			"3. Use a different web app name that already exists", d.name)
+	}
+
+	logging.Infof("Creating new Web App '%s'...", d.name)
+	if err := createWebApp(ctx, d.resourceGroup, d.name, d.appServicePlan); err != nil {
+		return fmt.Errorf("failed to create WebApp: %w", err)
+	}
+	return updateWebApp(ctx, d.resourceGroup, d.name, d.cfg, d.image)
+}
@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/furiatona/azctl/internal/config"
+	"github.com/furiatona/azctl/internal/deploy"
+	"github.com/furiatona/azctl/internal/runx"
+)
+
+// azCommands returns the az subcommands called, e.g. "container delete"
+func azCommands(fake *runx.Fake) string {
+	var commands []string
+	for _, call := range fake.Calls() {
+		fields := strings.Fields(call)
+		if len(fields) > 2 && fields[0] == "webapp" && fields[1] == "config" {
+			fields = fields[1:]
+		}
+		commands = append(commands, strings.Join(fields[:2], " "))
+	}
+	return strings.Join(commands, ",")
+}
+
+func TestACIDeploymentStrategies(t *testing.T) {
+	definition := `{"name":"api","location":"westeurope","properties":{"osType":"Linux","containers":[` +
+		`{"name":"app","properties":{"image":"api:v1"}}]}}`
+	live := `{"name":"api","location":"westeurope","osType":"Linux","containers":[{"name":"app","image":"api:v1"}]}`
+	target := &aciTarget{resourceGroup: "rg", groupName: "api"}
+
+	tests := []struct {
+		strategy string
+		force    bool
+		deployed bool
+		commands string
+	}{
+		{deploy.StrategyRecreate, false, true, "container show,container delete,container create"},
+		{deploy.StrategyInPlace, false, true, "container create"},
+		{deploy.StrategySkipIfUnchanged, false, false, "container show"},
+		{deploy.StrategySkipIfUnchanged, true, true, "container create"},
+	}
+	for _, tt := range tests {
+		cfg := config.New()
+		cfg.Set("ACI_DEPLOY_STRATEGY", tt.strategy)
+		strategy, err := deployStrategy(cfg, "ACI", deploy.StrategyInPlace, tt.force)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fake := runx.NewFake().On("container show", live, nil).On("container", "", nil)
+		restore := runx.SetExecutor(fake)
+		deployed, err := strategy.Deploy(context.Background(), &aciDeployment{
+			target: target, definition: definition, out: io.Discard,
+			create: func(ctx context.Context) error { return createContainerGroup(ctx, "rg", definition) },
+		})
+		restore()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.strategy, err)
+		}
+		if deployed != tt.deployed || azCommands(fake) != tt.commands {
+			t.Errorf("%s (force %v): deployed=%v commands=%q, want %v %q",
+				tt.strategy, tt.force, deployed, azCommands(fake), tt.deployed, tt.commands)
+		}
+	}
+}
+
+func TestACIRecreateLookupFailure(t *testing.T) {
+	fake := runx.NewFake().On("container show", "", errors.New("az command failed: AADSTS700082: token expired"))
+	defer runx.SetExecutor(fake)()
+
+	target := &aciTarget{resourceGroup: "rg", groupName: "api"}
+	_, err := deploy.Recreate{}.Deploy(context.Background(), &aciDeployment{target: target, out: io.Discard})
+	if err == nil || !strings.Contains(err.Error(), "AADSTS700082") {
+		t.Errorf("expected the lookup error, got %v", err)
+	}
+	if azCommands(fake) != "container show" {
+		t.Errorf("nothing may be deleted or created after a failed lookup, got %q", azCommands(fake))
+	}
+}
+
+// fakeWebApp is an executor where the Web App exists until it is deleted
+type fakeWebApp struct {
+	*runx.Fake
+	deleted bool
+}
+
+func (f *fakeWebApp) Output(ctx context.Context, args ...string) (string, error) {
+	out, err := f.Fake.Output(ctx, args...)
+	switch strings.Join(args[:2], " ") {
+	case "webapp delete":
+		f.deleted = true
+	case "webapp show":
+		if f.deleted {
+			return "", errors.New("az command failed: ResourceNotFound")
+		}
+	}
+	return out, err
+}
+
+func (f *fakeWebApp) Run(ctx context.Context, args ...string) error {
+	_, err := f.Output(ctx, args...)
+	return err
+}
+
+func TestWebAppDeploymentStrategies(t *testing.T) {
+	cfg := config.New()
+	for key, value := range map[string]string{
+		"ACR_REGISTRY": "myacr", "ACR_USERNAME": "myacr", "ACR_PASSWORD": "pw",
+		"IMAGE_NAME": "web", "IMAGE_TAG": "v1", "NODE_ENV": "production",
+	} {
+		cfg.Set(key, value)
+	}
+	settings := `[{"name":"NODE_ENV","value":"production"},` +
+		`{"name":"DOCKER_REGISTRY_SERVER_URL","value":"https://myacr.azurecr.io"},` +
+		`{"name":"DOCKER_REGISTRY_SERVER_USERNAME","value":"myacr"},` +
+		`{"name":"DOCKER_REGISTRY_SERVER_PASSWORD","value":"pw"}]`
+
+	tests := []struct {
+		strategy string
+		image    string
+		deployed bool
+		commands string
+	}{
+		{deploy.StrategyRecreate, "", true,
+			"webapp show,webapp delete,webapp show,webapp create,config container,config appsettings,config appsettings"},
+		{deploy.StrategyInPlace, "", true, "webapp show,config container,config appsettings,config appsettings"},
+		{deploy.StrategySkipIfUnchanged, "", false, "webapp show,config show,config appsettings"},
+		{deploy.StrategySkipIfUnchanged, "myacr.azurecr.io/web:v2", true,
+			"webapp show,config show,webapp show,config container,config appsettings,config appsettings"},
+	}
+	for _, tt := range tests {
+		strategy, err := deploy.New(tt.strategy)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fake := runx.NewFake().
+			On("webapp", "", nil).
+			On("webapp config show", "DOCKER|myacr.azurecr.io/web:v1\n", nil).
+			On("webapp config appsettings list", settings, nil)
+		restore := runx.SetExecutor(&fakeWebApp{Fake: fake})
+		deployed, err := strategy.Deploy(context.Background(), &webAppDeployment{
+			cfg: cfg, resourceGroup: "rg", name: "web", appServicePlan: "plan", image: tt.image,
+		})
+		restore()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.strategy, err)
+		}
+		if deployed != tt.deployed || azCommands(fake) != tt.commands {
+			t.Errorf("%s: deployed=%v commands=%q, want %v %q",
+				tt.strategy, deployed, azCommands(fake), tt.deployed, tt.commands)
+		}
+	}
+}
+
+func TestWebAppRecreateWithoutPlan(t *testing.T) {
+	fake := runx.NewFake().On("webapp", "", nil)
+	restore := runx.SetExecutor(&fakeWebApp{Fake: fake})
+	defer restore()
+
+	_, err := deploy.Recreate{}.Deploy(context.Background(), &webAppDeployment{
+		cfg: config.New(), resourceGroup: "rg", name: "web",
+	})
+	if err == nil || !strings.Contains(err.Error(), "APP_SERVICE_PLAN") {
+		t.Errorf("expected an APP_SERVICE_PLAN error, got %v", err)
+	}
+	if azCommands(fake) != "webapp show" {
+		t.Errorf("the Web App must not be deleted, got %q", azCommands(fake))
+	}
+}
+
+func TestWebAppLookupFailure(t *testing.T) {
+	fake := runx.NewFake().On("webapp show", "", errors.New("az command failed: AADSTS700082: token expired"))
+	defer runx.SetExecutor(&fakeWebApp{Fake: fake})()
+
+	app := &webAppDeployment{cfg: config.New(), resourceGroup: "rg", name: "web", appServicePlan: "plan"}
+	_, err := deploy.Recreate{}.Deploy(context.Background(), app)
+	if err == nil || !strings.Contains(err.Error(), "AADSTS700082") {
+		t.Errorf("expected the lookup error, got %v", err)
+	}
+	if changed, err := app.Changed(context.Background()); err == nil {
+		t.Errorf("expected Changed to report the lookup error, got changed=%v", changed)
+	}
+	if azCommands(fake) != "webapp show,webapp show" {
+		t.Errorf("nothing may be deleted or created after a failed lookup, got %q", azCommands(fake))
+	}
+}
@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/furiatona/azctl/internal/aci"
+	"github.com/furiatona/azctl/internal/config"
+	"github.com/furiatona/azctl/internal/logging"
+	"github.com/furiatona/azctl/internal/validation"
+
+	"github.com/spf13/cobra"
+)
+
+func newValidateCmd() *cobra.Command {
+	var (
+		target        string
+		resourceGroup string
+		format        string
+		templatePath  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate configuration for a deployment target without deploying",
+		Long: `Validate configuration for a deployment target and report every problem at once.
+
+Configuration is resolved exactly as the deploy commands resolve it (CI detection,
+environment-specific resource groups, defaults). Each problem is reported with the
+configuration key and the source it was loaded from (file and line for .env files).
+Warnings are reported but only errors cause a non-zero exit code.
+
+Organisation policies from AZCTL_POLICY_FILE (or --policy, or .azctl/policy.yaml when
+present) are evaluated alongside the built-in rules, here and in every deploy command.
+
+Output formats:
+  text    Human-readable lines (default)
+  json    Structured findings for scripting
+  sarif   SARIF 2.1.0 for GitHub code scanning (upload-sarif)
+  github  GitHub Actions workflow annotations shown inline in pull requests
+
+Examples:
+  # Validate ACI configuration for dev
+  azctl validate --target aci --env dev
+
+  # Validate WebApp configuration for production
+  azctl validate --target webapp --env prod
+
+  # Produce SARIF for GitHub code scanning
+  azctl validate --target aci --env prod --format sarif > azctl.sarif`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			envName := resolveEnvName(cmd)
+			cfg := config.Current()
+
+			switch target {
+			case validation.TargetACI:
+				prepareACIConfig(cfg, envName, resourceGroup)
+			case validation.TargetWebApp:
+				prepareWebAppConfig(cfg, resourceGroup)
+			case validation.TargetACR:
+				applyCIImageDefaults(cfg)
+			}
+
+			engine, err := newValidationEngine(cfg, envName, target)
+			if err != nil {
+				return err
+			}
+
+			report := engine.Run(cfg)
+			report.DefaultFile = resolveEnvFile(cmd)
+			if target == validation.TargetACI {
+				issues, err := templateKeyIssues(cfg, aciTemplatePaths(cfg, templatePath, envName)...)
+				if err != nil {
+					return err
+				}
+				report.Issues = append(report.Issues, issues...)
+			}
+			if err := report.Write(cmd.OutOrStdout(), format, Version); err != nil {
+				return fmt.Errorf("failed to write validation report: %w", err)
+			}
+
+			if errs := report.Errors(); len(errs) > 0 {
+				return fmt.Errorf("%s configuration has %d error(s)", target, len(errs))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&target, "target", "", "Deployment target to validate: aci, webapp or acr")
+	cmd.Flags().StringVar(&resourceGroup, "resource-group", "", "Resource group (env: RESOURCE_GROUP)")
+	cmd.Flags().StringVar(&format, "format", validation.FormatText, "Output format: text, json, sarif, github")
+	cmd.Flags().StringVar(&templatePath, "template", defaultACITemplate,
+		"ACI template whose referenced keys are checked (target aci)")
+	_ = cmd.MarkFlagRequired("target")
+	return cmd
+}
+
+// defaultPolicyFile is loaded automatically when AZCTL_POLICY_FILE is not set
+const defaultPolicyFile = ".azctl/policy.yaml"
+
+// newValidationEngine builds the engine for a target: the predefined rules (and the probe
+// settings for ACI) plus every policy from AZCTL_POLICY_FILE (comma-separated) or
+// .azctl/policy.yaml that applies to the environment and target
+func newValidationEngine(cfg *config.Config, envName, target string) (*validation.ValidationEngine, error) {
+	engine, err := validation.ForTarget(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid validation target: %w", err)
+	}
+	if target == validation.TargetACI {
+		engine.AddRule(aci.ProbeValidation)
+	}
+
+	policyFiles := config.SplitList(cfg.Get("AZCTL_POLICY_FILE"))
+	if len(policyFiles) == 0 {
+		if _, err := os.Stat(defaultPolicyFile); err == nil {
+			policyFiles = []string{defaultPolicyFile}
+		}
+	}
+	for _, path := range policyFiles {
+		set, err := validation.LoadPolicyFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load policies: %w", err)
+		}
+		rules := set.Rules(envName, target)
+		logging.Debugf("Loaded %d applicable policies from %s", len(rules), path)
+		for _, rule := range rules {
+			engine.AddRule(rule)
+		}
+	}
+	return engine, nil
+}
+
+// runValidation runs the full validation engine for a target, logging warnings and
+// returning an error listing every error-severity issue. Extra issues (e.g. keys referenced
+// by a template) are reported alongside, skipping keys the engine already reported.
+func runValidation(cfg *config.Config, envName, target string, extra ...validation.Issue) error {
+	engine, err := newValidationEngine(cfg, envName, target)
+	if err != nil {
+		return err
+	}
+
+	report := engine.Run(cfg)
+	reported := make(map[string]bool, len(report.Issues))
+	for _, issue := range report.Issues {
+		reported[issue.Key] = true
+	}
+	for _, issue := range extra {
+		if !reported[issue.Key] {
+			report.Issues = append(report.Issues, issue)
+		}
+	}
+	return reportValidation(report)
+}
+
+// reportValidation logs warnings, emits GitHub annotations in GitHub Actions and returns
+// an error listing every error-severity issue
+func reportValidation(report *validation.Report) error {
+	for _, issue := range report.Warnings() {
+		logging.Warnf("%s", issue.String())
+	}
+	if os.Getenv("GITHUB_ACTIONS") == envTrue {
+		if err := report.WriteGitHub(os.Stdout); err != nil {
+			logging.Warnf("Failed to write GitHub annotations: %v", err)
+		}
+	}
+	return report.Err() //nolint:wrapcheck // report errors already list rule, key and source
+}
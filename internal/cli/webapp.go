@@ -6,7 +6,10 @@ import (
 	"strings"
 
 	"github.com/furiatona/azctl/internal/config"
+	"github.com/furiatona/azctl/internal/deploy"
+	"github.com/furiatona/azctl/internal/envvars"
 	"github.com/furiatona/azctl/internal/logging"
+	"github.com/furiatona/azctl/internal/preflight"
 	"github.com/furiatona/azctl/internal/runx"
 	"github.com/furiatona/azctl/internal/validation"
 
@@ -19,51 +22,22 @@ func newWebAppCmd() *cobra.Command {
 		webAppName     string
 		appServicePlan string
 		image          string
+		runChecks      bool
 	)
 
 	cmd := &cobra.Command{
 		Use:   "webapp",
 		Short: "Deploy to Azure Web App using container image from ACR",
 		RunE: func(cmd *cobra.Command, _ []string) error {
-			// Get environment from root command
-			envName, _ := cmd.Flags().GetString("env")
-
+			envName := resolveEnvName(cmd)
 			cfg := config.Current()
 
-			// Auto-detect environment in CI if not provided
-			if envName == "" && isCIEnvironment() {
-				detectedEnv := detectEnvironmentFromCI()
-				if detectedEnv != "" {
-					envName = detectedEnv
-					logging.Debugf("Auto-detected environment in CI: %s", envName)
-				}
-			}
-
 			// Environment is required for WebApp deployment
 			if envName == "" {
 				return fmt.Errorf("environment required for webapp deployment (--env dev|staging|prod)")
 			}
 
-			// Auto-detect IMAGE_NAME and IMAGE_TAG in CI if not set
-			if isCIEnvironment() {
-				if cfg.Get("IMAGE_NAME") == "" {
-					if detectedImageName := detectImageNameFromCI(); detectedImageName != "" {
-						cfg.Set("IMAGE_NAME", detectedImageName)
-						logging.Debugf("Auto-detected IMAGE_NAME from CI: %s", detectedImageName)
-					}
-				}
-				if cfg.Get("IMAGE_TAG") == "" {
-					if detectedImageTag := detectImageTagFromCI(); detectedImageTag != "" {
-						cfg.Set("IMAGE_TAG", detectedImageTag)
-						logging.Debugf("Auto-detected IMAGE_TAG from CI: %s", detectedImageTag)
-					}
-				}
-			}
-
-			// Apply flag overrides
-			if resourceGroup == "" {
-				resourceGroup = cfg.Get("RESOURCE_GROUP")
-			}
+			resourceGroup = prepareWebAppConfig(cfg, resourceGroup)
 			if webAppName == "" {
 				webAppName = getWebAppName(cfg, envName)
 			}
@@ -71,36 +45,28 @@ func newWebAppCmd() *cobra.Command {
 				appServicePlan = getAppServicePlan(cfg, envName)
 			}
 
-			// Validate required variables
-			if err := validation.RequiredVars(cfg, validation.WebAppRequiredVars()); err != nil {
+			// Validate the full WebApp configuration before touching any resources
+			if err := runValidation(cfg, envName, validation.TargetWebApp); err != nil {
 				return fmt.Errorf("WebApp deployment validation failed: %w", err)
 			}
 
-			// Check if WebApp exists
-			exists, err := checkWebAppExists(cmd.Context(), resourceGroup, webAppName)
-			if err != nil {
-				return fmt.Errorf("failed to check WebApp existence: %w", err)
-			}
-
-			if exists {
-				// Update existing WebApp
-				logging.Infof("Updating existing Web App '%s'...", webAppName)
-				return updateWebApp(cmd.Context(), resourceGroup, webAppName, cfg, image)
-			} else {
-				// Create new WebApp
-				if appServicePlan == "" {
-					return fmt.Errorf("WebApp '%s' does not exist and APP_SERVICE_PLAN not provided. "+
-						"Please either:\n1. Set APP_SERVICE_PLAN environment variable to create new web apps, or\n"+
-						"2. Create the web app manually first, or\n"+
-						"3. Use a different web app name that already exists", webAppName)
+			if runChecks {
+				checks := preflight.WebAppChecks(cfg, resourceGroup, appServicePlan)
+				if err := runPreflight(cmd.Context(), checks); err != nil {
+					return fmt.Errorf("WebApp preflight failed: %w", err)
 				}
+			}
 
-				logging.Infof("Creating new Web App '%s'...", webAppName)
-				if err := createWebApp(cmd.Context(), resourceGroup, webAppName, appServicePlan); err != nil {
-					return fmt.Errorf("failed to create WebApp: %w", err)
-				}
-				return updateWebApp(cmd.Context(), resourceGroup, webAppName, cfg, image)
+			strategy, err := deployStrategy(cfg, "WEBAPP", deploy.StrategyInPlace, false)
+			if err != nil {
+				return err
 			}
+			logging.Infof("Deploy strategy: %s", strategy.Name())
+			_, err = strategy.Deploy(cmd.Context(), &webAppDeployment{
+				cfg: cfg, resourceGroup: resourceGroup, name: webAppName,
+				appServicePlan: appServicePlan, image: image,
+			})
+			return err //nolint:wrapcheck // strategies name the Web App in their errors
 		},
 	}
 
@@ -110,9 +76,23 @@ func newWebAppCmd() *cobra.Command {
 		"App Service Plan (env: APP_SERVICE_PLAN or <env>_APP_SERVICE_PLAN)")
 	cmd.Flags().StringVar(&image, "image", "",
 		"Full container image (e.g., registry.azurecr.io/image:tag) or auto-built from env vars")
+	cmd.Flags().BoolVar(&runChecks, "preflight", false, "Check live Azure resources before deploying")
 	return cmd
 }
 
+// prepareWebAppConfig resolves CI defaults and the resource group for a WebApp deployment
+// and returns the resource group to deploy into
+func prepareWebAppConfig(cfg *config.Config, resourceGroup string) string {
+	applyCIImageDefaults(cfg)
+
+	// Apply flag overrides
+	if resourceGroup == "" {
+		return cfg.Get("RESOURCE_GROUP")
+	}
+	cfg.Set("RESOURCE_GROUP", resourceGroup)
+	return resourceGroup
+}
+
 // getWebAppName determines the WebApp name based on environment and configuration
 func getWebAppName(cfg *config.Config, env string) string {
 	// Check for environment-specific name first
@@ -146,11 +126,18 @@ func getAppServicePlan(cfg *config.Config, env string) string {
 	return cfg.Get("APP_SERVICE_PLAN")
 }
 
-// checkWebAppExists checks if a WebApp exists in the specified resource group
+// checkWebAppExists checks if a WebApp exists in the specified resource group. Failures
+// other than a missing app (login, network, throttling) are returned.
 func checkWebAppExists(ctx context.Context, resourceGroup, webAppName string) (bool, error) {
-	args := []string{"webapp", "show", "--name", webAppName, "--resource-group", resourceGroup}
-	err := runx.AZ(ctx, args...)
-	return err == nil, nil // If command succeeds, WebApp exists
+	_, err := runx.AZOutput(ctx, "webapp", "show", "--name", webAppName, "--resource-group", resourceGroup,
+		"--output", "json")
+	if runx.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to look up Web App %s: %w", webAppName, err)
+	}
+	return true, nil
 }
 
 // createWebApp creates a new WebApp
@@ -169,34 +156,12 @@ func createWebApp(ctx context.Context, resourceGroup, webAppName, appServicePlan
 
 // updateWebApp updates an existing WebApp with container configuration
 func updateWebApp(ctx context.Context, resourceGroup, webAppName string, cfg *config.Config, customImage string) error {
-	var fullImageName, registryUrl string
-
+	fullImageName, registryUrl, err := resolveWebAppImage(cfg, customImage)
+	if err != nil {
+		return err
+	}
 	if customImage != "" {
-		// Use custom image directly
-		fullImageName = customImage
 		logging.Infof("Using custom image: %s", fullImageName)
-
-		// Extract registry URL from custom image
-		// Expected format: registry.azurecr.io/image:tag or registry.domain/image:tag
-		parts := strings.Split(customImage, "/")
-		if len(parts) > 0 {
-			registryHost := parts[0]
-			registryUrl = fmt.Sprintf("https://%s", registryHost)
-		} else {
-			return fmt.Errorf("invalid image format: %s (expected format: registry.azurecr.io/image:tag)", customImage)
-		}
-	} else {
-		// Build from config variables
-		registry := cfg.Get("ACR_REGISTRY")
-		imageName := cfg.Get("IMAGE_NAME")
-		imageTag := cfg.Get("IMAGE_TAG")
-
-		if registry == "" || imageName == "" || imageTag == "" {
-			return fmt.Errorf("missing required variables: ACR_REGISTRY, IMAGE_NAME, IMAGE_TAG (or use --image flag)")
-		}
-
-		fullImageName = fmt.Sprintf("%s.azurecr.io/%s:%s", registry, imageName, imageTag)
-		registryUrl = fmt.Sprintf("https://%s.azurecr.io", registry)
 	}
 
 	// Set container image
@@ -211,8 +176,12 @@ func updateWebApp(ctx context.Context, resourceGroup, webAppName string, cfg *co
 		return fmt.Errorf("failed to update webapp container: %w", err)
 	}
 
-	// Set Docker registry credentials
-	if err := setWebAppRegistryCredentials(ctx, resourceGroup, webAppName, cfg); err != nil {
+	// Authenticate image pulls with a managed identity or the registry's admin credentials
+	if pullsWithIdentity(cfg) {
+		if err := setWebAppRegistryIdentity(ctx, resourceGroup, webAppName, cfg); err != nil {
+			return fmt.Errorf("failed to set webapp registry identity: %w", err)
+		}
+	} else if err := setWebAppRegistryCredentials(ctx, resourceGroup, webAppName, cfg); err != nil {
 		return fmt.Errorf("failed to set webapp registry credentials: %w", err)
 	}
 
@@ -224,35 +193,32 @@ func updateWebApp(ctx context.Context, resourceGroup, webAppName string, cfg *co
 	return nil
 }
 
-// setWebAppSettings sets application settings (environment variables) for the WebApp
-func setWebAppSettings(ctx context.Context, resourceGroup, webAppName string, cfg *config.Config) error {
-	// Collect only application-specific environment variables (like ACI does)
-	allVars := cfg.GetAll()
-	settings := make([]string, 0, len(allVars))
-	for key, value := range allVars {
-		// Skip internal azctl variables that shouldn't be passed to the container
-		if isInternalVariable(key) {
-			continue
-		}
-
-		// Skip variables with very long values that might cause Azure CLI issues
-		if len(value) > 4000 {
-			logging.Debugf("Skipping variable '%s' - value too long (%d chars)", key, len(value))
-			continue
-		}
+// resolveWebAppImage returns the image to deploy and its registry URL, from customImage
+// (--image) or ACR_REGISTRY, IMAGE_NAME and IMAGE_TAG
+func resolveWebAppImage(cfg *config.Config, customImage string) (image, registryUrl string, err error) {
+	if customImage != "" {
+		// Extract registry URL from custom image
+		// Expected format: registry.azurecr.io/image:tag or registry.domain/image:tag
+		registryHost, _, _ := strings.Cut(customImage, "/")
+		return customImage, fmt.Sprintf("https://%s", registryHost), nil
+	}
 
-		// Only include variables that are application-specific (similar to ACI environmentVariables)
-		if !isApplicationVariable(key) {
-			logging.Debugf("Skipping infrastructure variable '%s'", key)
-			continue
-		}
+	// Build from config variables
+	registry := cfg.Get("ACR_REGISTRY")
+	imageName := cfg.Get("IMAGE_NAME")
+	imageTag := cfg.Get("IMAGE_TAG")
 
-		// Escape the value for shell safety (but don't add quotes)
-		escapedValue := escapeShellValue(value)
-		settings = append(settings, fmt.Sprintf("%s=%s", key, escapedValue))
-		logging.Debugf("Including application setting: %s", key)
+	if registry == "" || imageName == "" || imageTag == "" {
+		return "", "", fmt.Errorf("missing required variables: ACR_REGISTRY, IMAGE_NAME, IMAGE_TAG (or use --image flag)")
 	}
 
+	return fmt.Sprintf("%s.azurecr.io/%s:%s", registry, imageName, imageTag),
+		fmt.Sprintf("https://%s.azurecr.io", registry), nil
+}
+
+// setWebAppSettings sets application settings (environment variables) for the WebApp
+func setWebAppSettings(ctx context.Context, resourceGroup, webAppName string, cfg *config.Config) error {
+	settings := webAppSettings(cfg)
 	if len(settings) == 0 {
 		logging.Debugf("No application settings to configure for WebApp '%s'", webAppName)
 		return nil
@@ -287,22 +253,67 @@ func setWebAppSettings(ctx context.Context, resourceGroup, webAppName string, cf
 	return nil
 }
 
-// setWebAppRegistryCredentials sets Docker registry credentials for the WebApp
-func setWebAppRegistryCredentials(ctx context.Context, resourceGroup, webAppName string, cfg *config.Config) error {
+// webAppSettings returns the application settings (KEY=value) deployed to the WebApp
+func webAppSettings(cfg *config.Config) []string {
+	// Collect only application-specific environment variables (like ACI does)
+	allVars := cfg.GetAll()
+	settings := make([]string, 0, len(allVars))
+	for key, value := range allVars {
+		// Skip internal azctl variables that shouldn't be passed to the container
+		if envvars.IsInternal(key) {
+			continue
+		}
+
+		// Skip variables with very long values that might cause Azure CLI issues
+		if len(value) > 4000 {
+			logging.Debugf("Skipping variable '%s' - value too long (%d chars)", key, len(value))
+			continue
+		}
+
+		// Only include variables that are application-specific (similar to ACI environmentVariables)
+		if !envvars.IsApplication(key) {
+			logging.Debugf("Skipping infrastructure variable '%s'", key)
+			continue
+		}
+
+		// Escape the value for shell safety (but don't add quotes)
+		escapedValue := escapeShellValue(value)
+		settings = append(settings, fmt.Sprintf("%s=%s", key, escapedValue))
+		logging.Debugf("Including application setting: %s", key)
+	}
+	return settings
+}
+
+// webAppRegistrySettings returns the app settings holding the Docker registry credentials;
+// with ACR_AUTH=identity only the registry URL is set
+func webAppRegistrySettings(cfg *config.Config) ([]string, error) {
 	acrRegistry := cfg.Get("ACR_REGISTRY")
 	acrUsername := cfg.Get("ACR_USERNAME")
 	acrPassword := cfg.Get("ACR_PASSWORD")
 
+	// Set Docker registry server URL (should include .azurecr.io suffix)
+	registryUrl := fmt.Sprintf("https://%s.azurecr.io", acrRegistry)
+	if pullsWithIdentity(cfg) && acrRegistry != "" {
+		return []string{fmt.Sprintf("DOCKER_REGISTRY_SERVER_URL=%s", registryUrl)}, nil
+	}
+
 	if acrRegistry == "" || acrUsername == "" || acrPassword == "" {
-		return fmt.Errorf("missing required ACR credentials: ACR_REGISTRY, ACR_USERNAME, ACR_PASSWORD")
+		return nil, fmt.Errorf("missing required ACR credentials: ACR_REGISTRY, ACR_USERNAME, ACR_PASSWORD " +
+			"(or set ACR_AUTH=identity)")
 	}
 
-	// Set Docker registry server URL (should include .azurecr.io suffix)
-	registryUrl := fmt.Sprintf("https://%s.azurecr.io", acrRegistry)
-	registrySettings := []string{
+	return []string{
 		fmt.Sprintf("DOCKER_REGISTRY_SERVER_URL=%s", registryUrl),
 		fmt.Sprintf("DOCKER_REGISTRY_SERVER_USERNAME=%s", acrUsername),
 		fmt.Sprintf("DOCKER_REGISTRY_SERVER_PASSWORD=%s", acrPassword),
+	}, nil
+}
+
+// setWebAppRegistryCredentials sets Docker registry credentials for the WebApp
+func setWebAppRegistryCredentials(ctx context.Context, resourceGroup, webAppName string, cfg *config.Config) error {
+	registrySettings, err := webAppRegistrySettings(cfg)
+	if err != nil {
+		return err
 	}
 
 	// Set Docker registry credentials using az CLI
@@ -314,8 +325,8 @@ func setWebAppRegistryCredentials(ctx context.Context, resourceGroup, webAppName
 	}
 	args = append(args, registrySettings...)
 
-	logging.Debugf("Setting Docker registry credentials for WebApp '%s': URL=%s, Username=%s",
-		webAppName, registryUrl, acrUsername)
+	logging.Debugf("Setting Docker registry credentials for WebApp '%s': %s, %s",
+		webAppName, registrySettings[0], registrySettings[1])
 
 	if err := runx.AZ(ctx, args...); err != nil {
 		return fmt.Errorf("failed to set Docker registry credentials: %w", err)
@@ -332,70 +343,3 @@ func escapeShellValue(value string) string {
 	escaped := strings.ReplaceAll(value, `"`, `\"`)
 	return escaped
 }
-
-// isInternalVariable checks if a variable is internal to azctl and shouldn't be passed to containers
-func isInternalVariable(key string) bool {
-	internalVars := []string{
-		"ACR_REGISTRY",
-		"ACR_RESOURCE_GROUP",
-		"ACR_USERNAME",
-		"ACR_PASSWORD",
-		"RESOURCE_GROUP",
-		"IMAGE_NAME",
-		"IMAGE_TAG",
-		"WEBAPP_NAME",
-		"APP_SERVICE_PLAN",
-		"LOG_STORAGE_ACCOUNT",
-		"LOG_STORAGE_KEY",
-		"LOG_STORAGE_NAME",
-		"FLUENTBIT_CONFIG",
-		"APP_CONFIG_NAME",
-		"APP_CONFIG_LABEL",
-		"APP_CONFIG_SKIP",
-	}
-
-	for _, internal := range internalVars {
-		if key == internal {
-			return true
-		}
-	}
-	return false
-}
-
-// isApplicationVariable checks if a variable should be passed to the application container
-// This matches the environmentVariables in the ACI template
-func isApplicationVariable(key string) bool {
-	// Application-specific prefixes and variables (like in ACI environmentVariables)
-	applicationPrefixes := []string{
-		"NEXT_PUBLIC_",
-		"SUPABASE_",
-		"SOLANA_",
-		"AZURE_OPENAI_",
-		"OPENAI_",
-		"LOGFLARE_",
-		"FIREBASE_",
-		"SAGEMAKER_",
-	}
-
-	// Check prefixes
-	for _, prefix := range applicationPrefixes {
-		if strings.HasPrefix(key, prefix) {
-			return true
-		}
-	}
-
-	// Specific application variables (not prefixed)
-	applicationVars := []string{
-		"PORT",
-		"NODE_ENV",
-		"ENVIRONMENT",
-	}
-
-	for _, appVar := range applicationVars {
-		if key == appVar {
-			return true
-		}
-	}
-
-	return false
-}
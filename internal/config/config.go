@@ -12,6 +12,9 @@ import (
 
 const envTrue = "true"
 
+// SourceDerived marks values that azctl set itself (defaults, CI detection, flag mappings)
+const SourceDerived = "derived"
+
 // Provider defines the interface for configuration providers
 type Provider interface {
 	Name() string
@@ -21,14 +24,18 @@ type Provider interface {
 
 // Config represents the application configuration
 type Config struct {
-	values map[string]string
-	mu     sync.RWMutex
+	values  map[string]string
+	sources map[string]string
+	lines   map[string]int
+	mu      sync.RWMutex
 }
 
 // New creates a new configuration instance
 func New() *Config {
 	return &Config{
-		values: make(map[string]string),
+		values:  make(map[string]string),
+		sources: make(map[string]string),
+		lines:   make(map[string]int),
 	}
 }
 
@@ -56,9 +63,21 @@ func (c *Config) Load(ctx context.Context, envfile string, env string) error {
 			continue
 		}
 
+		source := describeProvider(provider)
+		var lines map[string]int
+		if fileProvider, ok := provider.(*EnvFileProvider); ok {
+			lines = fileProvider.lines
+		}
 		c.mu.Lock()
 		for k, v := range values {
-			c.values[strings.ToUpper(k)] = v
+			key := strings.ToUpper(k)
+			c.values[key] = v
+			c.sources[key] = source
+			if line, ok := lines[key]; ok {
+				c.lines[key] = line
+			} else {
+				delete(c.lines, key)
+			}
 		}
 		c.mu.Unlock()
 	}
@@ -86,6 +105,44 @@ func (c *Config) Set(key, value string) {
 	defer c.mu.Unlock()
 
 	c.values[strings.ToUpper(key)] = value
+	c.sources[strings.ToUpper(key)] = SourceDerived
+	delete(c.lines, strings.ToUpper(key))
+}
+
+// Source reports where a configuration value came from (e.g. ".env.dev", "Environment"),
+// or an empty string if the key is not set
+func (c *Config) Source(key string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.sources[strings.ToUpper(key)]
+}
+
+// Location reports the file and 1-based line a value was loaded from. The line is 0 when
+// the value did not come from a file (environment, App Configuration or derived values).
+func (c *Config) Location(key string) (string, int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	key = strings.ToUpper(key)
+	line := c.lines[key]
+	if line == 0 {
+		return "", 0
+	}
+	return c.sources[key], line
+}
+
+// describeProvider returns a human-readable source label for values loaded by a provider
+func describeProvider(p Provider) string {
+	switch v := p.(type) {
+	case *EnvFileProvider:
+		return v.envfile
+	case *AzureAppConfigProvider:
+		if v.env != "" {
+			return fmt.Sprintf("%s (label: %s)", v.Name(), v.env)
+		}
+	}
+	return p.Name()
 }
 
 // GetAll returns all configuration values
@@ -114,6 +171,17 @@ func (c *Config) Has(key string) bool {
 	return c.Get(key) != ""
 }
 
+// SplitList splits a comma-separated configuration value, dropping empty entries
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // applyFallbacks applies fallback logic for common variables
 func (c *Config) applyFallbacks() {
 	// Try to derive ACR_REGISTRY from other sources
@@ -167,6 +235,7 @@ func (p *EnvironmentProvider) Load(ctx context.Context) (map[string]string, erro
 // EnvFileProvider loads configuration from .env files
 type EnvFileProvider struct {
 	envfile string
+	lines   map[string]int // line number of each key, recorded by Load
 }
 
 func (p *EnvFileProvider) Name() string  { return "EnvFile" }
@@ -197,9 +266,32 @@ func (p *EnvFileProvider) Load(ctx context.Context) (map[string]string, error) {
 		result[strings.ToUpper(k)] = v
 	}
 
+	// Record where each key is defined so validation can point at the offending line
+	if raw, err := os.ReadFile(p.envfile); err == nil {
+		p.lines = envFileLines(string(raw))
+	}
+
 	return result, nil
 }
 
+// envFileLines maps each key assigned in a .env file to the 1-based line of its last assignment
+func envFileLines(content string) map[string]int {
+	lines := make(map[string]int)
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		sep := strings.IndexAny(line, "=:")
+		if sep <= 0 {
+			continue
+		}
+		lines[strings.ToUpper(strings.TrimSpace(line[:sep]))] = i + 1
+	}
+	return lines
+}
+
 // AzureAppConfigProvider loads configuration from Azure App Configuration
 type AzureAppConfigProvider struct {
 	env string
@@ -96,3 +96,54 @@ func TestCISkipsEnvFile(t *testing.T) {
 		t.Errorf("in CI mode, expected env var to win, got %q", got)
 	}
 }
+
+func TestConfigSource(t *testing.T) {
+	envFile := t.TempDir() + "/.env.dev"
+	if err := os.WriteFile(envFile, []byte("SOURCE_TEST_VAR=from_dotenv\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CI", "")
+	t.Setenv("APP_CONFIG_SKIP", "true")
+
+	cfg := New()
+	if err := cfg.Load(context.Background(), envFile, ""); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if got := cfg.Source("SOURCE_TEST_VAR"); got != envFile {
+		t.Errorf("expected source %q, got %q", envFile, got)
+	}
+
+	cfg.Set("SOURCE_TEST_VAR", "overridden")
+	if got := cfg.Source("source_test_var"); got != SourceDerived {
+		t.Errorf("expected source %q after Set, got %q", SourceDerived, got)
+	}
+
+	if got := cfg.Source("NOT_SET"); got != "" {
+		t.Errorf("expected empty source for unset key, got %q", got)
+	}
+}
+
+func TestConfigLocation(t *testing.T) {
+	envFile := t.TempDir() + "/.env.staging"
+	content := "# comment\nFIRST=1\n\nexport SECOND=2\n"
+	if err := os.WriteFile(envFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CI", "")
+	t.Setenv("APP_CONFIG_SKIP", "true")
+
+	cfg := New()
+	if err := cfg.Load(context.Background(), envFile, ""); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if file, line := cfg.Location("SECOND"); file != envFile || line != 4 {
+		t.Errorf("expected %s:4, got %s:%d", envFile, file, line)
+	}
+
+	cfg.Set("FIRST", "overridden")
+	if _, line := cfg.Location("FIRST"); line != 0 {
+		t.Errorf("expected no line after Set, got %d", line)
+	}
+}
@@ -0,0 +1,142 @@
+// Package deploy defines how a resource is replaced when it is deployed. Commands describe
+// the resource as a Target; the Strategy chosen by DEPLOY_STRATEGY decides whether it is
+// deleted first, updated in place, or left alone when nothing changed.
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/furiatona/azctl/internal/config"
+	"github.com/furiatona/azctl/internal/logging"
+)
+
+// Strategy names accepted in DEPLOY_STRATEGY
+const (
+	StrategyRecreate        = "recreate"
+	StrategyInPlace         = "in-place"
+	StrategySkipIfUnchanged = "skip-if-unchanged"
+)
+
+// Target is a deployable resource, such as an ACI container group or a Web App
+type Target interface {
+	// Name identifies the resource in logs, e.g. "container group api"
+	Name() string
+	Exists(ctx context.Context) (bool, error)
+	// Changed reports whether applying would change the live resource
+	Changed(ctx context.Context) (bool, error)
+	Delete(ctx context.Context) error
+	// Apply creates the resource or updates it in place
+	Apply(ctx context.Context) error
+}
+
+// Validator is implemented by targets that can tell up front whether they can be applied.
+// Recreate checks it before deleting, so a target it could not create again is left alone.
+type Validator interface {
+	Validate(ctx context.Context) error
+}
+
+// Strategy deploys a target
+type Strategy interface {
+	Name() string
+	// Deploy deploys t and reports whether anything was deployed
+	Deploy(ctx context.Context, t Target) (bool, error)
+}
+
+// New returns the strategy with the given name
+func New(name string) (Strategy, error) {
+	switch strings.ToLower(name) {
+	case StrategyRecreate:
+		return Recreate{}, nil
+	case StrategyInPlace:
+		return InPlace{}, nil
+	case StrategySkipIfUnchanged:
+		return SkipIfUnchanged{}, nil
+	}
+	return nil, fmt.Errorf("unknown deploy strategy %q (expected %s, %s or %s)",
+		name, StrategyRecreate, StrategyInPlace, StrategySkipIfUnchanged)
+}
+
+// FromConfig returns the strategy set in <prefix>_DEPLOY_STRATEGY (e.g. ACI_DEPLOY_STRATEGY),
+// then DEPLOY_STRATEGY, or fallback if neither is set
+func FromConfig(cfg *config.Config, prefix, fallback string) (Strategy, error) {
+	name := cfg.Get(prefix + "_DEPLOY_STRATEGY")
+	if name == "" {
+		name = cfg.Get("DEPLOY_STRATEGY")
+	}
+	if name == "" {
+		name = fallback
+	}
+	strategy, err := New(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DEPLOY_STRATEGY: %w", err)
+	}
+	return strategy, nil
+}
+
+// Recreate deletes an existing resource before creating it again. It avoids drift from
+// in-place updates at the cost of downtime.
+type Recreate struct{}
+
+// Name implements Strategy
+func (Recreate) Name() string { return StrategyRecreate }
+
+// Deploy implements Strategy
+func (Recreate) Deploy(ctx context.Context, t Target) (bool, error) {
+	exists, err := t.Exists(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check %s: %w", t.Name(), err)
+	}
+	if exists {
+		if v, ok := t.(Validator); ok {
+			if err := v.Validate(ctx); err != nil {
+				return false, fmt.Errorf("refusing to delete %s: %w", t.Name(), err)
+			}
+		}
+		logging.Infof("🗑️  Deleting %s before recreating it...", t.Name())
+		if err := t.Delete(ctx); err != nil {
+			return false, fmt.Errorf("failed to delete %s: %w", t.Name(), err)
+		}
+		logging.Infof("✅ Deleted %s", t.Name())
+	}
+	return true, t.Apply(ctx)
+}
+
+// InPlace creates the resource or updates the existing one
+type InPlace struct{}
+
+// Name implements Strategy
+func (InPlace) Name() string { return StrategyInPlace }
+
+// Deploy implements Strategy
+func (InPlace) Deploy(ctx context.Context, t Target) (bool, error) {
+	return true, t.Apply(ctx)
+}
+
+// SkipIfUnchanged updates the resource in place, unless it already matches what would be
+// deployed
+type SkipIfUnchanged struct{}
+
+// Name implements Strategy
+func (SkipIfUnchanged) Name() string { return StrategySkipIfUnchanged }
+
+// Deploy implements Strategy
+func (SkipIfUnchanged) Deploy(ctx context.Context, t Target) (bool, error) {
+	changed, err := t.Changed(ctx)
+	if err != nil {
+		// Deploying when unsure is the safe choice
+		logging.Warnf("Could not compare with the live %s: %v", t.Name(), err)
+	} else if !changed {
+		logging.Infof("✅ %s is unchanged. Skipping deployment", capitalize(t.Name()))
+		return false, nil
+	}
+	return true, t.Apply(ctx)
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,106 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/furiatona/azctl/internal/config"
+)
+
+// fakeTarget records the operations a strategy performs
+type fakeTarget struct {
+	exists     bool
+	existsErr  error
+	changed    bool
+	changedErr error
+	invalid    error
+	ops        []string
+}
+
+func (t *fakeTarget) Name() string { return "container group api" }
+
+func (t *fakeTarget) Exists(context.Context) (bool, error) { return t.exists, t.existsErr }
+
+func (t *fakeTarget) Changed(context.Context) (bool, error) { return t.changed, t.changedErr }
+
+func (t *fakeTarget) Validate(context.Context) error { return t.invalid }
+
+func (t *fakeTarget) Delete(context.Context) error {
+	t.ops = append(t.ops, "delete")
+	return nil
+}
+
+func (t *fakeTarget) Apply(context.Context) error {
+	t.ops = append(t.ops, "apply")
+	return nil
+}
+
+func TestStrategies(t *testing.T) {
+	tests := []struct {
+		strategy Strategy
+		target   *fakeTarget
+		deployed bool
+		ops      string
+	}{
+		{Recreate{}, &fakeTarget{exists: true}, true, "delete,apply"},
+		{Recreate{}, &fakeTarget{}, true, "apply"},
+		{InPlace{}, &fakeTarget{exists: true}, true, "apply"},
+		{SkipIfUnchanged{}, &fakeTarget{exists: true, changed: true}, true, "apply"},
+		{SkipIfUnchanged{}, &fakeTarget{exists: true}, false, ""},
+		{SkipIfUnchanged{}, &fakeTarget{changedErr: errors.New("az failed")}, true, "apply"},
+	}
+	for _, tt := range tests {
+		deployed, err := tt.strategy.Deploy(context.Background(), tt.target)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.strategy.Name(), err)
+		}
+		if deployed != tt.deployed || strings.Join(tt.target.ops, ",") != tt.ops {
+			t.Errorf("%s: deployed=%v ops=%v, want %v %q",
+				tt.strategy.Name(), deployed, tt.target.ops, tt.deployed, tt.ops)
+		}
+	}
+}
+
+func TestRecreateValidates(t *testing.T) {
+	target := &fakeTarget{exists: true, invalid: errors.New("APP_SERVICE_PLAN not provided")}
+	if _, err := (Recreate{}).Deploy(context.Background(), target); err == nil ||
+		!strings.Contains(err.Error(), "refusing to delete") {
+		t.Errorf("expected a refusal, got %v", err)
+	}
+	if len(target.ops) != 0 {
+		t.Errorf("expected nothing to be deleted, got %v", target.ops)
+	}
+}
+
+func TestRecreateLookupFailure(t *testing.T) {
+	target := &fakeTarget{existsErr: errors.New("az login expired")}
+	if _, err := (Recreate{}).Deploy(context.Background(), target); err == nil ||
+		!strings.Contains(err.Error(), "az login expired") {
+		t.Errorf("expected the lookup error, got %v", err)
+	}
+	if len(target.ops) != 0 {
+		t.Errorf("expected nothing to be deleted or applied, got %v", target.ops)
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	cfg := config.New()
+	if s, err := FromConfig(cfg, "WEBAPP", StrategyInPlace); err != nil || s.Name() != StrategyInPlace {
+		t.Errorf("expected the fallback, got %v (err %v)", s, err)
+	}
+	cfg.Set("DEPLOY_STRATEGY", "Recreate")
+	if s, err := FromConfig(cfg, "WEBAPP", StrategyInPlace); err != nil || s.Name() != StrategyRecreate {
+		t.Errorf("expected recreate, got %v (err %v)", s, err)
+	}
+	cfg.Set("WEBAPP_DEPLOY_STRATEGY", "skip-if-unchanged")
+	if s, err := FromConfig(cfg, "WEBAPP", StrategyInPlace); err != nil || s.Name() != StrategySkipIfUnchanged {
+		t.Errorf("expected the command's own setting to win, got %v (err %v)", s, err)
+	}
+	cfg.Set("WEBAPP_DEPLOY_STRATEGY", "rolling")
+	_, err := FromConfig(cfg, "WEBAPP", StrategyInPlace)
+	if err == nil || !strings.Contains(err.Error(), "DEPLOY_STRATEGY") {
+		t.Errorf("expected an invalid strategy error, got %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+// Package envvars decides which configuration keys are passed to application containers
+// (ACI environmentVariables, WebApp app settings) and which of them are secrets.
+package envvars
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/furiatona/azctl/internal/config"
+	"github.com/furiatona/azctl/internal/validation"
+)
+
+// internalVars are azctl infrastructure keys that are never passed to containers
+var internalVars = []string{
+	"ACR_REGISTRY",
+	"ACR_RESOURCE_GROUP",
+	"ACR_USERNAME",
+	"ACR_PASSWORD",
+	"ACR_AUTH",
+	"ACR_IDENTITY_ID",
+	"ACR_ASSIGN_PULL_ROLE",
+	"ACI_SECRETS_HASH_KEY",
+	"RESOURCE_GROUP",
+	"IMAGE_NAME",
+	"IMAGE_TAG",
+	"WEBAPP_NAME",
+	"APP_SERVICE_PLAN",
+	"LOG_STORAGE_ACCOUNT",
+	"LOG_STORAGE_KEY",
+	"LOG_STORAGE_NAME",
+	"FLUENTBIT_CONFIG",
+	"APP_CONFIG_NAME",
+	"APP_CONFIG_LABEL",
+	"APP_CONFIG_SKIP",
+}
+
+// applicationPrefixes and applicationVars select application keys when no explicit
+// selection is configured
+var (
+	applicationPrefixes = []string{
+		"NEXT_PUBLIC_",
+		"SUPABASE_",
+		"SOLANA_",
+		"AZURE_OPENAI_",
+		"OPENAI_",
+		"LOGFLARE_",
+		"FIREBASE_",
+		"SAGEMAKER_",
+	}
+	applicationVars = []string{
+		"PORT",
+		"NODE_ENV",
+		"ENVIRONMENT",
+	}
+)
+
+// IsInternal checks if a variable is internal to azctl and shouldn't be passed to containers
+func IsInternal(key string) bool {
+	return contains(internalVars, key)
+}
+
+// IsApplication checks if a variable belongs to the application by its well-known prefix or name
+func IsApplication(key string) bool {
+	return hasAnyPrefix(key, applicationPrefixes) || contains(applicationVars, key)
+}
+
+// IsSecret reports whether a value must not appear in plain text (see validation.IsSecret)
+func IsSecret(key, value string) bool {
+	return validation.IsSecret(key, value)
+}
+
+// Classifier decides which variables are secrets: the IsSecret heuristics, overridden by
+// explicit lists
+type Classifier struct {
+	// Secret lists keys that are always secrets
+	Secret []string
+	// Plain lists keys that are never secrets, for values the heuristics misjudge
+	Plain []string
+}
+
+// ClassifierFromConfig reads ACI_SECRET_KEYS and ACI_PLAIN_KEYS (comma-separated)
+func ClassifierFromConfig(cfg *config.Config) Classifier {
+	return Classifier{
+		Secret: config.SplitList(cfg.Get("ACI_SECRET_KEYS")),
+		Plain:  config.SplitList(cfg.Get("ACI_PLAIN_KEYS")),
+	}
+}
+
+// IsSecret reports whether a variable must be passed as a secret
+func (c Classifier) IsSecret(key, value string) bool {
+	if contains(c.Plain, key) {
+		return false
+	}
+	return contains(c.Secret, key) || IsSecret(key, value)
+}
+
+// Selection chooses the configuration keys passed to a container. With no Include prefixes
+// and no Keys, the well-known application keys are selected.
+type Selection struct {
+	// Include selects keys starting with any of these prefixes
+	Include []string
+	// Exclude drops keys starting with any of these prefixes, even if listed in Keys
+	Exclude []string
+	// Keys selects these exact keys
+	Keys []string
+	// Classifier marks the selected variables that are secrets
+	Classifier Classifier
+}
+
+// SelectionFromConfig reads a selection from ACI_ENV_INCLUDE, ACI_ENV_EXCLUDE and ACI_ENV_KEYS
+// (comma-separated), classifying secrets with ClassifierFromConfig
+func SelectionFromConfig(cfg *config.Config) Selection {
+	return Selection{
+		Include:    config.SplitList(cfg.Get("ACI_ENV_INCLUDE")),
+		Exclude:    config.SplitList(cfg.Get("ACI_ENV_EXCLUDE")),
+		Keys:       config.SplitList(cfg.Get("ACI_ENV_KEYS")),
+		Classifier: ClassifierFromConfig(cfg),
+	}
+}
+
+// Matches reports whether the selection includes key. Internal keys never match.
+func (s Selection) Matches(key string) bool {
+	key = strings.ToUpper(key)
+	if IsInternal(key) || hasAnyPrefix(key, s.Exclude) {
+		return false
+	}
+	if len(s.Include) == 0 && len(s.Keys) == 0 {
+		return IsApplication(key)
+	}
+	return hasAnyPrefix(key, s.Include) || contains(s.Keys, key)
+}
+
+// Variable is a selected configuration value
+type Variable struct {
+	Name   string
+	Value  string
+	Secret bool
+}
+
+// Select returns the selected variables with non-empty values, sorted by name
+func Select(cfg *config.Config, s Selection) []Variable {
+	var vars []Variable
+	for key, value := range cfg.GetAll() {
+		if value == "" || !s.Matches(key) {
+			continue
+		}
+		vars = append(vars, Variable{Name: key, Value: value, Secret: s.Classifier.IsSecret(key, value)})
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	return vars
+}
+
+func contains(values []string, key string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyPrefix(key string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, strings.ToUpper(prefix)) {
+			return true
+		}
+	}
+	return false
+}
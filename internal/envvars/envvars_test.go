@@ -0,0 +1,90 @@
+package envvars
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/furiatona/azctl/internal/config"
+)
+
+func TestSelect(t *testing.T) {
+	cfg := config.New()
+	cfg.Set("NEXT_PUBLIC_URL", "https://example.com")
+	cfg.Set("SUPABASE_URL", "https://db.example.com")
+	cfg.Set("SUPABASE_KEY", "anon")
+	cfg.Set("SUPABASE_SERVICE_ROLE", "service")
+	cfg.Set("PORT", "8080")
+	cfg.Set("FEATURE_FLAGS", "beta")
+	cfg.Set("ACR_PASSWORD", "registry-secret")
+	cfg.Set("EMPTY_VALUE", "")
+
+	names := func(vars []Variable) []string {
+		var result []string
+		for _, v := range vars {
+			result = append(result, v.Name)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name      string
+		selection Selection
+		want      []string
+	}{
+		{"default application keys", Selection{},
+			[]string{"NEXT_PUBLIC_URL", "PORT", "SUPABASE_KEY", "SUPABASE_SERVICE_ROLE", "SUPABASE_URL"}},
+		{"include and exclude", Selection{Include: []string{"supabase_"}, Exclude: []string{"SUPABASE_SERVICE_"}},
+			[]string{"SUPABASE_KEY", "SUPABASE_URL"}},
+		{"explicit keys", Selection{Keys: []string{"FEATURE_FLAGS", "EMPTY_VALUE", "ACR_PASSWORD"}},
+			[]string{"FEATURE_FLAGS"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := names(Select(cfg, tt.selection)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsSecret(t *testing.T) {
+	tests := []struct {
+		key, value string
+		want       bool
+	}{
+		{"SUPABASE_KEY", "anon", true},
+		{"STRIPE_WEBHOOK_SECRET", "x", true},
+		{"DATABASE_URL", "postgres://user:pass@db:5432/app", true},
+		{"OPENAI_API", "sk-abcdefghijklmnopqrstuvwxyz123456", true},
+		{"SUPABASE_URL", "https://db.example.com", false},
+		{"PORT", "8080", false},
+	}
+	for _, tt := range tests {
+		if got := IsSecret(tt.key, tt.value); got != tt.want {
+			t.Errorf("IsSecret(%s) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestClassifier(t *testing.T) {
+	cfg := config.New()
+	cfg.Set("ACI_SECRET_KEYS", "SUPABASE_URL")
+	cfg.Set("ACI_PLAIN_KEYS", "BUILD_SHA, SUPABASE_KEY")
+	c := ClassifierFromConfig(cfg)
+
+	tests := []struct {
+		key, value string
+		want       bool
+	}{
+		{"SUPABASE_URL", "https://db.example.com", true},
+		{"supabase_key", "anon", false},
+		{"BUILD_SHA", "9f8e7d6c5b4a39281706f5e4d3c2b1a0", false},
+		{"STRIPE_WEBHOOK_SECRET", "x", true},
+		{"PORT", "8080", false},
+	}
+	for _, tt := range tests {
+		if got := c.IsSecret(tt.key, tt.value); got != tt.want {
+			t.Errorf("IsSecret(%s) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
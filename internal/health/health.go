@@ -0,0 +1,314 @@
+// Package health verifies a container group after deployment: every container must reach the
+// Running state, and the application can optionally be probed over HTTP.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/furiatona/azctl/internal/logging"
+	"github.com/furiatona/azctl/internal/runx"
+)
+
+// Defaults for post-deploy verification
+const (
+	DefaultTimeout = 5 * time.Minute
+	DefaultStatus  = http.StatusOK
+)
+
+// PollInterval is the delay between status checks; tests shorten it
+var PollInterval = 5 * time.Second
+
+// HTTPClient sends health probes; each request is bounded by its own timeout
+var HTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// ContainerStatus is the runtime state of one container
+type ContainerStatus struct {
+	Name         string `json:"name"`
+	Image        string `json:"image,omitempty"`
+	State        string `json:"state"`
+	DetailStatus string `json:"detailStatus,omitempty"`
+	RestartCount int    `json:"restartCount"`
+	ExitCode     *int   `json:"exitCode,omitempty"`
+	// PreviousState and PreviousExitCode describe the run before the last restart
+	PreviousState    string `json:"previousState,omitempty"`
+	PreviousExitCode *int   `json:"previousExitCode,omitempty"`
+}
+
+// Event is an instance view event such as Pulling, Started or BackOff
+type Event struct {
+	Container string    `json:"container,omitempty"`
+	Type      string    `json:"type"`
+	Name      string    `json:"name"`
+	Message   string    `json:"message"`
+	Count     int       `json:"count"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// Status is the runtime state of a container group
+type Status struct {
+	ProvisioningState string            `json:"provisioningState"`
+	State             string            `json:"state"`
+	IP                string            `json:"ip,omitempty"`
+	FQDN              string            `json:"fqdn,omitempty"`
+	Ports             []int             `json:"ports,omitempty"`
+	Containers        []ContainerStatus `json:"containers"`
+	Events            []Event           `json:"events"`
+}
+
+// ParseStatus reads the instance view from `az container show` output
+func ParseStatus(data []byte) (*Status, error) {
+	var group struct {
+		ProvisioningState string `json:"provisioningState"`
+		InstanceView      *struct {
+			State  string      `json:"state"`
+			Events []eventJSON `json:"events"`
+		} `json:"instanceView"`
+		IPAddress *struct {
+			IP    string `json:"ip"`
+			FQDN  string `json:"fqdn"`
+			Ports []struct {
+				Port int `json:"port"`
+			} `json:"ports"`
+		} `json:"ipAddress"`
+		Containers []struct {
+			Name         string `json:"name"`
+			Image        string `json:"image"`
+			InstanceView *struct {
+				RestartCount  int         `json:"restartCount"`
+				CurrentState  stateJSON   `json:"currentState"`
+				PreviousState *stateJSON  `json:"previousState"`
+				Events        []eventJSON `json:"events"`
+			} `json:"instanceView"`
+		} `json:"containers"`
+	}
+	if err := json.Unmarshal(data, &group); err != nil {
+		return nil, fmt.Errorf("failed to parse container group: %w", err)
+	}
+
+	status := &Status{ProvisioningState: group.ProvisioningState}
+	if group.IPAddress != nil {
+		status.IP, status.FQDN = group.IPAddress.IP, group.IPAddress.FQDN
+		for _, p := range group.IPAddress.Ports {
+			status.Ports = append(status.Ports, p.Port)
+		}
+	}
+	if group.InstanceView != nil {
+		status.State = group.InstanceView.State
+		for _, e := range group.InstanceView.Events {
+			status.Events = append(status.Events, e.event(""))
+		}
+	}
+	for _, c := range group.Containers {
+		container := ContainerStatus{Name: c.Name, Image: c.Image}
+		if c.InstanceView != nil {
+			container.State = c.InstanceView.CurrentState.State
+			container.DetailStatus = c.InstanceView.CurrentState.DetailStatus
+			container.ExitCode = c.InstanceView.CurrentState.ExitCode
+			container.RestartCount = c.InstanceView.RestartCount
+			if previous := c.InstanceView.PreviousState; previous != nil {
+				container.PreviousState, container.PreviousExitCode = previous.State, previous.ExitCode
+			}
+			for _, e := range c.InstanceView.Events {
+				status.Events = append(status.Events, e.event(c.Name))
+			}
+		}
+		status.Containers = append(status.Containers, container)
+	}
+	return status, nil
+}
+
+type stateJSON struct {
+	State        string `json:"state"`
+	DetailStatus string `json:"detailStatus"`
+	ExitCode     *int   `json:"exitCode"`
+}
+
+type eventJSON struct {
+	Type          string    `json:"type"`
+	Name          string    `json:"name"`
+	Message       string    `json:"message"`
+	Count         int       `json:"count"`
+	LastTimestamp time.Time `json:"lastTimestamp"`
+}
+
+func (e eventJSON) event(container string) Event {
+	return Event{
+		Container: container, Type: e.Type, Name: e.Name, Message: e.Message, Count: e.Count,
+		LastSeen: e.LastTimestamp,
+	}
+}
+
+// Running reports whether every container is running
+func (s *Status) Running() bool {
+	if len(s.Containers) == 0 {
+		return false
+	}
+	for _, c := range s.Containers {
+		if c.State != "Running" {
+			return false
+		}
+	}
+	return true
+}
+
+// Failure returns an error if the group cannot become healthy without intervention: the
+// deployment failed, or a container is crash looping or has exited
+func (s *Status) Failure() error {
+	if strings.EqualFold(s.ProvisioningState, "Failed") || strings.EqualFold(s.State, "Failed") {
+		return fmt.Errorf("container group failed (%s)", s.Summary())
+	}
+	for _, c := range s.Containers {
+		if strings.Contains(c.DetailStatus, "CrashLoopBackOff") {
+			return fmt.Errorf("container %s is crash looping after %d restart(s): %s",
+				c.Name, c.RestartCount, c.DetailStatus)
+		}
+		if c.State == "Terminated" && c.ExitCode != nil && *c.ExitCode != 0 {
+			return fmt.Errorf("container %s exited with code %d after %d restart(s)", c.Name, *c.ExitCode, c.RestartCount)
+		}
+	}
+	return nil
+}
+
+// Summary describes the state of every container, e.g. "app: Running, otel: Waiting (2 restarts)"
+func (s *Status) Summary() string {
+	parts := make([]string, 0, len(s.Containers))
+	for _, c := range s.Containers {
+		state := c.State
+		if state == "" {
+			state = "Pending"
+		}
+		part := fmt.Sprintf("%s: %s", c.Name, state)
+		if c.RestartCount > 0 {
+			part += fmt.Sprintf(" (%d restarts)", c.RestartCount)
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return "no containers reported"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// FetchStatus returns the current runtime state of a container group
+func FetchStatus(ctx context.Context, resourceGroup, name string) (*Status, error) {
+	out, err := runx.AZOutput(ctx, "container", "show",
+		"--resource-group", resourceGroup, "--name", name, "--output", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container group %s: %w", name, err)
+	}
+	return ParseStatus([]byte(out))
+}
+
+// WaitForRunning polls the container group until every container is running. New events and
+// restarts are logged as they appear. It fails as soon as the group cannot recover, or when
+// timeout elapses.
+func WaitForRunning(ctx context.Context, resourceGroup, name string, timeout time.Duration) (*Status, error) {
+	deadline := time.Now().Add(timeout)
+	seen := make(map[string]bool)
+	for {
+		status, err := FetchStatus(ctx, resourceGroup, name)
+		if err != nil {
+			return nil, err
+		}
+		logEvents(status, seen)
+
+		if err := status.Failure(); err != nil {
+			return status, err
+		}
+		if status.Running() {
+			return status, nil
+		}
+		if time.Now().After(deadline) {
+			return status, fmt.Errorf("containers not running after %s (%s)", timeout, status.Summary())
+		}
+
+		logging.Infof("⏳ Waiting for containers: %s", status.Summary())
+		select {
+		case <-ctx.Done():
+			return status, fmt.Errorf("stopped waiting for containers: %w", ctx.Err())
+		case <-time.After(PollInterval):
+		}
+	}
+}
+
+func logEvents(status *Status, seen map[string]bool) {
+	for _, e := range status.Events {
+		key := fmt.Sprintf("%s/%s/%s/%d", e.Container, e.Name, e.Message, e.Count)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		source := e.Container
+		if source == "" {
+			source = "group"
+		}
+		if strings.EqualFold(e.Type, "Warning") {
+			logging.Warnf("[%s] %s: %s", source, e.Name, e.Message)
+		} else {
+			logging.Debugf("[%s] %s: %s", source, e.Name, e.Message)
+		}
+	}
+}
+
+// ProbeURL returns the public URL of a container group endpoint,
+// http://<label>.<location>.azurecontainer.io:<port><path>; an empty port means port 80
+func ProbeURL(dnsLabel, location, port, path string) string {
+	host := fmt.Sprintf("%s.%s.azurecontainer.io", dnsLabel, strings.ToLower(strings.ReplaceAll(location, " ", "")))
+	return HostURL(host, port, path)
+}
+
+// HostURL returns http://<host>:<port><path>, e.g. for the private IP of a container group;
+// an empty port means port 80
+func HostURL(host, port, path string) string {
+	if port != "" {
+		host += ":" + port
+	}
+	if path != "" && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return "http://" + host + path
+}
+
+// Probe sends GET requests to url until it answers with the expected status or timeout
+// elapses. Connection errors are retried since the DNS record and listener may lag behind
+// the container reaching Running.
+func Probe(ctx context.Context, url string, expected int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		status, err := get(ctx, url)
+		if err == nil && status == expected {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			if err != nil {
+				return fmt.Errorf("GET %s failed after %s: %w", url, timeout, err)
+			}
+			return fmt.Errorf("GET %s returned %d after %s, expected %d", url, status, timeout, expected)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stopped probing %s: %w", url, ctx.Err())
+		case <-time.After(PollInterval):
+		}
+	}
+}
+
+func get(ctx context.Context, url string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("invalid probe URL: %w", err)
+	}
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode, nil
+}
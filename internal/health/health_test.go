@@ -0,0 +1,133 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/furiatona/azctl/internal/runx"
+)
+
+const runningGroup = `{
+  "provisioningState": "Succeeded",
+  "instanceView": {"state": "Running", "events": []},
+  "ipAddress": {"ip": "20.1.2.3", "fqdn": "api.westeurope.azurecontainer.io", "ports": [{"port": 8080}]},
+  "containers": [
+    {"name": "app", "instanceView": {"restartCount": 0, "currentState": {"state": "Running"},
+      "events": [{"type": "Normal", "name": "Started", "message": "Started container", "count": 1,
+        "lastTimestamp": "2024-05-01T10:00:00Z"}]}},
+    {"name": "otel", "instanceView": {"restartCount": 1, "currentState": {"state": "Running"},
+      "previousState": {"state": "Terminated", "exitCode": 137}}}
+  ]
+}`
+
+func TestParseStatus(t *testing.T) {
+	status, err := ParseStatus([]byte(runningGroup))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Running() || status.Failure() != nil {
+		t.Errorf("expected a healthy group, got %+v", status)
+	}
+	if got := status.Summary(); got != "app: Running, otel: Running (1 restarts)" {
+		t.Errorf("summary = %q", got)
+	}
+	if len(status.Events) != 1 || status.Events[0].Container != "app" || status.Events[0].LastSeen.IsZero() {
+		t.Errorf("unexpected events: %+v", status.Events)
+	}
+	otel := status.Containers[1]
+	if otel.PreviousState != "Terminated" || otel.PreviousExitCode == nil || *otel.PreviousExitCode != 137 {
+		t.Errorf("unexpected previous state: %+v", otel)
+	}
+	if status.IP != "20.1.2.3" || len(status.Ports) != 1 || status.Ports[0] != 8080 {
+		t.Errorf("unexpected address: %s %v", status.IP, status.Ports)
+	}
+}
+
+func TestStatusFailure(t *testing.T) {
+	tests := map[string]string{
+		"crash loop": `{"containers": [{"name": "app", "instanceView": {"restartCount": 4, "currentState":
+			{"state": "Waiting", "detailStatus": "CrashLoopBackOff: Back-off restarting failed"}}}]}`,
+		"exited": `{"containers": [{"name": "otel", "instanceView": {"currentState":
+			{"state": "Terminated", "exitCode": 1, "detailStatus": "Error"}}}]}`,
+		"failed group": `{"provisioningState": "Failed", "containers": [{"name": "app"}]}`,
+	}
+	for name, doc := range tests {
+		t.Run(name, func(t *testing.T) {
+			status, err := ParseStatus([]byte(doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status.Failure() == nil {
+				t.Errorf("expected a failure for %+v", status)
+			}
+		})
+	}
+
+	pending, _ := ParseStatus([]byte(`{"containers": [{"name": "app", "instanceView": {"currentState":
+		{"state": "Waiting", "detailStatus": "ContainerCreating"}}}]}`))
+	if pending.Failure() != nil || pending.Running() {
+		t.Errorf("a creating container is neither failed nor running: %+v", pending)
+	}
+}
+
+func TestWaitForRunning(t *testing.T) {
+	defer func(d time.Duration) { PollInterval = d }(PollInterval)
+	PollInterval = time.Millisecond
+
+	fake := runx.NewFake().On("container show", runningGroup, nil)
+	defer runx.SetExecutor(fake)()
+	if _, err := WaitForRunning(context.Background(), "rg", "api", time.Second); err != nil {
+		t.Fatalf("expected running group: %v", err)
+	}
+
+	crashing := `{"containers": [{"name": "app", "instanceView": {"restartCount": 3,
+		"currentState": {"state": "Waiting", "detailStatus": "CrashLoopBackOff"}}}]}`
+	defer runx.SetExecutor(runx.NewFake().On("container show", crashing, nil))()
+	_, err := WaitForRunning(context.Background(), "rg", "api", time.Second)
+	if err == nil || !strings.Contains(err.Error(), "crash looping after 3 restart(s)") {
+		t.Errorf("expected crash loop error, got %v", err)
+	}
+
+	pending := `{"containers": [{"name": "app", "instanceView": {"currentState": {"state": "Waiting"}}}]}`
+	defer runx.SetExecutor(runx.NewFake().On("container show", pending, nil))()
+	_, err = WaitForRunning(context.Background(), "rg", "api", 10*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "app: Waiting") {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	defer func(d time.Duration) { PollInterval = d }(PollInterval)
+	PollInterval = time.Millisecond
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Unavailable until the third request
+		if requests.Add(1) < 3 || r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	if err := Probe(context.Background(), server.URL+"/healthz", http.StatusOK, time.Second); err != nil {
+		t.Errorf("expected probe to succeed: %v", err)
+	}
+	err := Probe(context.Background(), server.URL+"/missing", http.StatusOK, 10*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "returned 503") {
+		t.Errorf("expected status error, got %v", err)
+	}
+}
+
+func TestProbeURL(t *testing.T) {
+	got := ProbeURL("api-prod", "West Europe", "8080", "healthz")
+	if got != "http://api-prod.westeurope.azurecontainer.io:8080/healthz" {
+		t.Errorf("got %s", got)
+	}
+}
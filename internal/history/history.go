@@ -0,0 +1,139 @@
+// Package history records deployed container group definitions as numbered revisions so a
+// previous deployment can be inspected and redeployed.
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDir is where the local store keeps revisions
+const DefaultDir = ".azctl/history"
+
+// ErrNotFound is returned when a revision does not exist
+var ErrNotFound = errors.New("revision not found")
+
+// Revision is one successful deployment of a container group
+type Revision struct {
+	Number        int       `json:"number"`
+	ResourceGroup string    `json:"resourceGroup"`
+	Name          string    `json:"name"`
+	Environment   string    `json:"environment,omitempty"`
+	Image         string    `json:"image,omitempty"`
+	ConfigHash    string    `json:"configHash"`
+	Commit        string    `json:"commit,omitempty"`
+	DeployedAt    time.Time `json:"deployedAt"`
+	// Note describes how the revision was created, e.g. "rollback to 3"
+	Note string `json:"note,omitempty"`
+	// Definition is the deployed container group with secrets replaced by aci.SecretPlaceholder
+	Definition json.RawMessage `json:"definition"`
+}
+
+// Store persists revisions. Save assigns the next revision number.
+type Store interface {
+	Save(ctx context.Context, rev *Revision) error
+	// List returns the revisions of a container group, oldest first
+	List(ctx context.Context, resourceGroup, name string) ([]Revision, error)
+	Get(ctx context.Context, resourceGroup, name string, number int) (*Revision, error)
+}
+
+// LocalStore keeps revisions as JSON files under Dir/<resource group>/<container group>/
+type LocalStore struct {
+	Dir string
+}
+
+// NewLocalStore creates a store rooted at dir, or DefaultDir if dir is empty
+func NewLocalStore(dir string) *LocalStore {
+	if dir == "" {
+		dir = DefaultDir
+	}
+	return &LocalStore{Dir: dir}
+}
+
+// Save writes rev as the next revision of its container group
+func (s *LocalStore) Save(ctx context.Context, rev *Revision) error {
+	existing, err := s.List(ctx, rev.ResourceGroup, rev.Name)
+	if err != nil {
+		return err
+	}
+	rev.Number = 1
+	if len(existing) > 0 {
+		rev.Number = existing[len(existing)-1].Number + 1
+	}
+
+	dir := s.groupDir(rev.ResourceGroup, rev.Name)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	data, err := json.MarshalIndent(rev, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode revision: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, fileName(rev.Number)), data, 0o600); err != nil {
+		return fmt.Errorf("failed to write revision: %w", err)
+	}
+	return nil
+}
+
+// List returns every stored revision of a container group, oldest first
+func (s *LocalStore) List(_ context.Context, resourceGroup, name string) ([]Revision, error) {
+	dir := s.groupDir(resourceGroup, name)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	var revisions []Revision
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), ".json")); err != nil || entry.IsDir() {
+			continue
+		}
+		rev, err := readRevision(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *rev)
+	}
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Number < revisions[j].Number })
+	return revisions, nil
+}
+
+// Get returns a single revision
+func (s *LocalStore) Get(_ context.Context, resourceGroup, name string, number int) (*Revision, error) {
+	rev, err := readRevision(filepath.Join(s.groupDir(resourceGroup, name), fileName(number)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s/%s #%d", ErrNotFound, resourceGroup, name, number)
+	}
+	return rev, err
+}
+
+func (s *LocalStore) groupDir(resourceGroup, name string) string {
+	return filepath.Join(s.Dir, filepath.Base(resourceGroup), filepath.Base(name))
+}
+
+func fileName(number int) string {
+	return fmt.Sprintf("%06d.json", number)
+}
+
+func readRevision(path string) (*Revision, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is built from the store directory
+	if err != nil {
+		return nil, err //nolint:wrapcheck // callers check for os.ErrNotExist
+	}
+	var rev Revision
+	if err := json.Unmarshal(data, &rev); err != nil {
+		return nil, fmt.Errorf("invalid revision %s: %w", path, err)
+	}
+	return &rev, nil
+}
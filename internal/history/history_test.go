@@ -0,0 +1,50 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewLocalStore(t.TempDir())
+
+	revisions, err := store.List(ctx, "rg", "api")
+	if err != nil || len(revisions) != 0 {
+		t.Fatalf("expected empty history, got %v (err %v)", revisions, err)
+	}
+
+	for _, image := range []string{"api:v1", "api:v2"} {
+		rev := &Revision{ResourceGroup: "rg", Name: "api", Image: image, Definition: json.RawMessage(`{"name":"api"}`)}
+		if err := store.Save(ctx, rev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Other groups are numbered independently
+	worker := &Revision{ResourceGroup: "rg", Name: "worker", Definition: json.RawMessage(`{}`)}
+	if err := store.Save(ctx, worker); err != nil || worker.Number != 1 {
+		t.Fatalf("unexpected worker revision %d (err %v)", worker.Number, err)
+	}
+
+	revisions, err = store.List(ctx, "rg", "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 2 || revisions[0].Number != 1 || revisions[1].Image != "api:v2" {
+		t.Errorf("unexpected revisions: %+v", revisions)
+	}
+
+	rev, err := store.Get(ctx, "rg", "api", 1)
+	if err != nil || rev.Image != "api:v1" {
+		t.Fatalf("unexpected revision: %+v (err %v)", rev, err)
+	}
+	var definition struct{ Name string }
+	if err := json.Unmarshal(rev.Definition, &definition); err != nil || definition.Name != "api" {
+		t.Errorf("unexpected definition: %s", rev.Definition)
+	}
+	if _, err := store.Get(ctx, "rg", "api", 9); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
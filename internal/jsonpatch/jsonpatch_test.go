@@ -0,0 +1,93 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func assertJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var g, w any
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("result is not valid JSON: %v", err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("bad expectation: %v", err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("got %s want %s", got, want)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	doc := `{"a":"b","c":{"d":"e","f":"g"},"list":[1,2]}`
+	patch := `{"a":"z","c":{"f":null,"h":"i"},"list":[3]}`
+
+	got, err := MergePatch([]byte(doc), []byte(patch))
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	assertJSON(t, got, `{"a":"z","c":{"d":"e","h":"i"},"list":[3]}`)
+}
+
+func TestApplyPatch(t *testing.T) {
+	doc := `{"properties":{"restartPolicy":"Always","containers":[{"name":"app"}],"tags":{"a/b":"1"}}}`
+	patch := `[
+		{"op":"replace","path":"/properties/restartPolicy","value":"OnFailure"},
+		{"op":"add","path":"/properties/containers/-","value":{"name":"sidecar"}},
+		{"op":"add","path":"/properties/containers/0","value":{"name":"init"}},
+		{"op":"test","path":"/properties/containers/1/name","value":"app"},
+		{"op":"copy","from":"/properties/containers/1","path":"/properties/primary"},
+		{"op":"move","from":"/properties/tags/a~1b","path":"/properties/tags/moved"},
+		{"op":"remove","path":"/properties/containers/2"}
+	]`
+
+	got, err := ApplyPatch([]byte(doc), []byte(patch))
+	if err != nil {
+		t.Fatalf("patch failed: %v", err)
+	}
+	assertJSON(t, got, `{"properties":{"restartPolicy":"OnFailure",
+		"containers":[{"name":"init"},{"name":"app"}],"primary":{"name":"app"},"tags":{"moved":"1"}}}`)
+}
+
+func TestApplyPatchErrors(t *testing.T) {
+	doc := `{"list":[1],"name":"app"}`
+	tests := []struct {
+		name    string
+		patch   string
+		wantErr string
+	}{
+		{"missing member", `[{"op":"remove","path":"/missing"}]`, `member "missing" not found`},
+		{"index out of range", `[{"op":"replace","path":"/list/3","value":1}]`, "out of range"},
+		{"failed test", `[{"op":"test","path":"/name","value":"web"}]`, "test failed"},
+		{"unknown op", `[{"op":"merge","path":"/name"}]`, "unknown operation"},
+		{"bad pointer", `[{"op":"remove","path":"name"}]`, "invalid JSON pointer"},
+		{"move into child", `[{"op":"move","from":"/list","path":"/list/0"}]`, "into one of its children"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ApplyPatch([]byte(doc), []byte(tt.patch))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestApplyDetectsFormat(t *testing.T) {
+	doc := []byte(`{"a":1}`)
+
+	got, err := Apply(doc, []byte(` [{"op":"add","path":"/b","value":2}]`))
+	if err != nil {
+		t.Fatalf("apply JSON patch failed: %v", err)
+	}
+	assertJSON(t, got, `{"a":1,"b":2}`)
+
+	got, err = Apply(doc, []byte(`{"a":null,"c":3}`))
+	if err != nil {
+		t.Fatalf("apply merge patch failed: %v", err)
+	}
+	assertJSON(t, got, `{"c":3}`)
+}
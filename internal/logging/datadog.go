@@ -3,6 +3,7 @@ package logging
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/furiatona/azctl/internal/config"
 	"github.com/furiatona/azctl/internal/templatex"
@@ -27,11 +28,13 @@ func (p *DatadogProvider) GetInfoMessage() string {
 	return "Datadog logging is enabled. Set DATADOG_API_KEY and DATADOG_SITE in Azure App Configuration."
 }
 
-func (p *DatadogProvider) GenerateConfig(cfg *config.Config, imageName, envName string) (string, error) {
-	// This would read a different template for Datadog
-	templatePath := "deploy/configs/fluent-bit-datadog.conf"
+// TemplatePath implements TemplateProvider
+func (p *DatadogProvider) TemplatePath() string {
+	return "deploy/configs/fluent-bit-datadog.conf"
+}
 
-	return generateConfigFromTemplate(templatePath, cfg, "Datadog")
+func (p *DatadogProvider) GenerateConfig(cfg *config.Config, imageName, envName string) (string, error) {
+	return generateConfigFromTemplate(p.TemplatePath(), cfg, "Datadog")
 }
 
 // generateConfigFromTemplate is a shared function for generating config from template
@@ -41,6 +44,22 @@ func generateConfigFromTemplate(templatePath string, cfg *config.Config, provide
 		return "", fmt.Errorf("failed to read %s Fluent-bit template: %w", providerName, err)
 	}
 
+	// Report every missing key at once rather than the first one rendering trips over
+	refs, err := templatex.References(string(templateBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s Fluent-bit template: %w", providerName, err)
+	}
+	var missing []string
+	for _, ref := range templatex.Missing(refs, cfg) {
+		if ref.Required {
+			missing = append(missing, ref.Key)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%s Fluent-bit template %s requires unset configuration: %s",
+			providerName, templatePath, strings.Join(missing, ", "))
+	}
+
 	// Render the template with configuration values
 	rendered, err := templatex.RenderEnv(string(templateBytes), cfg)
 	if err != nil {
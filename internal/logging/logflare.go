@@ -22,9 +22,11 @@ func (p *LogflareProvider) GetInfoMessage() string {
 	return "Default logging is configured for Logflare. To use other logging providers, submit a PR."
 }
 
-func (p *LogflareProvider) GenerateConfig(cfg *config.Config, imageName, envName string) (string, error) {
-	// Read the Fluent-bit template
-	templatePath := "deploy/configs/fluent-bit.conf"
+// TemplatePath implements TemplateProvider
+func (p *LogflareProvider) TemplatePath() string {
+	return "deploy/configs/fluent-bit.conf"
+}
 
-	return generateConfigFromTemplate(templatePath, cfg, "Logflare")
+func (p *LogflareProvider) GenerateConfig(cfg *config.Config, imageName, envName string) (string, error) {
+	return generateConfigFromTemplate(p.TemplatePath(), cfg, "Logflare")
 }
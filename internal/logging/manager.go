@@ -19,6 +19,12 @@ type LoggingProvider interface {
 	GetInfoMessage() string
 }
 
+// TemplateProvider is implemented by providers that render a Fluent-bit template, so the
+// configuration keys it references can be checked before deploying
+type TemplateProvider interface {
+	TemplatePath() string
+}
+
 // Manager handles different logging providers
 type Manager struct {
 	providers []LoggingProvider
@@ -34,6 +40,20 @@ func NewManager() *Manager {
 	}
 }
 
+// TemplatePath returns the Fluent-bit template of the first enabled provider, or "" if none
+// is enabled or it has no template
+func (m *Manager) TemplatePath(cfg *config.Config) string {
+	for _, provider := range m.providers {
+		if provider.IsEnabled(cfg) {
+			if t, ok := provider.(TemplateProvider); ok {
+				return t.TemplatePath()
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
 // RegisterProvider adds a new logging provider
 func (m *Manager) RegisterProvider(provider LoggingProvider) {
 	m.providers = append(m.providers, provider)
@@ -112,3 +112,25 @@ func TestManager_GenerateConfig_WithStorageConfig(t *testing.T) {
 		t.Logf("Failed to clean up fluent-bit directory: %v", err)
 	}
 }
+
+func TestManager_TemplatePath(t *testing.T) {
+	cfg := config.New()
+	manager := NewManager()
+	manager.RegisterProvider(&MockProvider{})
+
+	if path := manager.TemplatePath(cfg); path != "" {
+		t.Errorf("expected no template without an enabled provider, got %q", path)
+	}
+
+	// Providers without a template contribute nothing
+	cfg.Set("MOCK_ENABLED", "true")
+	if path := manager.TemplatePath(cfg); path != "" {
+		t.Errorf("expected no template for the mock provider, got %q", path)
+	}
+
+	cfg.Set("LOGFLARE_API_KEY", "key")
+	cfg.Set("LOGFLARE_SOURCE_ID", "source")
+	if path := manager.TemplatePath(cfg); path != "deploy/configs/fluent-bit.conf" {
+		t.Errorf("expected the Logflare template, got %q", path)
+	}
+}
@@ -0,0 +1,440 @@
+// Package preflight verifies live Azure resources before a deployment mutates anything.
+// Every check runs even when an earlier one fails, so all problems are reported together.
+package preflight
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/furiatona/azctl/internal/aci"
+	"github.com/furiatona/azctl/internal/config"
+	"github.com/furiatona/azctl/internal/runx"
+	"github.com/furiatona/azctl/internal/validation"
+)
+
+// RuleName is reported as the rule for every preflight issue
+const RuleName = "Preflight"
+
+// Check is a single preflight verification
+type Check struct {
+	Name string
+	// Key is the configuration key the check relates to, if any
+	Key string
+	// Severity of a failure; defaults to validation.SeverityError
+	Severity validation.Severity
+	Run      func(ctx context.Context) error
+}
+
+// Run executes every check and returns a report with one issue per failed check
+func Run(ctx context.Context, checks []Check) *validation.Report {
+	report := &validation.Report{}
+	for _, check := range checks {
+		if err := check.Run(ctx); err != nil {
+			severity := check.Severity
+			if severity == "" {
+				severity = validation.SeverityError
+			}
+			report.Issues = append(report.Issues, validation.Issue{
+				Rule:     RuleName,
+				Key:      check.Key,
+				Severity: severity,
+				Message:  fmt.Sprintf("%s: %v", check.Name, err),
+			})
+		}
+	}
+	return report
+}
+
+// LookupHost resolves DNS names for DNSLabelCheck; tests replace it
+var LookupHost = net.DefaultResolver.LookupHost
+
+// Required RBAC actions per operation
+var (
+	ACIActions = []string{
+		"Microsoft.ContainerInstance/containerGroups/read",
+		"Microsoft.ContainerInstance/containerGroups/write",
+		"Microsoft.ContainerInstance/containerGroups/delete",
+	}
+	WebAppActions = []string{
+		"Microsoft.Web/sites/read",
+		"Microsoft.Web/sites/write",
+		"Microsoft.Web/sites/config/write",
+	}
+)
+
+// ResourceGroupCheck verifies the resource group exists and is in the expected location
+func ResourceGroupCheck(resourceGroup, location string) Check {
+	return Check{
+		Name: fmt.Sprintf("resource group %s", resourceGroup),
+		Key:  "RESOURCE_GROUP",
+		Run: func(ctx context.Context) error {
+			out, err := runx.AZOutput(ctx, "group", "show", "--name", resourceGroup, "--query", "location", "-o", "tsv")
+			if err != nil {
+				return fmt.Errorf("not found or not accessible: %w", err)
+			}
+			actual := strings.TrimSpace(out)
+			if location != "" && normalizeLocation(actual) != normalizeLocation(location) {
+				return fmt.Errorf("is in %s but LOCATION is %s", actual, location)
+			}
+			return nil
+		},
+	}
+}
+
+// AppServicePlanCheck verifies the App Service plan exists and is a Linux plan
+func AppServicePlanCheck(resourceGroup, plan string) Check {
+	return Check{
+		Name: fmt.Sprintf("App Service plan %s", plan),
+		Key:  "APP_SERVICE_PLAN",
+		Run: func(ctx context.Context) error {
+			out, err := runx.AZOutput(ctx, "appservice", "plan", "show",
+				"--name", plan, "--resource-group", resourceGroup, "-o", "json")
+			if runx.IsNotFound(err) || (err == nil && strings.TrimSpace(out) == "") {
+				return fmt.Errorf("not found in resource group %s", resourceGroup)
+			}
+			if err != nil {
+				return fmt.Errorf("could not be checked: %w", err)
+			}
+			var info struct {
+				Kind     string `json:"kind"`
+				Reserved bool   `json:"reserved"`
+			}
+			if err := json.Unmarshal([]byte(out), &info); err != nil {
+				return fmt.Errorf("failed to parse plan details: %w", err)
+			}
+			if !info.Reserved && !strings.Contains(strings.ToLower(info.Kind), "linux") {
+				return fmt.Errorf("is not a Linux plan (kind: %s)", info.Kind)
+			}
+			return nil
+		},
+	}
+}
+
+// ImageTagCheck verifies the image tag has been pushed to the registry
+func ImageTagCheck(registry, image, tag string) Check {
+	return Check{
+		Name: fmt.Sprintf("image %s.azurecr.io/%s:%s", registry, image, tag),
+		Key:  "IMAGE_TAG",
+		Run: func(ctx context.Context) error {
+			out, err := runx.AZOutput(ctx, "acr", "repository", "show-tags",
+				"--name", registry, "--repository", image, "-o", "tsv")
+			if err != nil {
+				return fmt.Errorf("repository not found or not accessible: %w", err)
+			}
+			for _, line := range strings.Split(out, "\n") {
+				if strings.TrimSpace(line) == tag {
+					return nil
+				}
+			}
+			return fmt.Errorf("tag %s does not exist (run `azctl acr` first)", tag)
+		},
+	}
+}
+
+// StorageAccountCheck verifies the storage account exists
+func StorageAccountCheck(account string) Check {
+	return Check{
+		Name: fmt.Sprintf("storage account %s", account),
+		Key:  "LOG_STORAGE_ACCOUNT",
+		Run: func(ctx context.Context) error {
+			out, err := runx.AZOutput(ctx, "storage", "account", "show", "--name", account, "--query", "name", "-o", "tsv")
+			if runx.IsNotFound(err) || (err == nil && strings.TrimSpace(out) == "") {
+				return fmt.Errorf("not found")
+			}
+			if err != nil {
+				return fmt.Errorf("could not be checked: %w", err)
+			}
+			return nil
+		},
+	}
+}
+
+// FileShareCheck verifies a file share exists in the storage account
+func FileShareCheck(key, account, accountKey, share string) Check {
+	return Check{
+		Name: fmt.Sprintf("file share %s/%s", account, share),
+		Key:  key,
+		Run: func(ctx context.Context) error {
+			out, err := runx.AZOutput(ctx, "storage", "share", "exists",
+				"--account-name", account, "--account-key", accountKey, "--name", share,
+				"--query", "exists", "-o", "tsv")
+			if err != nil {
+				return fmt.Errorf("could not be checked: %w", err)
+			}
+			if strings.TrimSpace(out) != "true" {
+				return fmt.Errorf("does not exist")
+			}
+			return nil
+		},
+	}
+}
+
+// DNSLabelCheck verifies the ACI DNS name label is free or already owned by this container group
+func DNSLabelCheck(resourceGroup, containerGroup, label, location string) Check {
+	return Check{
+		Name: fmt.Sprintf("DNS name label %s", label),
+		Key:  "DNS_NAME_LABEL",
+		Run: func(ctx context.Context) error {
+			fqdn := fmt.Sprintf("%s.%s.azurecontainer.io", label, normalizeLocation(location))
+			if _, err := LookupHost(ctx, fqdn); err != nil {
+				// Not resolvable, so nobody holds the label
+				return nil
+			}
+			out, err := runx.AZOutput(ctx, "container", "show", "--resource-group", resourceGroup,
+				"--name", containerGroup, "--query", "ipAddress.fqdn", "-o", "tsv")
+			if err == nil && strings.EqualFold(strings.TrimSpace(out), fqdn) {
+				return nil
+			}
+			return fmt.Errorf("%s is already in use by another container group", fqdn)
+		},
+	}
+}
+
+// containerGroupDelegation is the service a subnet must be delegated to for container groups
+const containerGroupDelegation = "Microsoft.ContainerInstance/containerGroups"
+
+// subnet is the part of `az network vnet subnet show` the subnet checks read
+type subnet struct {
+	Delegations []struct {
+		ServiceName string `json:"serviceName"`
+	} `json:"delegations"`
+	NetworkSecurityGroup *struct {
+		ID string `json:"id"`
+	} `json:"networkSecurityGroup"`
+}
+
+func showSubnet(ctx context.Context, subnetID string) (*subnet, error) {
+	out, err := runx.AZOutput(ctx, "network", "vnet", "subnet", "show", "--ids", subnetID, "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("does not exist or is not accessible: %w", err)
+	}
+	var s subnet
+	if err := json.Unmarshal([]byte(out), &s); err != nil {
+		return nil, fmt.Errorf("failed to parse subnet: %w", err)
+	}
+	return &s, nil
+}
+
+// SubnetCheck verifies a subnet exists and is delegated to container groups
+func SubnetCheck(subnetID string) Check {
+	return Check{
+		Name: "subnet " + lastSegment(subnetID),
+		Key:  "ACI_SUBNET_IDS",
+		Run: func(ctx context.Context) error {
+			s, err := showSubnet(ctx, subnetID)
+			if err != nil {
+				return err
+			}
+			for _, d := range s.Delegations {
+				if strings.EqualFold(d.ServiceName, containerGroupDelegation) {
+					return nil
+				}
+			}
+			return fmt.Errorf("is not delegated to %s", containerGroupDelegation)
+		},
+	}
+}
+
+// securityRule is an inbound or outbound rule of a network security group
+type securityRule struct {
+	Name                  string   `json:"name"`
+	Priority              int      `json:"priority"`
+	Direction             string   `json:"direction"`
+	Access                string   `json:"access"`
+	Protocol              string   `json:"protocol"`
+	SourceAddressPrefix   string   `json:"sourceAddressPrefix"`
+	SourceAddressPrefixes []string `json:"sourceAddressPrefixes"`
+	DestinationPortRange  string   `json:"destinationPortRange"`
+	DestinationPortRanges []string `json:"destinationPortRanges"`
+}
+
+// NSGCheck verifies the network security group of a subnet, if any, does not deny inbound
+// TCP traffic to port from within the virtual network. Only rules applying to any source or
+// to VirtualNetwork are considered; the default rules allow traffic inside the network.
+func NSGCheck(subnetID string, port int) Check {
+	return Check{
+		Name: fmt.Sprintf("network security group of subnet %s", lastSegment(subnetID)),
+		Key:  "ACI_SUBNET_IDS",
+		Run: func(ctx context.Context) error {
+			s, err := showSubnet(ctx, subnetID)
+			if err != nil || s.NetworkSecurityGroup == nil {
+				// SubnetCheck reports a missing subnet
+				return nil
+			}
+			nsgID := s.NetworkSecurityGroup.ID
+			out, err := runx.AZOutput(ctx, "network", "nsg", "show", "--ids", nsgID,
+				"--query", "securityRules", "-o", "json")
+			if err != nil {
+				return fmt.Errorf("could not read %s: %w", lastSegment(nsgID), err)
+			}
+			var rules []securityRule
+			if err := json.Unmarshal([]byte(out), &rules); err != nil {
+				return fmt.Errorf("failed to parse security rules: %w", err)
+			}
+			sort.Slice(rules, func(i, j int) bool { return rules[i].Priority < rules[j].Priority })
+			for _, rule := range rules {
+				if !rule.appliesTo(port) {
+					continue
+				}
+				if strings.EqualFold(rule.Access, "Deny") {
+					return fmt.Errorf("rule %s of %s denies inbound port %d", rule.Name, lastSegment(nsgID), port)
+				}
+				return nil
+			}
+			return nil
+		},
+	}
+}
+
+// appliesTo reports whether the rule matches inbound TCP traffic to port from the network
+func (r securityRule) appliesTo(port int) bool {
+	if !strings.EqualFold(r.Direction, "Inbound") || (r.Protocol != "*" && !strings.EqualFold(r.Protocol, "Tcp")) {
+		return false
+	}
+	sources := append([]string{r.SourceAddressPrefix}, r.SourceAddressPrefixes...)
+	if !slices.ContainsFunc(sources, func(s string) bool { return s == "*" || strings.EqualFold(s, "VirtualNetwork") }) {
+		return false
+	}
+	ranges := append([]string{r.DestinationPortRange}, r.DestinationPortRanges...)
+	return slices.ContainsFunc(ranges, func(spec string) bool { return portInRange(spec, port) })
+}
+
+// portInRange matches a port against "*", "8080" or "8000-8100"
+func portInRange(spec string, port int) bool {
+	if spec == "*" {
+		return true
+	}
+	low, high, found := strings.Cut(spec, "-")
+	if !found {
+		high = low
+	}
+	from, err1 := strconv.Atoi(strings.TrimSpace(low))
+	to, err2 := strconv.Atoi(strings.TrimSpace(high))
+	return err1 == nil && err2 == nil && port >= from && port <= to
+}
+
+// lastSegment returns the resource name at the end of an Azure resource ID
+func lastSegment(id string) string {
+	return id[strings.LastIndex(id, "/")+1:]
+}
+
+// PermissionsCheck verifies the caller holds the given RBAC actions on the resource group
+func PermissionsCheck(resourceGroup string, actions []string) Check {
+	return Check{
+		Name: fmt.Sprintf("permissions on resource group %s", resourceGroup),
+		Key:  "RESOURCE_GROUP",
+		Run: func(ctx context.Context) error {
+			url := fmt.Sprintf("/subscriptions/{subscriptionId}/resourceGroups/%s/providers/"+
+				"Microsoft.Authorization/permissions?api-version=2022-04-01", resourceGroup)
+			out, err := runx.AZOutput(ctx, "rest", "--method", "get", "--url", url)
+			if err != nil {
+				return fmt.Errorf("could not list permissions: %w", err)
+			}
+			var perms struct {
+				Value []Permission `json:"value"`
+			}
+			if err := json.Unmarshal([]byte(out), &perms); err != nil {
+				return fmt.Errorf("failed to parse permissions: %w", err)
+			}
+
+			var missing []string
+			for _, action := range actions {
+				if !Allowed(perms.Value, action) {
+					missing = append(missing, action)
+				}
+			}
+			if len(missing) > 0 {
+				return fmt.Errorf("caller is missing %s", strings.Join(missing, ", "))
+			}
+			return nil
+		},
+	}
+}
+
+// Permission is one entry returned by the Microsoft.Authorization/permissions API
+type Permission struct {
+	Actions    []string `json:"actions"`
+	NotActions []string `json:"notActions"`
+}
+
+// Allowed reports whether any permission grants the action without excluding it
+func Allowed(perms []Permission, action string) bool {
+	for _, perm := range perms {
+		if matchesAny(perm.Actions, action) && !matchesAny(perm.NotActions, action) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(patterns []string, action string) bool {
+	for _, pattern := range patterns {
+		expr := "(?i)^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+		if matched, err := regexp.MatchString(expr, action); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ACIChecks builds the preflight checks for an ACI deployment from resolved configuration
+func ACIChecks(cfg *config.Config, resourceGroup string) []Check {
+	location := cfg.Get("LOCATION")
+	checks := []Check{
+		ResourceGroupCheck(resourceGroup, location),
+		ImageTagCheck(cfg.Get("ACR_REGISTRY"), cfg.Get("IMAGE_NAME"), cfg.Get("IMAGE_TAG")),
+	}
+
+	if network := aci.NetworkFromConfig(cfg); network != nil {
+		port, _ := strconv.Atoi(cfg.Get("ACI_PORT"))
+		for _, id := range network.SubnetIDs {
+			checks = append(checks, SubnetCheck(id))
+			if port > 0 {
+				checks = append(checks, NSGCheck(id, port))
+			}
+		}
+	} else if label := cfg.Get("DNS_NAME_LABEL"); label != "" {
+		checks = append(checks, DNSLabelCheck(resourceGroup, cfg.Get("CONTAINER_GROUP_NAME"), label, location))
+	}
+
+	account := cfg.Get("LOG_STORAGE_ACCOUNT")
+	accountKey := cfg.Get("LOG_STORAGE_KEY")
+	if account != "" {
+		checks = append(checks, StorageAccountCheck(account))
+		if share := cfg.Get("LOG_STORAGE_NAME"); share != "" {
+			checks = append(checks, FileShareCheck("LOG_STORAGE_NAME", account, accountKey, share))
+		}
+		if share := cfg.Get("FLUENTBIT_CONFIG"); share != "" {
+			// The Fluent-bit config share is created on deploy if missing
+			check := FileShareCheck("FLUENTBIT_CONFIG", account, accountKey, share)
+			check.Severity = validation.SeverityWarning
+			checks = append(checks, check)
+		}
+	}
+
+	return append(checks, PermissionsCheck(resourceGroup, ACIActions))
+}
+
+// WebAppChecks builds the preflight checks for a WebApp deployment from resolved configuration
+func WebAppChecks(cfg *config.Config, resourceGroup, appServicePlan string) []Check {
+	checks := []Check{
+		ResourceGroupCheck(resourceGroup, cfg.Get("LOCATION")),
+		ImageTagCheck(cfg.Get("ACR_REGISTRY"), cfg.Get("IMAGE_NAME"), cfg.Get("IMAGE_TAG")),
+	}
+	if appServicePlan != "" {
+		checks = append(checks, AppServicePlanCheck(resourceGroup, appServicePlan))
+	}
+	return append(checks, PermissionsCheck(resourceGroup, WebAppActions))
+}
+
+// normalizeLocation turns "East US" into "eastus"
+func normalizeLocation(location string) string {
+	return strings.ToLower(strings.ReplaceAll(location, " ", ""))
+}
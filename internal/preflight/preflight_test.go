@@ -0,0 +1,163 @@
+package preflight
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/furiatona/azctl/internal/config"
+	"github.com/furiatona/azctl/internal/runx"
+	"github.com/furiatona/azctl/internal/validation"
+)
+
+func TestACIChecksReportAllFailures(t *testing.T) {
+	fake := runx.NewFake().
+		On("group show", "westeurope\n", nil).
+		On("acr repository show-tags", "v1\nv2\n", nil).
+		On("storage account show", "", errors.New("(ResourceNotFound) account logs was not found")).
+		On("storage share exists", "false\n", nil).
+		On("container show", "other.eastus.azurecontainer.io\n", nil).
+		On("rest --method get", `{"value":[{"actions":["Microsoft.ContainerInstance/*"],`+
+			`"notActions":["Microsoft.ContainerInstance/containerGroups/delete"]}]}`, nil)
+	defer runx.SetExecutor(fake)()
+
+	defer func(lookup func(context.Context, string) ([]string, error)) { LookupHost = lookup }(LookupHost)
+	LookupHost = func(context.Context, string) ([]string, error) { return []string{"10.0.0.1"}, nil }
+
+	cfg := config.New()
+	cfg.Set("LOCATION", "eastus")
+	cfg.Set("ACR_REGISTRY", "reg")
+	cfg.Set("IMAGE_NAME", "app")
+	cfg.Set("IMAGE_TAG", "v3")
+	cfg.Set("DNS_NAME_LABEL", "app-dev")
+	cfg.Set("CONTAINER_GROUP_NAME", "app")
+	cfg.Set("LOG_STORAGE_ACCOUNT", "logs")
+	cfg.Set("LOG_STORAGE_KEY", "key")
+	cfg.Set("LOG_STORAGE_NAME", "applogs")
+	cfg.Set("FLUENTBIT_CONFIG", "fbconf")
+
+	report := Run(context.Background(), ACIChecks(cfg, "rg"))
+
+	wants := map[string]validation.Severity{
+		"is in westeurope but LOCATION is eastus":                    validation.SeverityError,
+		"tag v3 does not exist":                                      validation.SeverityError,
+		"storage account logs: not found":                            validation.SeverityError,
+		"file share logs/applogs: does not exist":                    validation.SeverityError,
+		"file share logs/fbconf: does not exist":                     validation.SeverityWarning,
+		"app-dev.eastus.azurecontainer.io is already in use":         validation.SeverityError,
+		"missing Microsoft.ContainerInstance/containerGroups/delete": validation.SeverityError,
+	}
+	for want, severity := range wants {
+		found := false
+		for _, issue := range report.Issues {
+			if strings.Contains(issue.Message, want) && issue.Severity == severity {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected %s issue containing %q in %v", severity, want, report.Issues)
+		}
+	}
+	if len(report.Issues) != len(wants) {
+		t.Errorf("expected %d issues, got %d: %v", len(wants), len(report.Issues), report.Issues)
+	}
+}
+
+func TestWebAppChecksPass(t *testing.T) {
+	fake := runx.NewFake().
+		On("group show", "eastus\n", nil).
+		On("acr repository show-tags", "v1\n", nil).
+		On("appservice plan show", `{"kind":"linux","reserved":true}`, nil).
+		On("rest --method get", `{"value":[{"actions":["*"],"notActions":[]}]}`, nil)
+	defer runx.SetExecutor(fake)()
+
+	cfg := config.New()
+	cfg.Set("ACR_REGISTRY", "reg")
+	cfg.Set("IMAGE_NAME", "app")
+	cfg.Set("IMAGE_TAG", "v1")
+
+	report := Run(context.Background(), WebAppChecks(cfg, "rg", "plan"))
+	if len(report.Issues) != 0 {
+		t.Errorf("expected no issues, got %v", report.Issues)
+	}
+	if !fake.Called("appservice plan show --name plan") {
+		t.Errorf("expected plan to be checked, calls: %v", fake.Calls())
+	}
+}
+
+func TestAppServicePlanCheckRejectsWindows(t *testing.T) {
+	fake := runx.NewFake().On("appservice plan show", `{"kind":"app","reserved":false}`, nil)
+	defer runx.SetExecutor(fake)()
+
+	err := AppServicePlanCheck("rg", "plan").Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "not a Linux plan") {
+		t.Errorf("expected Linux plan error, got %v", err)
+	}
+}
+
+func TestLookupFailuresAreNotReportedAsMissing(t *testing.T) {
+	fake := runx.NewFake().
+		On("appservice plan show", "", errors.New("AuthorizationFailed")).
+		On("storage account show", "", errors.New("AuthorizationFailed"))
+	defer runx.SetExecutor(fake)()
+
+	checks := []Check{AppServicePlanCheck("rg", "plan"), StorageAccountCheck("logs")}
+	for _, check := range checks {
+		err := check.Run(context.Background())
+		if err == nil || strings.Contains(err.Error(), "not found") || !strings.Contains(err.Error(), "AuthorizationFailed") {
+			t.Errorf("%s: expected the az error, got %v", check.Name, err)
+		}
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	perms := []Permission{{Actions: []string{"Microsoft.Web/*/read"}, NotActions: nil}}
+	if !Allowed(perms, "microsoft.web/sites/read") {
+		t.Error("expected wildcard read to be allowed")
+	}
+	if Allowed(perms, "Microsoft.Web/sites/write") {
+		t.Error("expected write to be denied")
+	}
+}
+
+func TestPrivateNetworkChecks(t *testing.T) {
+	const vnet = "/subscriptions/s/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/"
+	fake := runx.NewFake().
+		On("network vnet subnet show --ids "+vnet+"aci", `{"delegations":[`+
+			`{"serviceName":"Microsoft.ContainerInstance/containerGroups"}],"networkSecurityGroup":{"id":"/x/nsg-aci"}}`, nil).
+		On("network vnet subnet show --ids "+vnet+"shared", `{"delegations":[]}`, nil).
+		On("network nsg show", `[
+			{"name":"allow-web","priority":300,"direction":"Inbound","access":"Allow","protocol":"Tcp",`+
+			`"sourceAddressPrefix":"*","destinationPortRange":"80"},
+			{"name":"deny-internet","priority":100,"direction":"Inbound","access":"Deny","protocol":"*",`+
+			`"sourceAddressPrefix":"Internet","destinationPortRange":"*"},
+			{"name":"deny-apps","priority":200,"direction":"Inbound","access":"Deny","protocol":"*",`+
+			`"sourceAddressPrefix":"VirtualNetwork","destinationPortRanges":["8000-8100"]}]`, nil)
+	defer runx.SetExecutor(fake)()
+
+	cfg := config.New()
+	cfg.Set("ACI_NETWORK_MODE", "private")
+	cfg.Set("ACI_SUBNET_IDS", vnet+"aci,"+vnet+"shared")
+	cfg.Set("ACI_PORT", "8080")
+
+	var checks []Check
+	for _, check := range ACIChecks(cfg, "rg") {
+		if check.Key == "ACI_SUBNET_IDS" {
+			checks = append(checks, check)
+		}
+	}
+	report := Run(context.Background(), checks)
+
+	var messages []string
+	for _, issue := range report.Issues {
+		messages = append(messages, issue.Message)
+	}
+	want := []string{
+		"network security group of subnet aci: rule deny-apps of nsg-aci denies inbound port 8080",
+		"subnet shared: is not delegated to Microsoft.ContainerInstance/containerGroups",
+	}
+	if strings.Join(messages, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected issues:\n%s\nwant:\n%s", strings.Join(messages, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -1,13 +1,29 @@
 package runx
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"strings"
 )
 
-func AZ(ctx context.Context, args ...string) error {
+// Executor runs az CLI commands. The default executor shells out to the az binary;
+// tests replace it with SetExecutor.
+type Executor interface {
+	// Run executes a command, streaming its output to the console
+	Run(ctx context.Context, args ...string) error
+	// Output executes a command and returns its stdout
+	Output(ctx context.Context, args ...string) (string, error)
+}
+
+// CLI executes commands with the installed az binary
+type CLI struct{}
+
+// Run executes az with stdio attached to the current process
+func (CLI) Run(ctx context.Context, args ...string) error {
 	cmd := exec.CommandContext(ctx, "az", args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -18,12 +34,59 @@ func AZ(ctx context.Context, args ...string) error {
 	return nil
 }
 
-// AZOutput runs az command and returns the output as a string
-func AZOutput(ctx context.Context, args ...string) (string, error) {
+// Output executes az and returns its stdout
+func (CLI) Output(ctx context.Context, args ...string) (string, error) {
 	cmd := exec.CommandContext(ctx, "az", args...)
 	output, err := cmd.Output()
 	if err != nil {
+		// az explains the failure on stderr, e.g. "(ResourceNotFound) The Resource ... was not found"
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(bytes.TrimSpace(exitErr.Stderr)) > 0 {
+			return "", fmt.Errorf("az command failed: %w: %s", err, bytes.TrimSpace(exitErr.Stderr))
+		}
 		return "", fmt.Errorf("az command failed: %w", err)
 	}
 	return string(output), nil
 }
+
+// IsNotFound reports whether an az command failed because the resource or its resource
+// group does not exist, as opposed to failing for any other reason (login, network, throttling)
+func IsNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+	message := err.Error()
+	return strings.Contains(message, "ResourceNotFound") || strings.Contains(message, "ResourceGroupNotFound")
+}
+
+var current Executor = CLI{}
+
+// SetExecutor replaces the executor used by AZ and AZOutput and returns a function
+// that restores the previous one
+func SetExecutor(e Executor) (restore func()) {
+	previous := current
+	current = e
+	return func() { current = previous }
+}
+
+func AZ(ctx context.Context, args ...string) error {
+	return current.Run(ctx, args...) //nolint:wrapcheck // executors already wrap their errors
+}
+
+// AZOutput runs az command and returns the output as a string
+func AZOutput(ctx context.Context, args ...string) (string, error) {
+	return current.Output(ctx, args...) //nolint:wrapcheck // executors already wrap their errors
+}
+
+// Docker runs a docker command with stdio attached and extra environment variables
+// (KEY=VALUE) appended to the current environment, e.g. for BuildKit secrets
+func Docker(ctx context.Context, env []string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("docker command failed: %w", err)
+	}
+	return nil
+}
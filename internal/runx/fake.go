@@ -0,0 +1,82 @@
+package runx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Fake is an Executor for tests. It records every call and answers with canned
+// responses registered by command prefix; the longest matching prefix wins.
+type Fake struct {
+	mu        sync.Mutex
+	calls     [][]string
+	responses map[string]fakeResponse
+}
+
+type fakeResponse struct {
+	output string
+	err    error
+}
+
+// NewFake creates an empty fake executor
+func NewFake() *Fake {
+	return &Fake{responses: make(map[string]fakeResponse)}
+}
+
+// On registers the output and error returned for commands starting with prefix,
+// e.g. On("container show", `{"name":"app"}`, nil)
+func (f *Fake) On(prefix, output string, err error) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses[prefix] = fakeResponse{output: output, err: err}
+	return f
+}
+
+// Calls returns every command executed so far, space-joined
+func (f *Fake) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	calls := make([]string, 0, len(f.calls))
+	for _, args := range f.calls {
+		calls = append(calls, strings.Join(args, " "))
+	}
+	return calls
+}
+
+// Called reports whether any executed command starts with prefix
+func (f *Fake) Called(prefix string) bool {
+	for _, call := range f.Calls() {
+		if strings.HasPrefix(call, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Run records the call and returns the registered error
+func (f *Fake) Run(ctx context.Context, args ...string) error {
+	_, err := f.Output(ctx, args...)
+	return err
+}
+
+// Output records the call and returns the registered response
+func (f *Fake) Output(_ context.Context, args ...string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, args)
+
+	command := strings.Join(args, " ")
+	best, found := "", false
+	for prefix := range f.responses {
+		if strings.HasPrefix(command, prefix) && (!found || len(prefix) > len(best)) {
+			best, found = prefix, true
+		}
+	}
+	if !found {
+		return "", fmt.Errorf("az command failed: no fake response for %q", command)
+	}
+	response := f.responses[best]
+	return response.output, response.err
+}
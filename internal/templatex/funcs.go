@@ -0,0 +1,188 @@
+package templatex
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/furiatona/azctl/internal/config"
+	"github.com/furiatona/azctl/internal/envvars"
+)
+
+// now returns the current time; tests replace it
+var now = time.Now
+
+// FuncMap returns the functions available to templates rendered against cfg:
+//
+//	env KEY                  value of KEY; fails if empty
+//	envOr KEY DEFAULT        value of KEY, or DEFAULT if empty
+//	hasKey KEY               true if KEY has a non-empty value
+//	envPrefix PREFIX         map of every KEY starting with PREFIX to its value
+//	required MESSAGE VALUE   VALUE; fails with MESSAGE if VALUE is empty
+//	default DEFAULT VALUE    VALUE, or DEFAULT if VALUE is empty
+//	b64enc VALUE / b64dec VALUE
+//	toJson VALUE             VALUE encoded as JSON
+//	quote VALUE              VALUE as a JSON string, also valid as a double-quoted YAML string
+//	lower VALUE / upper VALUE
+//	trimPrefix PREFIX VALUE
+//	split SEP VALUE          list of VALUE's parts separated by SEP
+//	join SEP LIST
+//	sha256 VALUE             hex-encoded SHA-256 of VALUE
+//	now                      current UTC time in RFC 3339 format
+//	environmentVariables [OPTION...]
+//	                         JSON array of ACI environment variables built from the
+//	                         configuration; see EnvironmentVariables
+//
+// Functions taking VALUE last can be used in pipelines, e.g. {{ envOr "TIER" "" | default "basic" | upper }}.
+func FuncMap(cfg *config.Config) template.FuncMap {
+	return template.FuncMap{
+		"env": func(k string) (string, error) {
+			v := cfg.Get(k)
+			if v == "" {
+				return "", fmt.Errorf("missing env: %s", k)
+			}
+			return v, nil
+		},
+		"envOr": func(k, fallback string) string {
+			if v := cfg.Get(k); v != "" {
+				return v
+			}
+			return fallback
+		},
+		"hasKey": cfg.Has,
+		"envPrefix": func(prefix string) map[string]string {
+			result := make(map[string]string)
+			for k, v := range cfg.GetAll() {
+				if strings.HasPrefix(k, strings.ToUpper(prefix)) {
+					result[k] = v
+				}
+			}
+			return result
+		},
+		"required": func(message string, v any) (any, error) {
+			if isEmpty(v) {
+				return nil, fmt.Errorf("%s", message)
+			}
+			return v, nil
+		},
+		"default": func(fallback, v any) any {
+			if isEmpty(v) {
+				return fallback
+			}
+			return v
+		},
+		"b64enc": func(v string) string {
+			return base64.StdEncoding.EncodeToString([]byte(v))
+		},
+		"b64dec": func(v string) (string, error) {
+			decoded, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return "", fmt.Errorf("b64dec: %w", err)
+			}
+			return string(decoded), nil
+		},
+		"toJson": func(v any) (string, error) {
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				return "", fmt.Errorf("toJson: %w", err)
+			}
+			return string(encoded), nil
+		},
+		"quote": func(v any) string {
+			return `"` + escapeJSONString(fmt.Sprint(v)) + `"`
+		},
+		"lower":      strings.ToLower,
+		"upper":      strings.ToUpper,
+		"trimPrefix": func(prefix, v string) string { return strings.TrimPrefix(v, prefix) },
+		"split": func(sep, v string) []string {
+			if v == "" {
+				return []string{}
+			}
+			return strings.Split(v, sep)
+		},
+		"join": func(sep string, list []string) string { return strings.Join(list, sep) },
+		"sha256": func(v string) string {
+			sum := sha256.Sum256([]byte(v))
+			return hex.EncodeToString(sum[:])
+		},
+		"now": func() string { return now().UTC().Format(time.RFC3339) },
+		"environmentVariables": func(options ...string) (string, error) {
+			return EnvironmentVariables(cfg, options...)
+		},
+	}
+}
+
+// EnvironmentVariables returns a JSON array for an ACI container's environmentVariables,
+// e.g. "environmentVariables": {{ environmentVariables "include=NEXT_PUBLIC_,SUPABASE_" }}.
+// Options are include=PREFIX,..., exclude=PREFIX,... and keys=KEY,...; without options
+// ACI_ENV_INCLUDE, ACI_ENV_EXCLUDE and ACI_ENV_KEYS are used, falling back to the
+// well-known application keys. Internal azctl keys are always skipped and secrets are
+// emitted as secureValue (see envvars.ClassifierFromConfig).
+func EnvironmentVariables(cfg *config.Config, options ...string) (string, error) {
+	selection := envvars.SelectionFromConfig(cfg)
+	if len(options) > 0 {
+		selection = envvars.Selection{Classifier: selection.Classifier}
+		for _, option := range options {
+			name, value, ok := strings.Cut(option, "=")
+			if !ok {
+				return "", fmt.Errorf("environmentVariables: invalid option %q (expected include=, exclude= or keys=)", option)
+			}
+			list := config.SplitList(value)
+			switch strings.TrimSpace(name) {
+			case "include":
+				selection.Include = append(selection.Include, list...)
+			case "exclude":
+				selection.Exclude = append(selection.Exclude, list...)
+			case "keys":
+				selection.Keys = append(selection.Keys, list...)
+			default:
+				return "", fmt.Errorf("environmentVariables: unknown option %q", name)
+			}
+		}
+	}
+
+	type envVar struct {
+		Name        string `json:"name"`
+		Value       string `json:"value,omitempty"`
+		SecureValue string `json:"secureValue,omitempty"`
+	}
+	vars := []envVar{}
+	for _, v := range envvars.Select(cfg, selection) {
+		if v.Secret {
+			vars = append(vars, envVar{Name: v.Name, SecureValue: v.Value})
+		} else {
+			vars = append(vars, envVar{Name: v.Name, Value: v.Value})
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(vars); err != nil {
+		return "", fmt.Errorf("environmentVariables: %w", err)
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// isEmpty reports whether a template value counts as unset for required/default
+func isEmpty(v any) bool {
+	switch value := v.(type) {
+	case nil:
+		return true
+	case string:
+		return value == ""
+	case bool:
+		return !value
+	case []string:
+		return len(value) == 0
+	case map[string]string:
+		return len(value) == 0
+	}
+	return false
+}
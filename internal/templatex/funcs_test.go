@@ -0,0 +1,130 @@
+package templatex
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/furiatona/azctl/internal/config"
+)
+
+func newTestConfig() *config.Config {
+	cfg := config.New()
+	cfg.Set("IMAGE_NAME", "api")
+	cfg.Set("TIER", "premium")
+	cfg.Set("NEXT_PUBLIC_URL", "https://example.com")
+	cfg.Set("NEXT_PUBLIC_ENV", "dev")
+	cfg.Set("HOSTS", "a.example.com,b.example.com")
+	return cfg
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	restore := now
+	now = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*3600)) }
+	defer func() { now = restore }()
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"env", `{{ env "IMAGE_NAME" }}`, "api"},
+		{"envOr set", `{{ envOr "TIER" "basic" }}`, "premium"},
+		{"envOr unset", `{{ envOr "MISSING" "basic" }}`, "basic"},
+		{"hasKey", `{{ hasKey "TIER" }} {{ hasKey "MISSING" }}`, "true false"},
+		{"envPrefix", `{{ range $k, $v := envPrefix "NEXT_PUBLIC_" }}{{ $k }}={{ $v }};{{ end }}`,
+			"NEXT_PUBLIC_ENV=dev;NEXT_PUBLIC_URL=https://example.com;"},
+		{"required", `{{ required "TIER is needed" (env "TIER") }}`, "premium"},
+		{"default unset", `{{ envOr "MISSING" "" | default "basic" }}`, "basic"},
+		{"default set", `{{ env "TIER" | default "basic" }}`, "premium"},
+		{"b64enc", `{{ env "IMAGE_NAME" | b64enc }}`, "YXBp"},
+		{"b64dec", `{{ "YXBp" | b64dec }}`, "api"},
+		{"toJson string", `{{ "say \"hi\"" | toJson }}`, `"say \"hi\""`},
+		{"toJson list", `{{ env "HOSTS" | split "," | toJson }}`, `["a.example.com","b.example.com"]`},
+		{"quote", `{{ env "TIER" | quote }}`, `"premium"`},
+		{"quote control characters", `{{ "a\x00b\a\u2028<" | quote }}`, `"a\u0000b\u0007\u2028<"`},
+		{"lower", `{{ "API" | lower }}`, "api"},
+		{"upper", `{{ env "TIER" | upper }}`, "PREMIUM"},
+		{"trimPrefix", `{{ env "NEXT_PUBLIC_URL" | trimPrefix "https://" }}`, "example.com"},
+		{"split and join", `{{ env "HOSTS" | split "," | join " " }}`, "a.example.com b.example.com"},
+		{"sha256", `{{ env "IMAGE_NAME" | sha256 }}`,
+			"14c2529eb4498c5d1ffd6915d05bf58a91bdda796af59f41d480d11c099d0479"},
+		{"now", `{{ now }}`, "2024-05-01T10:00:00Z"},
+	}
+
+	cfg := newTestConfig()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderEnv(tt.input, cfg)
+			if err != nil {
+				t.Fatalf("render failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateFuncErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{"env missing", `{{ env "MISSING" }}`, "missing env: MISSING"},
+		{"required empty", `{{ required "SUPABASE_URL must be set" (envOr "SUPABASE_URL" "") }}`,
+			"SUPABASE_URL must be set"},
+		{"b64dec invalid", `{{ "not base64!" | b64dec }}`, "b64dec"},
+	}
+
+	cfg := newTestConfig()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := RenderEnv(tt.input, cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestEnvironmentVariables(t *testing.T) {
+	cfg := config.New()
+	cfg.Set("SUPABASE_URL", "https://db.example.com")
+	cfg.Set("SUPABASE_KEY", "anon")
+	cfg.Set("APP_MODE", "worker")
+	cfg.Set("IMAGE_NAME", "api")
+
+	out, err := RenderJSON(`{"env": {{ environmentVariables "include=SUPABASE_" "keys=APP_MODE,IMAGE_NAME" }}}`, cfg)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	want := `{"env": [{"name":"APP_MODE","value":"worker"},{"name":"SUPABASE_KEY","secureValue":"anon"},` +
+		`{"name":"SUPABASE_URL","value":"https://db.example.com"}]}`
+	if out != want {
+		t.Errorf("got %s\nwant %s", out, want)
+	}
+
+	// Without options the selection comes from configuration
+	cfg.Set("ACI_ENV_KEYS", "APP_MODE")
+	out, err = EnvironmentVariables(cfg)
+	if err != nil || out != `[{"name":"APP_MODE","value":"worker"}]` {
+		t.Errorf("got %s (err %v)", out, err)
+	}
+
+	// A trailing comma adds no empty prefix and spaces around prefixes are ignored
+	cfg.Set("TRAFFIC_DNS_ZONE", "example.com")
+	out, err = EnvironmentVariables(cfg, "include=SUPABASE_,")
+	if err != nil || strings.Contains(out, "APP_MODE") || !strings.Contains(out, "SUPABASE_URL") {
+		t.Errorf("trailing comma: got %s (err %v)", out, err)
+	}
+	out, err = EnvironmentVariables(cfg, "include=SUPABASE_, TRAFFIC_")
+	if err != nil || !strings.Contains(out, "TRAFFIC_DNS_ZONE") || !strings.Contains(out, "SUPABASE_URL") {
+		t.Errorf("spaces: got %s (err %v)", out, err)
+	}
+
+	if _, err := EnvironmentVariables(cfg, "prefix=SUPABASE_"); err == nil {
+		t.Error("expected error for unknown option")
+	}
+}
@@ -0,0 +1,261 @@
+package templatex
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/furiatona/azctl/internal/config"
+)
+
+// captureFunc is appended to every output action when rendering JSON
+const captureFunc = "azctlCaptureJSONValue"
+
+// sentinel marks where a captured value is placed in the raw output
+const sentinel = "\x00azctl:"
+
+// keyFuncs are the template functions whose first argument is a configuration key
+var keyFuncs = map[string]bool{"env": true, "envOr": true, "hasKey": true}
+
+// jsonFuncs are the template functions that produce JSON, so their output may be placed
+// outside a JSON string as is. Any other value placed there must be a number.
+var jsonFuncs = map[string]bool{"toJson": true, "quote": true, "environmentVariables": true, "hasKey": true}
+
+// jsonNumber matches a JSON number
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// capture is a value produced by a template action
+type capture struct {
+	value string
+	line  int
+	key   string
+	// json is set when the action ends in one of jsonFuncs
+	json bool
+}
+
+// placement records where a substituted value ended up in the final output
+type placement struct {
+	offset int
+	capture
+}
+
+// RenderJSON renders a JSON template like RenderEnv, but escapes each value for the place it
+// is substituted: values inside JSON strings are escaped (quotes, backslashes, newlines) and
+// values outside strings must be numbers, e.g. "port": {{ env "ACI_PORT" }}, unless they are
+// the output of toJson, quote, environmentVariables or hasKey. Errors name the template line and
+// the configuration key.
+func RenderJSON(input string, cfg *config.Config) (string, error) {
+	raw, captures, err := execute(input, cfg)
+	if err != nil {
+		return "", err
+	}
+
+	out, placements, err := substitute(raw, captures)
+	if err != nil {
+		return "", err
+	}
+
+	if err := checkJSON(out, placements); err != nil {
+		return "", err
+	}
+	return out, nil
+}
+
+// execute renders a template with every output action replaced by a sentinel, and returns
+// the output and the captured values the sentinels refer to
+func execute(input string, cfg *config.Config) (string, []capture, error) {
+	var captures []capture
+	funcs := FuncMap(cfg)
+	funcs[captureFunc] = func(index string, v any) string {
+		meta := strings.SplitN(index, ":", 3)
+		n, _ := strconv.Atoi(meta[0])
+		captures = append(captures, capture{value: fmt.Sprint(v), line: n, json: meta[1] == "json", key: meta[2]})
+		return fmt.Sprintf("%s%d\x00", sentinel, len(captures)-1)
+	}
+
+	t, err := template.New("aci").Option("missingkey=error").Funcs(funcs).Parse(input)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree != nil {
+			instrument(tmpl.Tree.Root)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, map[string]string{}); err != nil {
+		return "", nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+	return buf.String(), captures, nil
+}
+
+// instrument pipes the result of every output action through captureFunc so the value can
+// be escaped once its JSON context is known
+func instrument(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			instrument(child)
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) > 0 {
+			return
+		}
+		kind := ""
+		if last := n.Pipe.Cmds[len(n.Pipe.Cmds)-1]; len(last.Args) > 0 {
+			if ident, ok := last.Args[0].(*parse.IdentifierNode); ok && jsonFuncs[ident.Ident] {
+				kind = "json"
+			}
+		}
+		meta := fmt.Sprintf("%d:%s:%s", n.Line, kind, pipeKey(n.Pipe))
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pos,
+			Args: []parse.Node{
+				parse.NewIdentifier(captureFunc).SetPos(n.Pos),
+				&parse.StringNode{NodeType: parse.NodeString, Pos: n.Pos, Quoted: strconv.Quote(meta), Text: meta},
+			},
+		})
+	case *parse.IfNode:
+		instrument(n.List)
+		instrument(n.ElseList)
+	case *parse.RangeNode:
+		instrument(n.List)
+		instrument(n.ElseList)
+	case *parse.WithNode:
+		instrument(n.List)
+		instrument(n.ElseList)
+	}
+}
+
+// pipeKey returns the first configuration key referenced by a pipeline, if any
+func pipeKey(pipe *parse.PipeNode) string {
+	if pipe == nil {
+		return ""
+	}
+	for _, cmd := range pipe.Cmds {
+		for i, arg := range cmd.Args {
+			switch a := arg.(type) {
+			case *parse.IdentifierNode:
+				if keyFuncs[a.Ident] && i+1 < len(cmd.Args) {
+					if s, ok := cmd.Args[i+1].(*parse.StringNode); ok {
+						return s.Text
+					}
+				}
+			case *parse.PipeNode:
+				if key := pipeKey(a); key != "" {
+					return key
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// substitute replaces sentinels with their values, escaped for the JSON context they appear in
+func substitute(raw string, captures []capture) (string, []placement, error) {
+	var (
+		out        strings.Builder
+		placements []placement
+		state      jsonState
+	)
+	for i := 0; i < len(raw); {
+		if !strings.HasPrefix(raw[i:], sentinel) {
+			state.feed(raw[i])
+			out.WriteByte(raw[i])
+			i++
+			continue
+		}
+
+		start := i + len(sentinel)
+		end := strings.IndexByte(raw[start:], 0)
+		index, _ := strconv.Atoi(raw[start : start+end])
+		c := captures[index]
+		i = start + end + 1
+
+		value := c.value
+		if state.inString {
+			value = escapeJSONString(value)
+		} else if c.json && !json.Valid([]byte(value)) {
+			return "", nil, fmt.Errorf("template line %d: %s: function output %q is not valid JSON",
+				c.line, describeKey(c.key), c.value)
+		} else if !c.json && !jsonNumber.MatchString(value) {
+			return "", nil, fmt.Errorf("template line %d: %s: value %q must be a number when used outside "+
+				"a JSON string (use toJson for other values)", c.line, describeKey(c.key), c.value)
+		}
+		placements = append(placements, placement{offset: out.Len(), capture: c})
+		for j := 0; j < len(value); j++ {
+			state.feed(value[j])
+		}
+		out.WriteString(value)
+	}
+	return out.String(), placements, nil
+}
+
+// jsonState tracks whether the output so far ends inside a JSON string
+type jsonState struct {
+	inString bool
+	escaped  bool
+}
+
+func (s *jsonState) feed(b byte) {
+	switch {
+	case s.escaped:
+		s.escaped = false
+	case s.inString && b == '\\':
+		s.escaped = true
+	case b == '"':
+		s.inString = !s.inString
+	}
+}
+
+// escapeJSONString escapes a value for use between the quotes of a JSON string
+func escapeJSONString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s) // encoding a string cannot fail
+	encoded := strings.TrimSuffix(buf.String(), "\n")
+	return encoded[1 : len(encoded)-1]
+}
+
+// checkJSON reports invalid output with the template line and key of the nearest value
+func checkJSON(out string, placements []placement) error {
+	var js any
+	err := json.Unmarshal([]byte(out), &js)
+	if err == nil {
+		return nil
+	}
+
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return fmt.Errorf("rendered JSON invalid: %w", err)
+	}
+	offset := int(syntaxErr.Offset)
+	outputLine := strings.Count(out[:min(offset, len(out))], "\n") + 1
+	for i := len(placements) - 1; i >= 0; i-- {
+		if placements[i].offset < offset {
+			p := placements[i]
+			return fmt.Errorf("rendered JSON invalid at output line %d (after template line %d, %s): %w",
+				outputLine, p.line, describeKey(p.key), err)
+		}
+	}
+	return fmt.Errorf("rendered JSON invalid at output line %d: %w", outputLine, err)
+}
+
+func describeKey(key string) string {
+	if key == "" {
+		return "value"
+	}
+	return "key " + key
+}
@@ -0,0 +1,121 @@
+package templatex
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/furiatona/azctl/internal/config"
+)
+
+func TestRenderJSONEscapesStrings(t *testing.T) {
+	cfg := config.New()
+	cfg.Set("PEM", "-----BEGIN KEY-----\nabc\\def\n-----END KEY-----")
+	cfg.Set("CONN", `{"host":"db","tls":true}`)
+	cfg.Set("ACI_PORT", "8080")
+	cfg.Set("HOSTS", "a,b")
+
+	input := `{
+  "key": "{{ env "PEM" }}",
+  "conn": "{{ env "CONN" }}",
+  "port": {{ env "ACI_PORT" }},
+  "hosts": {{ env "HOSTS" | split "," | toJson }},
+  "url": "https://{{ env "HOSTS" | split "," | join "." }}.example.com"
+}`
+	out, err := RenderJSON(input, cfg)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	var got struct {
+		Key   string   `json:"key"`
+		Conn  string   `json:"conn"`
+		Port  int      `json:"port"`
+		Hosts []string `json:"hosts"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, out)
+	}
+	if got.Key != cfg.Get("PEM") || got.Conn != cfg.Get("CONN") {
+		t.Errorf("values did not round-trip: %+v", got)
+	}
+	if got.Port != 8080 || len(got.Hosts) != 2 {
+		t.Errorf("unexpected port or hosts: %+v", got)
+	}
+}
+
+func TestRenderJSONRejectsNonNumeric(t *testing.T) {
+	cfg := config.New()
+	cfg.Set("ACI_PORT", "eighty")
+
+	input := "{\n  \"port\": {{ env \"ACI_PORT\" }}\n}"
+	_, err := RenderJSON(input, cfg)
+	if err == nil {
+		t.Fatal("expected error for non-numeric value outside a string")
+	}
+	for _, want := range []string{"template line 2", "ACI_PORT", "eighty"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestRenderJSONRequiresNumbersOutsideStrings(t *testing.T) {
+	for _, value := range []string{`{"x":1}`, "true", `"80"`, "[80]"} {
+		cfg := config.New()
+		cfg.Set("ACI_PORT", value)
+		if _, err := RenderJSON(`{"port": {{ env "ACI_PORT" }}}`, cfg); err == nil ||
+			!strings.Contains(err.Error(), "must be a number") {
+			t.Errorf("%s: expected a number error, got %v", value, err)
+		}
+	}
+
+	cfg := config.New()
+	cfg.Set("ACI_PORT", "-1.5e3")
+	cfg.Set("TAGS", `{"team":"api"}`)
+	out, err := RenderJSON(`{"port": {{ env "ACI_PORT" }}, "tags": {{ env "TAGS" | toJson }}, `+
+		`"logs": {{ hasKey "LOGS" }}}`, cfg)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	if out != `{"port": -1.5e3, "tags": "{\"team\":\"api\"}", "logs": false}` {
+		t.Errorf("unexpected output: %s", out)
+	}
+}
+
+func TestRenderJSONReportsTemplateLine(t *testing.T) {
+	cfg := config.New()
+	cfg.Set("NAME", "api")
+
+	// Missing comma after the name value
+	input := "{\n  \"name\": \"{{ env \"NAME\" }}\"\n  \"location\": \"westeurope\"\n}"
+	_, err := RenderJSON(input, cfg)
+	if err == nil {
+		t.Fatal("expected invalid JSON error")
+	}
+	for _, want := range []string{"output line 3", "template line 2", "NAME"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestRenderJSONControlStructures(t *testing.T) {
+	cfg := config.New()
+	cfg.Set("NEXT_PUBLIC_A", `say "hi"`)
+	cfg.Set("NEXT_PUBLIC_B", "2")
+
+	input := `[{{ range $k, $v := envPrefix "NEXT_PUBLIC_" }}{"name": "{{ $k }}", "value": "{{ $v }}"},{{ end }}` +
+		`{{ if hasKey "MISSING" }}{}{{ else }}{"name": "last"}{{ end }}]`
+	out, err := RenderJSON(input, cfg)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	var got []map[string]string
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, out)
+	}
+	if len(got) != 3 || got[0]["value"] != `say "hi"` {
+		t.Errorf("unexpected result: %v", got)
+	}
+}
@@ -0,0 +1,143 @@
+package templatex
+
+import (
+	"fmt"
+	"sort"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/furiatona/azctl/internal/config"
+)
+
+// Reference is a configuration key used by a template
+type Reference struct {
+	Key string
+	// Line is the first template line referencing the key
+	Line int
+	// Required is set when rendering fails without the key: an unconditional env call or
+	// a key wrapped in required
+	Required bool
+	// HasDefault is set when every use supplies a fallback (envOr) or only tests for the key (hasKey)
+	HasDefault bool
+}
+
+// References parses a template and returns every key passed to env, envOr or hasKey, sorted
+// by key. Keys used only inside if/with/range blocks are not Required, since rendering needs
+// them only when the block runs.
+func References(input string) ([]Reference, error) {
+	t, err := template.New("refs").Funcs(FuncMap(config.New())).Parse(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	w := &refWalker{refs: make(map[string]*Reference)}
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree != nil {
+			w.walk(tmpl.Tree.Root, false)
+		}
+	}
+
+	refs := make([]Reference, 0, len(w.refs))
+	for _, ref := range w.refs {
+		refs = append(refs, *ref)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Key < refs[j].Key })
+	return refs, nil
+}
+
+// Missing returns the references whose keys have no value in cfg
+func Missing(refs []Reference, cfg *config.Config) []Reference {
+	var missing []Reference
+	for _, ref := range refs {
+		if !cfg.Has(ref.Key) {
+			missing = append(missing, ref)
+		}
+	}
+	return missing
+}
+
+type refWalker struct {
+	refs map[string]*Reference
+	line int
+}
+
+func (w *refWalker) walk(node parse.Node, conditional bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			w.walk(child, conditional)
+		}
+	case *parse.ActionNode:
+		w.line = n.Line
+		w.pipe(n.Pipe, conditional, false)
+	case *parse.TemplateNode:
+		w.line = n.Line
+		w.pipe(n.Pipe, conditional, false)
+	case *parse.IfNode:
+		w.branch(&n.BranchNode, conditional)
+	case *parse.RangeNode:
+		w.branch(&n.BranchNode, conditional)
+	case *parse.WithNode:
+		w.branch(&n.BranchNode, conditional)
+	}
+}
+
+func (w *refWalker) branch(n *parse.BranchNode, conditional bool) {
+	w.line = n.Line
+	w.pipe(n.Pipe, conditional, false)
+	w.walk(n.List, true)
+	w.walk(n.ElseList, true)
+}
+
+func (w *refWalker) pipe(pipe *parse.PipeNode, conditional, required bool) {
+	if pipe == nil {
+		return
+	}
+	// {{ envOr "KEY" "" | required "message" }} requires KEY as much as the nested form does
+	for _, cmd := range pipe.Cmds {
+		if ident, ok := cmd.Args[0].(*parse.IdentifierNode); ok && ident.Ident == "required" {
+			required = true
+		}
+	}
+	for _, cmd := range pipe.Cmds {
+		w.command(cmd, conditional, required)
+	}
+}
+
+func (w *refWalker) command(cmd *parse.CommandNode, conditional, required bool) {
+	if len(cmd.Args) == 0 {
+		return
+	}
+	if ident, ok := cmd.Args[0].(*parse.IdentifierNode); ok {
+		if ident.Ident == "required" {
+			required = true
+		}
+		if keyFuncs[ident.Ident] && len(cmd.Args) > 1 {
+			if key, ok := cmd.Args[1].(*parse.StringNode); ok {
+				w.add(key.Text, ident.Ident, conditional, required)
+			}
+		}
+	}
+	for _, arg := range cmd.Args[1:] {
+		if nested, ok := arg.(*parse.PipeNode); ok {
+			w.pipe(nested, conditional, required)
+		}
+	}
+}
+
+func (w *refWalker) add(key, fn string, conditional, required bool) {
+	ref, ok := w.refs[key]
+	if !ok {
+		ref = &Reference{Key: key, Line: w.line, HasDefault: true}
+		w.refs[key] = ref
+	}
+	if fn == "env" || required {
+		ref.HasDefault = false
+		if !conditional {
+			ref.Required = true
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package templatex
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/furiatona/azctl/internal/config"
+)
+
+func TestReferences(t *testing.T) {
+	input := `{
+  "name": "{{ env "CONTAINER_GROUP_NAME" }}",
+  "tier": "{{ envOr "TIER" "basic" }}",
+  "url": "{{ envOr "SUPABASE_URL" "" | required "SUPABASE_URL must be set" }}",
+{{- if hasKey "SENTRY_DSN" }}
+  "sentry": "{{ env "SENTRY_DSN" }}",
+{{- end }}
+  "port": {{ env "PORT" | default (env "ACI_PORT") }}
+}`
+	refs, err := References(input)
+	if err != nil {
+		t.Fatalf("references failed: %v", err)
+	}
+
+	want := []Reference{
+		{Key: "ACI_PORT", Line: 8, Required: true},
+		{Key: "CONTAINER_GROUP_NAME", Line: 2, Required: true},
+		{Key: "PORT", Line: 8, Required: true},
+		{Key: "SENTRY_DSN", Line: 5},
+		{Key: "SUPABASE_URL", Line: 4, Required: true},
+		{Key: "TIER", Line: 3, HasDefault: true},
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("got %+v\nwant %+v", refs, want)
+	}
+
+	cfg := config.New()
+	cfg.Set("CONTAINER_GROUP_NAME", "api")
+	cfg.Set("PORT", "8080")
+	cfg.Set("ACI_PORT", "8080")
+
+	var missing []string
+	for _, ref := range Missing(refs, cfg) {
+		missing = append(missing, ref.Key)
+	}
+	if want := []string{"SENTRY_DSN", "SUPABASE_URL", "TIER"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("missing: got %v want %v", missing, want)
+	}
+}
+
+func TestReferencesDefinedTemplates(t *testing.T) {
+	refs, err := References(`{{ define "image" }}{{ env "ACR_REGISTRY" }}/{{ env "IMAGE_NAME" }}{{ end }}` +
+		`{"image": "{{ template "image" }}"}`)
+	if err != nil {
+		t.Fatalf("references failed: %v", err)
+	}
+	if len(refs) != 2 || refs[0].Key != "ACR_REGISTRY" || refs[1].Key != "IMAGE_NAME" {
+		t.Errorf("unexpected references: %+v", refs)
+	}
+}
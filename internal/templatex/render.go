@@ -1,3 +1,5 @@
+// Package templatex renders deployment templates (ACI manifests, Fluent-bit configs)
+// with values from the azctl configuration.
 package templatex
 
 import (
@@ -8,18 +10,11 @@ import (
 	"github.com/furiatona/azctl/internal/config"
 )
 
-// RenderEnv replaces placeholders like {{VAR}} using values from Config.
+// RenderEnv replaces placeholders like {{ env "VAR" }} using values from Config.
+// See FuncMap for the available template functions.
 func RenderEnv(input string, cfg *config.Config) (string, error) {
 	// register functions before parsing
-	t := template.New("aci").Option("missingkey=error").Funcs(template.FuncMap{
-		"env": func(k string) (string, error) {
-			v := cfg.Get(k)
-			if v == "" {
-				return "", fmt.Errorf("missing env: %s", k)
-			}
-			return v, nil
-		},
-	})
+	t := template.New("aci").Option("missingkey=error").Funcs(FuncMap(cfg))
 	var err error
 	t, err = t.Parse(input)
 	if err != nil {
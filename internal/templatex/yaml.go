@@ -0,0 +1,124 @@
+package templatex
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/furiatona/azctl/internal/config"
+)
+
+// placeholderFormat stands in for each substituted value when parsing the structure of a
+// YAML template without its values
+const placeholderFormat = "azctl_%d_"
+
+var placeholderPattern = regexp.MustCompile(`azctl_([0-9]+)_`)
+
+// RenderYAML renders a YAML template like RenderEnv, but fails when a substituted value
+// changes the structure of the document instead of becoming (part of) a scalar. In a plain
+// scalar, a value containing ": ", " #" or a newline, or starting with * or &, would
+// otherwise add keys, be truncated or be parsed as an alias; such values can be written as
+// {{ env "KEY" | quote }}. Output of toJson, quote, environmentVariables and hasKey may form
+// any YAML value. Errors name the template line and the configuration key.
+func RenderYAML(input string, cfg *config.Config) (string, error) {
+	raw, captures, err := execute(input, cfg)
+	if err != nil {
+		return "", err
+	}
+
+	placeholders := make([]capture, len(captures))
+	for i := range captures {
+		placeholders[i] = capture{value: fmt.Sprintf(placeholderFormat, i)}
+	}
+	var want yaml.Node
+	if err := yaml.Unmarshal([]byte(fill(raw, placeholders)), &want); err != nil {
+		return "", fmt.Errorf("invalid YAML template: %w", err)
+	}
+
+	out := fill(raw, captures)
+	if checkYAML(&want, out, captures) == nil {
+		return out, nil
+	}
+	// Substitute one value at a time to find the one that breaks the document
+	for i, c := range captures {
+		values := slices.Clone(placeholders)
+		values[i] = c
+		if checkYAML(&want, fill(raw, values), values) != nil {
+			return "", fmt.Errorf("template line %d: %s: value changes the YAML structure (it contains "+
+				"\": \", \" #\" or a newline, or starts with a YAML indicator); quote it, e.g. {{ env %q | quote }}",
+				c.line, describeKey(c.key), orDefault(c.key, "KEY"))
+		}
+	}
+	return "", fmt.Errorf("rendered YAML does not match the structure of the template: %w",
+		checkYAML(&want, out, captures))
+}
+
+// fill replaces the sentinels in raw with the captured values
+func fill(raw string, captures []capture) string {
+	var out strings.Builder
+	for i := 0; i < len(raw); {
+		if !strings.HasPrefix(raw[i:], sentinel) {
+			out.WriteByte(raw[i])
+			i++
+			continue
+		}
+		start := i + len(sentinel)
+		end := strings.IndexByte(raw[start:], 0)
+		index, _ := strconv.Atoi(raw[start : start+end])
+		out.WriteString(captures[index].value)
+		i = start + end + 1
+	}
+	return out.String()
+}
+
+// checkYAML parses doc and compares it with the template's structure
+func checkYAML(want *yaml.Node, doc string, captures []capture) error {
+	var got yaml.Node
+	if err := yaml.Unmarshal([]byte(doc), &got); err != nil {
+		return fmt.Errorf("invalid YAML: %w", err)
+	}
+	if !sameYAML(want, &got, captures) {
+		return fmt.Errorf("a value changes the document structure")
+	}
+	return nil
+}
+
+// sameYAML reports whether got has the structure of the template parsed with placeholders,
+// want, with every scalar holding values reading back as exactly those values
+func sameYAML(want, got *yaml.Node, captures []capture) bool {
+	if want.Kind == yaml.ScalarNode {
+		if loc := placeholderPattern.FindStringIndex(want.Value); loc != nil && loc[0] == 0 &&
+			loc[1] == len(want.Value) && captures[placeholderIndex(want.Value)].json {
+			return true
+		}
+		expected := placeholderPattern.ReplaceAllStringFunc(want.Value, func(p string) string {
+			return captures[placeholderIndex(p)].value
+		})
+		return got.Kind == yaml.ScalarNode && got.Value == expected
+	}
+	if want.Kind != got.Kind || want.Value != got.Value || len(want.Content) != len(got.Content) {
+		return false
+	}
+	for i := range want.Content {
+		if !sameYAML(want.Content[i], got.Content[i], captures) {
+			return false
+		}
+	}
+	return true
+}
+
+func placeholderIndex(placeholder string) int {
+	n, _ := strconv.Atoi(placeholderPattern.FindStringSubmatch(placeholder)[1])
+	return n
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
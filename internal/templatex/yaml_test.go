@@ -0,0 +1,70 @@
+package templatex
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/furiatona/azctl/internal/config"
+)
+
+func TestRenderYAML(t *testing.T) {
+	cfg := config.New()
+	cfg.Set("IMAGE", "myacr.azurecr.io/api:v1")
+	cfg.Set("PORT", "8080")
+	cfg.Set("GREETING", "hello # not a comment")
+	cfg.Set("PEM", "-----BEGIN KEY-----\nabc\n-----END KEY-----")
+
+	input := `name: api
+image: {{ env "IMAGE" }}
+port: {{ env "PORT" }}
+url: "https://{{ env "IMAGE" }}/health"
+greeting: {{ env "GREETING" | quote }}
+key: {{ env "PEM" | quote }}
+hosts: {{ env "IMAGE" | split "/" | toJson }}
+`
+	out, err := RenderYAML(input, cfg)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	var got struct {
+		Image    string   `yaml:"image"`
+		Port     int      `yaml:"port"`
+		Greeting string   `yaml:"greeting"`
+		Key      string   `yaml:"key"`
+		Hosts    []string `yaml:"hosts"`
+	}
+	if err := yaml.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Image != cfg.Get("IMAGE") || got.Port != 8080 || got.Greeting != cfg.Get("GREETING") ||
+		got.Key != cfg.Get("PEM") || len(got.Hosts) != 2 {
+		t.Errorf("values did not round-trip: %+v", got)
+	}
+}
+
+func TestRenderYAMLRejectsStructuralValues(t *testing.T) {
+	tests := map[string]string{
+		"comment":    "hello # truncated",
+		"newline":    "-----BEGIN KEY-----\nabc\n-----END KEY-----",
+		"mapping":    "a: b",
+		"alias":      "*ref",
+		"extra keys": "v1\nport: 9090",
+	}
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := config.New()
+			cfg.Set("VALUE", value)
+			_, err := RenderYAML("name: api\nvalue: {{ env \"VALUE\" }}\nport: 8080\n", cfg)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range []string{"template line 2", "VALUE"} {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+// Package traffic moves production traffic between container groups during blue/green
+// deployments. Each Switcher points one kind of entry point (an Azure DNS record, an
+// Application Gateway backend pool or a Front Door origin) at a new container group.
+package traffic
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/furiatona/azctl/internal/config"
+	"github.com/furiatona/azctl/internal/runx"
+)
+
+// Switcher names accepted by New
+const (
+	SwitcherNone      = "none"
+	SwitcherDNS       = "dns"
+	SwitcherAppGW     = "appgw"
+	SwitcherFrontDoor = "frontdoor"
+)
+
+// Endpoint is the container group traffic should be sent to
+type Endpoint struct {
+	Name string
+	FQDN string
+	IP   string
+}
+
+// Switcher points an entry point at a container group
+type Switcher interface {
+	// Name describes the switcher in logs, e.g. "DNS record api.example.com"
+	Name() string
+	Switch(ctx context.Context, target Endpoint) error
+}
+
+// Remover is implemented by switchers whose entry point exists only for the deployment, so
+// it is deleted when the deployment is torn down. Shared entry points such as an Application
+// Gateway are left alone.
+type Remover interface {
+	Remove(ctx context.Context) error
+}
+
+// New returns the switcher selected by name (env: TRAFFIC_SWITCHER), configured from cfg.
+// Missing settings are reported together.
+func New(name string, cfg *config.Config, resourceGroup string) (Switcher, error) {
+	switch strings.ToLower(name) {
+	case "", SwitcherNone:
+		return None{}, nil
+	case SwitcherDNS:
+		s := &DNSRecord{
+			ResourceGroup: orDefault(cfg.Get("TRAFFIC_DNS_RESOURCE_GROUP"), resourceGroup),
+			Zone:          cfg.Get("TRAFFIC_DNS_ZONE"),
+			Record:        cfg.Get("TRAFFIC_DNS_RECORD"),
+		}
+		return configured(s, requireSettings(name, map[string]string{
+			"TRAFFIC_DNS_ZONE":   s.Zone,
+			"TRAFFIC_DNS_RECORD": s.Record,
+		}))
+	case SwitcherAppGW:
+		s := &AppGateway{
+			ResourceGroup: orDefault(cfg.Get("TRAFFIC_APPGW_RESOURCE_GROUP"), resourceGroup),
+			Gateway:       cfg.Get("TRAFFIC_APPGW_NAME"),
+			Pool:          cfg.Get("TRAFFIC_APPGW_POOL"),
+		}
+		return configured(s, requireSettings(name, map[string]string{
+			"TRAFFIC_APPGW_NAME": s.Gateway,
+			"TRAFFIC_APPGW_POOL": s.Pool,
+		}))
+	case SwitcherFrontDoor:
+		s := &FrontDoorOrigin{
+			ResourceGroup: orDefault(cfg.Get("TRAFFIC_FRONTDOOR_RESOURCE_GROUP"), resourceGroup),
+			Profile:       cfg.Get("TRAFFIC_FRONTDOOR_PROFILE"),
+			OriginGroup:   cfg.Get("TRAFFIC_FRONTDOOR_ORIGIN_GROUP"),
+			Origin:        cfg.Get("TRAFFIC_FRONTDOOR_ORIGIN"),
+		}
+		return configured(s, requireSettings(name, map[string]string{
+			"TRAFFIC_FRONTDOOR_PROFILE":      s.Profile,
+			"TRAFFIC_FRONTDOOR_ORIGIN_GROUP": s.OriginGroup,
+			"TRAFFIC_FRONTDOOR_ORIGIN":       s.Origin,
+		}))
+	}
+	return nil, fmt.Errorf("unknown traffic switcher %q (expected %s, %s, %s or %s)",
+		name, SwitcherNone, SwitcherDNS, SwitcherAppGW, SwitcherFrontDoor)
+}
+
+// None leaves traffic alone, for setups where clients follow the new container group's DNS
+// name and for local testing
+type None struct{}
+
+// Name implements Switcher
+func (None) Name() string { return "no traffic switch" }
+
+// Switch implements Switcher
+func (None) Switch(context.Context, Endpoint) error { return nil }
+
+// DNSRecord points an Azure DNS CNAME record at the container group's FQDN
+type DNSRecord struct {
+	ResourceGroup string
+	Zone          string
+	Record        string
+}
+
+// Name implements Switcher
+func (s *DNSRecord) Name() string { return fmt.Sprintf("DNS record %s.%s", s.Record, s.Zone) }
+
+// Switch implements Switcher
+func (s *DNSRecord) Switch(ctx context.Context, target Endpoint) error {
+	if target.FQDN == "" {
+		return fmt.Errorf("container group %s has no FQDN (set a dnsNameLabel)", target.Name)
+	}
+	return run(ctx, s.Name(), "network", "dns", "record-set", "cname", "set-record",
+		"--resource-group", s.ResourceGroup, "--zone-name", s.Zone,
+		"--record-set-name", s.Record, "--cname", target.FQDN)
+}
+
+// Remove implements Remover
+func (s *DNSRecord) Remove(ctx context.Context) error {
+	if _, err := runx.AZOutput(ctx, "network", "dns", "record-set", "cname", "delete",
+		"--resource-group", s.ResourceGroup, "--zone-name", s.Zone, "--name", s.Record, "--yes"); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", s.Name(), err)
+	}
+	return nil
+}
+
+// AppGateway replaces the servers of an Application Gateway backend pool with the container
+// group's IP address
+type AppGateway struct {
+	ResourceGroup string
+	Gateway       string
+	Pool          string
+}
+
+// Name implements Switcher
+func (s *AppGateway) Name() string {
+	return fmt.Sprintf("Application Gateway %s backend pool %s", s.Gateway, s.Pool)
+}
+
+// Switch implements Switcher
+func (s *AppGateway) Switch(ctx context.Context, target Endpoint) error {
+	if target.IP == "" {
+		return fmt.Errorf("container group %s has no IP address", target.Name)
+	}
+	return run(ctx, s.Name(), "network", "application-gateway", "address-pool", "update",
+		"--resource-group", s.ResourceGroup, "--gateway-name", s.Gateway,
+		"--name", s.Pool, "--servers", target.IP)
+}
+
+// FrontDoorOrigin points a Front Door origin at the container group's FQDN
+type FrontDoorOrigin struct {
+	ResourceGroup string
+	Profile       string
+	OriginGroup   string
+	Origin        string
+}
+
+// Name implements Switcher
+func (s *FrontDoorOrigin) Name() string {
+	return fmt.Sprintf("Front Door %s origin %s/%s", s.Profile, s.OriginGroup, s.Origin)
+}
+
+// Switch implements Switcher
+func (s *FrontDoorOrigin) Switch(ctx context.Context, target Endpoint) error {
+	if target.FQDN == "" {
+		return fmt.Errorf("container group %s has no FQDN (set a dnsNameLabel)", target.Name)
+	}
+	return run(ctx, s.Name(), "afd", "origin", "update",
+		"--resource-group", s.ResourceGroup, "--profile-name", s.Profile,
+		"--origin-group-name", s.OriginGroup, "--origin-name", s.Origin,
+		"--host-name", target.FQDN, "--origin-host-header", target.FQDN)
+}
+
+func run(ctx context.Context, name string, args ...string) error {
+	if _, err := runx.AZOutput(ctx, args...); err != nil {
+		return fmt.Errorf("failed to update %s: %w", name, err)
+	}
+	return nil
+}
+
+// configured returns s, or nil if its settings are incomplete
+func configured(s Switcher, err error) (Switcher, error) {
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func requireSettings(switcher string, settings map[string]string) error {
+	var missing []string
+	for key, value := range settings {
+		if value == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Strings(missing)
+	return fmt.Errorf("%s traffic switcher requires %s", switcher, strings.Join(missing, ", "))
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
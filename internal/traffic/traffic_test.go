@@ -0,0 +1,81 @@
+package traffic
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/furiatona/azctl/internal/config"
+	"github.com/furiatona/azctl/internal/runx"
+)
+
+func TestNew(t *testing.T) {
+	cfg := config.New()
+	if s, err := New("", cfg, "rg"); err != nil || s.Name() != "no traffic switch" {
+		t.Errorf("expected the no-op switcher, got %v (err %v)", s, err)
+	}
+
+	_, err := New(SwitcherFrontDoor, cfg, "rg")
+	want := "TRAFFIC_FRONTDOOR_ORIGIN, TRAFFIC_FRONTDOOR_ORIGIN_GROUP, TRAFFIC_FRONTDOOR_PROFILE"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("expected every missing setting, got %v", err)
+	}
+
+	if _, err := New("haproxy", cfg, "rg"); err == nil {
+		t.Error("expected an unknown switcher error")
+	}
+}
+
+func TestSwitchers(t *testing.T) {
+	cfg := config.New()
+	cfg.Set("TRAFFIC_DNS_ZONE", "example.com")
+	cfg.Set("TRAFFIC_DNS_RECORD", "api")
+	cfg.Set("TRAFFIC_DNS_RESOURCE_GROUP", "dns-rg")
+	cfg.Set("TRAFFIC_APPGW_NAME", "gw")
+	cfg.Set("TRAFFIC_APPGW_POOL", "api-pool")
+	cfg.Set("TRAFFIC_FRONTDOOR_PROFILE", "fd")
+	cfg.Set("TRAFFIC_FRONTDOOR_ORIGIN_GROUP", "api")
+	cfg.Set("TRAFFIC_FRONTDOOR_ORIGIN", "aci")
+
+	target := Endpoint{Name: "api-green", FQDN: "api-green.westeurope.azurecontainer.io", IP: "20.1.2.3"}
+	tests := map[string]string{
+		SwitcherDNS: "network dns record-set cname set-record --resource-group dns-rg --zone-name example.com " +
+			"--record-set-name api --cname api-green.westeurope.azurecontainer.io",
+		SwitcherAppGW: "network application-gateway address-pool update --resource-group rg --gateway-name gw " +
+			"--name api-pool --servers 20.1.2.3",
+		SwitcherFrontDoor: "afd origin update --resource-group rg --profile-name fd --origin-group-name api " +
+			"--origin-name aci --host-name api-green.westeurope.azurecontainer.io",
+	}
+	for name, want := range tests {
+		t.Run(name, func(t *testing.T) {
+			fake := runx.NewFake().On("", "{}", nil)
+			defer runx.SetExecutor(fake)()
+
+			s, err := New(name, cfg, "rg")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Switch(context.Background(), target); err != nil {
+				t.Fatal(err)
+			}
+			if !fake.Called(want) {
+				t.Errorf("expected %q, got %v", want, fake.Calls())
+			}
+		})
+	}
+
+	s, _ := New(SwitcherDNS, cfg, "rg")
+	if err := s.Switch(context.Background(), Endpoint{Name: "api-green"}); err == nil {
+		t.Error("expected an error for a group without FQDN")
+	}
+
+	fake := runx.NewFake().On("", "", nil)
+	defer runx.SetExecutor(fake)()
+	if err := s.(Remover).Remove(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := "network dns record-set cname delete --resource-group dns-rg --zone-name example.com --name api"
+	if !fake.Called(want) {
+		t.Errorf("expected %q, got %v", want, fake.Calls())
+	}
+}
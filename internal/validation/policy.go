@@ -0,0 +1,334 @@
+package validation
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/furiatona/azctl/internal/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PolicySet is a collection of user-defined policies loaded from YAML.
+//
+// Example policy file:
+//
+//	policies:
+//	  - name: prod-min-cpu
+//	    message: production containers need at least one CPU
+//	    environments: [prod]
+//	    targets: [aci]
+//	    assert:
+//	      - key: ACI_CPU
+//	        gte: 1
+//	  - name: dns-label-prefix
+//	    severity: warning
+//	    assert:
+//	      - key: DNS_NAME_LABEL
+//	        starts_with: ${IMAGE_NAME}
+//	  - name: no-latest-outside-dev
+//	    except_environments: [dev]
+//	    assert:
+//	      - key: IMAGE_TAG
+//	        not_equals: latest
+type PolicySet struct {
+	Policies []Policy `yaml:"policies"`
+	// File is the path the set was loaded from
+	File string `yaml:"-"`
+}
+
+// Policy is a single named rule. A policy applies when the current environment and target
+// match its scope and every `when` condition holds; each failing `assert` condition is a violation.
+type Policy struct {
+	Name               string      `yaml:"name"`
+	Message            string      `yaml:"message"`
+	Severity           Severity    `yaml:"severity"`
+	Environments       []string    `yaml:"environments"`
+	ExceptEnvironments []string    `yaml:"except_environments"`
+	Targets            []string    `yaml:"targets"`
+	When               []Condition `yaml:"when"`
+	Assert             []Condition `yaml:"assert"`
+	line               int
+}
+
+// Condition tests a single configuration key. Operand values may reference other keys
+// as ${KEY}. Conditions on an unset key are skipped unless Required is set.
+type Condition struct {
+	Key        string   `yaml:"key"`
+	Required   bool     `yaml:"required"`
+	Equals     *string  `yaml:"equals"`
+	NotEquals  *string  `yaml:"not_equals"`
+	In         []string `yaml:"in"`
+	NotIn      []string `yaml:"not_in"`
+	Matches    *string  `yaml:"matches"`
+	NotMatches *string  `yaml:"not_matches"`
+	StartsWith *string  `yaml:"starts_with"`
+	EndsWith   *string  `yaml:"ends_with"`
+	GT         *string  `yaml:"gt"`
+	GTE        *string  `yaml:"gte"`
+	LT         *string  `yaml:"lt"`
+	LTE        *string  `yaml:"lte"`
+}
+
+// LoadPolicyFile reads and checks a YAML policy file
+func LoadPolicyFile(path string) (*PolicySet, error) {
+	raw, err := os.ReadFile(path) //nolint:gosec // policy path is provided by the user
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	set, err := ParsePolicies(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	set.File = path
+	return set, nil
+}
+
+// ParsePolicies decodes a YAML policy document, rejecting unknown fields, invalid
+// severities and invalid regular expressions
+func ParsePolicies(data []byte) (*PolicySet, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid policy YAML: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var set PolicySet
+	if err := dec.Decode(&set); err != nil {
+		return nil, fmt.Errorf("invalid policy YAML: %w", err)
+	}
+
+	lines := policyLines(&root)
+	for i := range set.Policies {
+		policy := &set.Policies[i]
+		if i < len(lines) {
+			policy.line = lines[i]
+		}
+		if err := policy.check(); err != nil {
+			return nil, fmt.Errorf("line %d: policy %q: %w", policy.line, policy.Name, err)
+		}
+	}
+	return &set, nil
+}
+
+// Rules converts the policies that apply to an environment and target into validation rules
+func (s *PolicySet) Rules(envName, target string) []ValidationRule {
+	var rules []ValidationRule
+	for i := range s.Policies {
+		policy := s.Policies[i]
+		if !policy.appliesTo(envName, target) {
+			continue
+		}
+		rules = append(rules, ValidationRule{
+			Name:     "Policy " + policy.Name,
+			Severity: policy.Severity,
+			Custom:   policy.evaluate,
+			File:     s.File,
+			Line:     policy.line,
+		})
+	}
+	return rules
+}
+
+func (p *Policy) check() error {
+	if p.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch p.Severity {
+	case "", SeverityError, SeverityWarning:
+	default:
+		return fmt.Errorf("invalid severity %q (expected error or warning)", p.Severity)
+	}
+	if len(p.Assert) == 0 {
+		return fmt.Errorf("at least one assert condition is required")
+	}
+	for _, conditions := range [][]Condition{p.When, p.Assert} {
+		for _, c := range conditions {
+			if c.Key == "" {
+				return fmt.Errorf("condition is missing key")
+			}
+			for _, pattern := range []*string{c.Matches, c.NotMatches} {
+				if pattern == nil {
+					continue
+				}
+				if _, err := regexp.Compile(*pattern); err != nil {
+					return fmt.Errorf("invalid regex for %s: %w", c.Key, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Policy) appliesTo(envName, target string) bool {
+	if len(p.Environments) > 0 && !containsFold(p.Environments, envName) {
+		return false
+	}
+	if containsFold(p.ExceptEnvironments, envName) {
+		return false
+	}
+	if len(p.Targets) > 0 && !containsFold(p.Targets, target) {
+		return false
+	}
+	return true
+}
+
+// evaluate returns a FieldError for each failing assertion, or nil when a `when`
+// condition does not hold. A `when` condition requiring a value does not hold for an
+// unset key.
+func (p *Policy) evaluate(cfg *config.Config) error {
+	for _, c := range p.When {
+		if (cfg.Get(c.Key) == "" && c.positive()) || c.test(cfg) != "" {
+			return nil
+		}
+	}
+
+	var errs []error
+	for _, c := range p.Assert {
+		if failure := c.test(cfg); failure != "" {
+			message := failure
+			if p.Message != "" {
+				message = fmt.Sprintf("%s (%s)", p.Message, failure)
+			}
+			errs = append(errs, &FieldError{Key: c.Key, Message: message})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// positive reports whether the condition requires a value, i.e. has an operator other
+// than not_equals, not_in and not_matches
+func (c *Condition) positive() bool {
+	return c.Required || c.Equals != nil || len(c.In) > 0 || c.Matches != nil || c.StartsWith != nil ||
+		c.EndsWith != nil || c.GT != nil || c.GTE != nil || c.LT != nil || c.LTE != nil
+}
+
+// test returns a description of why the condition fails, or "" if it holds
+func (c *Condition) test(cfg *config.Config) string {
+	value := cfg.Get(c.Key)
+	if value == "" {
+		if c.Required {
+			return fmt.Sprintf("%s is required", c.Key)
+		}
+		return ""
+	}
+
+	operand := func(s *string) string { return expand(*s, cfg) }
+	// Operands of a secret key are likely secrets too, e.g. equals: ${OTHER_KEY}
+	secret := quoteValue(cfg, c.Key, value) == redactedValue
+	quote := func(s string) string {
+		if secret {
+			return redactedValue
+		}
+		return strconv.Quote(s)
+	}
+
+	if c.Equals != nil && value != operand(c.Equals) {
+		return fmt.Sprintf("%s must equal %s (got %s)", c.Key, quote(operand(c.Equals)), quote(value))
+	}
+	if c.NotEquals != nil && value == operand(c.NotEquals) {
+		return fmt.Sprintf("%s must not be %s", c.Key, quote(value))
+	}
+	if len(c.In) > 0 && !containsExpanded(c.In, value, cfg) {
+		return fmt.Sprintf("%s must be one of %s (got %s)", c.Key, strings.Join(c.In, ", "), quote(value))
+	}
+	if len(c.NotIn) > 0 && containsExpanded(c.NotIn, value, cfg) {
+		return fmt.Sprintf("%s must not be one of %s (got %s)", c.Key, strings.Join(c.NotIn, ", "), quote(value))
+	}
+	if c.Matches != nil && !regexp.MustCompile(*c.Matches).MatchString(value) {
+		return fmt.Sprintf("%s must match %s (got %s)", c.Key, *c.Matches, quote(value))
+	}
+	if c.NotMatches != nil && regexp.MustCompile(*c.NotMatches).MatchString(value) {
+		return fmt.Sprintf("%s must not match %s (got %s)", c.Key, *c.NotMatches, quote(value))
+	}
+	if c.StartsWith != nil && !strings.HasPrefix(value, operand(c.StartsWith)) {
+		return fmt.Sprintf("%s must start with %s (got %s)", c.Key, quote(operand(c.StartsWith)), quote(value))
+	}
+	if c.EndsWith != nil && !strings.HasSuffix(value, operand(c.EndsWith)) {
+		return fmt.Sprintf("%s must end with %s (got %s)", c.Key, quote(operand(c.EndsWith)), quote(value))
+	}
+
+	comparisons := []struct {
+		operand *string
+		symbol  string
+		holds   func(a, b float64) bool
+	}{
+		{c.GT, ">", func(a, b float64) bool { return a > b }},
+		{c.GTE, ">=", func(a, b float64) bool { return a >= b }},
+		{c.LT, "<", func(a, b float64) bool { return a < b }},
+		{c.LTE, "<=", func(a, b float64) bool { return a <= b }},
+	}
+	for _, cmp := range comparisons {
+		if cmp.operand == nil {
+			continue
+		}
+		limitText := operand(cmp.operand)
+		actual, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Sprintf("%s must be a number (got %s)", c.Key, quote(value))
+		}
+		limit, err := strconv.ParseFloat(limitText, 64)
+		if err != nil {
+			return fmt.Sprintf("cannot compare %s with non-numeric %s", c.Key, quote(limitText))
+		}
+		if !cmp.holds(actual, limit) {
+			if secret {
+				return fmt.Sprintf("%s must be %s %s", c.Key, cmp.symbol, limitText)
+			}
+			return fmt.Sprintf("%s must be %s %s (got %s)", c.Key, cmp.symbol, limitText, value)
+		}
+	}
+
+	return ""
+}
+
+var keyReference = regexp.MustCompile(`\$\{([A-Za-z0-9_]+)\}`)
+
+// expand replaces ${KEY} references with configuration values
+func expand(s string, cfg *config.Config) string {
+	return keyReference.ReplaceAllStringFunc(s, func(ref string) string {
+		return cfg.Get(keyReference.FindStringSubmatch(ref)[1])
+	})
+}
+
+func containsExpanded(values []string, value string, cfg *config.Config) bool {
+	for _, v := range values {
+		if expand(v, cfg) == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// policyLines returns the line of each entry in the top-level policies sequence
+func policyLines(root *yaml.Node) []int {
+	if len(root.Content) == 0 {
+		return nil
+	}
+	mapping := root.Content[0]
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == "policies" {
+			var lines []int
+			for _, item := range mapping.Content[i+1].Content {
+				lines = append(lines, item.Line)
+			}
+			return lines
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package validation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/furiatona/azctl/internal/config"
+)
+
+const samplePolicies = `policies:
+  - name: prod-min-cpu
+    message: production containers need at least one CPU
+    environments: [prod]
+    targets: [aci]
+    assert:
+      - key: ACI_CPU
+        gte: 1
+  - name: dns-label-prefix
+    severity: warning
+    assert:
+      - key: DNS_NAME_LABEL
+        starts_with: ${IMAGE_NAME}
+  - name: no-latest-outside-dev
+    except_environments: [dev]
+    assert:
+      - key: IMAGE_TAG
+        not_equals: latest
+  - name: private-needs-subnet
+    when:
+      - key: ACI_NETWORK_MODE
+        equals: private
+    assert:
+      - key: ACI_SUBNET_IDS
+        required: true
+`
+
+func loadSample(t *testing.T) *PolicySet {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte(samplePolicies), 0644); err != nil {
+		t.Fatal(err)
+	}
+	set, err := LoadPolicyFile(path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	return set
+}
+
+func TestPolicyScoping(t *testing.T) {
+	set := loadSample(t)
+
+	if got := len(set.Rules("prod", TargetACI)); got != 4 {
+		t.Errorf("prod/aci: expected 4 rules, got %d", got)
+	}
+	if got := len(set.Rules("prod", TargetWebApp)); got != 3 {
+		t.Errorf("prod/webapp: expected 3 rules, got %d", got)
+	}
+	if got := len(set.Rules("dev", TargetACI)); got != 2 {
+		t.Errorf("dev/aci: expected 2 rules, got %d", got)
+	}
+}
+
+func TestPolicyViolations(t *testing.T) {
+	set := loadSample(t)
+	cfg := config.New()
+	cfg.Set("ACI_CPU", "0.5")
+	cfg.Set("IMAGE_NAME", "api")
+	cfg.Set("DNS_NAME_LABEL", "web-prod")
+	cfg.Set("IMAGE_TAG", "latest")
+	cfg.Set("ACI_NETWORK_MODE", "private")
+
+	engine := NewEngine()
+	for _, rule := range set.Rules("prod", TargetACI) {
+		engine.AddRule(rule)
+	}
+	report := engine.Run(cfg)
+
+	if len(report.Issues) != 4 {
+		t.Fatalf("expected 4 issues, got %v", report.Issues)
+	}
+
+	byKey := make(map[string]Issue)
+	for _, issue := range report.Issues {
+		byKey[issue.Key] = issue
+	}
+	if issue := byKey["ACI_CPU"]; !strings.Contains(issue.Message, "production containers need at least one CPU") ||
+		!strings.Contains(issue.Message, ">= 1") {
+		t.Errorf("unexpected ACI_CPU issue: %+v", issue)
+	}
+	if issue := byKey["DNS_NAME_LABEL"]; issue.Severity != SeverityWarning || !strings.Contains(issue.Message, `"api"`) {
+		t.Errorf("unexpected DNS_NAME_LABEL issue: %+v", issue)
+	}
+	if issue := byKey["ACI_SUBNET_IDS"]; issue.Line != 19 || issue.File != set.File {
+		t.Errorf("expected policy location %s:19, got %s:%d", set.File, issue.File, issue.Line)
+	}
+
+	// Satisfy the policies
+	cfg.Set("ACI_CPU", "1")
+	cfg.Set("DNS_NAME_LABEL", "api-prod")
+	cfg.Set("IMAGE_TAG", "v1.2.3")
+	cfg.Set("ACI_NETWORK_MODE", "public")
+	if report := engine.Run(cfg); len(report.Issues) != 0 {
+		t.Errorf("expected no issues, got %v", report.Issues)
+	}
+
+	// A `when` condition on an unset key does not hold
+	cfg.Set("ACI_NETWORK_MODE", "")
+	if report := engine.Run(cfg); len(report.Issues) != 0 {
+		t.Errorf("expected no issues without ACI_NETWORK_MODE, got %v", report.Issues)
+	}
+}
+
+func TestPolicyViolationsRedactSecrets(t *testing.T) {
+	set, err := ParsePolicies([]byte(`policies:
+  - name: supabase-key-format
+    assert:
+      - key: SUPABASE_KEY
+        starts_with: ${SUPABASE_PREFIX}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.New()
+	cfg.Set("SUPABASE_KEY", "weak-secret")
+	cfg.Set("SUPABASE_PREFIX", "eyJ-expected")
+
+	engine := NewEngine()
+	for _, rule := range set.Rules("prod", TargetACI) {
+		engine.AddRule(rule)
+	}
+	issues := engine.Run(cfg).Issues
+	if len(issues) != 1 {
+		t.Fatalf("expected 1 issue, got %v", issues)
+	}
+	if msg := issues[0].Message; strings.Contains(msg, "weak-secret") || strings.Contains(msg, "eyJ-expected") ||
+		!strings.Contains(msg, redactedValue) {
+		t.Errorf("expected a redacted message, got %q", msg)
+	}
+}
+
+func TestParsePoliciesRejectsInvalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown field":    "policies:\n  - name: x\n    asert: []\n",
+		"bad severity":     "policies:\n  - name: x\n    severity: fatal\n    assert:\n      - key: A\n        required: true\n",
+		"bad regex":        "policies:\n  - name: x\n    assert:\n      - key: A\n        matches: '['\n",
+		"missing assert":   "policies:\n  - name: x\n",
+		"missing cond key": "policies:\n  - name: x\n    assert:\n      - equals: y\n",
+	}
+	for name, doc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParsePolicies([]byte(doc)); err == nil {
+				t.Error("expected parse error")
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Report output formats understood by Report.Write
+const (
+	FormatText   = "text"
+	FormatJSON   = "json"
+	FormatSARIF  = "sarif"
+	FormatGitHub = "github"
+)
+
+// Write renders the report in the given format. version is reported as the tool version in SARIF output.
+func (r *Report) Write(w io.Writer, format, version string) error {
+	switch format {
+	case FormatText, "":
+		return r.WriteText(w)
+	case FormatJSON:
+		return r.WriteJSON(w)
+	case FormatSARIF:
+		return r.WriteSARIF(w, version)
+	case FormatGitHub:
+		return r.WriteGitHub(w)
+	default:
+		return fmt.Errorf("unknown report format: %s (expected %s, %s, %s or %s)",
+			format, FormatText, FormatJSON, FormatSARIF, FormatGitHub)
+	}
+}
+
+// WriteText prints every issue on its own line followed by a summary line
+func (r *Report) WriteText(w io.Writer) error {
+	for _, issue := range r.Issues {
+		if _, err := fmt.Fprintf(w, "%-8s %s\n", issue.Severity, issue.String()); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	}
+	if _, err := fmt.Fprintf(w, "%d error(s), %d warning(s)\n", len(r.Errors()), len(r.Warnings())); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// WriteJSON prints the issues and summary counts as a JSON document
+func (r *Report) WriteJSON(w io.Writer) error {
+	doc := struct {
+		Issues   []Issue `json:"issues"`
+		Errors   int     `json:"errors"`
+		Warnings int     `json:"warnings"`
+	}{
+		Issues:   r.Issues,
+		Errors:   len(r.Errors()),
+		Warnings: len(r.Warnings()),
+	}
+	if doc.Issues == nil {
+		doc.Issues = []Issue{}
+	}
+	return writeIndentedJSON(w, doc)
+}
+
+// WriteGitHub prints GitHub Actions workflow commands so issues show up as annotations
+func (r *Report) WriteGitHub(w io.Writer) error {
+	for _, issue := range r.Issues {
+		command := "error"
+		if issue.Severity == SeverityWarning {
+			command = "warning"
+		}
+
+		props := []string{"title=" + escapeGitHubProperty(issue.Rule)}
+		if issue.File != "" {
+			props = append(props, "file="+escapeGitHubProperty(issue.File))
+			if issue.Line > 0 {
+				props = append(props, fmt.Sprintf("line=%d", issue.Line))
+			}
+		}
+
+		message := issue.Message
+		if issue.Key != "" {
+			message = issue.Key + ": " + message
+		}
+		if _, err := fmt.Fprintf(w, "::%s %s::%s\n", command, strings.Join(props, ","),
+			escapeGitHubData(message)); err != nil {
+			return fmt.Errorf("failed to write annotation: %w", err)
+		}
+	}
+	return nil
+}
+
+// DefaultSARIFFile locates issues in SARIF output when neither the issue nor the report
+// names a file
+const DefaultSARIFFile = ".env"
+
+// WriteSARIF prints the issues as a SARIF 2.1.0 log for GitHub code scanning. Code scanning
+// drops results without a location, so issues without a file are reported at line 1 of
+// DefaultFile.
+func (r *Report) WriteSARIF(w io.Writer, version string) error {
+	type sarifText struct {
+		Text string `json:"text"`
+	}
+	type sarifRule struct {
+		ID               string    `json:"id"`
+		Name             string    `json:"name"`
+		ShortDescription sarifText `json:"shortDescription"`
+	}
+	type sarifRegion struct {
+		StartLine int `json:"startLine"`
+	}
+	type sarifArtifact struct {
+		URI string `json:"uri"`
+	}
+	type sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifact `json:"artifactLocation"`
+		Region           *sarifRegion  `json:"region,omitempty"`
+	}
+	type sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+	type sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifText       `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+
+	ruleNames := make(map[string]string)
+	results := make([]sarifResult, 0, len(r.Issues))
+	for _, issue := range r.Issues {
+		id := ruleID(issue.Rule)
+		ruleNames[id] = issue.Rule
+
+		result := sarifResult{
+			RuleID:  id,
+			Level:   sarifLevel(issue.Severity),
+			Message: sarifText{Text: issue.String()},
+		}
+		file, line := issue.File, issue.Line
+		if file == "" {
+			file, line = r.DefaultFile, 1
+		}
+		if file == "" {
+			file = DefaultSARIFFile
+		}
+		location := sarifPhysicalLocation{ArtifactLocation: sarifArtifact{URI: file}}
+		if line > 0 {
+			location.Region = &sarifRegion{StartLine: line}
+		}
+		result.Locations = []sarifLocation{{PhysicalLocation: location}}
+		results = append(results, result)
+	}
+
+	ids := make([]string, 0, len(ruleNames))
+	for id := range ruleNames {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	rules := make([]sarifRule, 0, len(ids))
+	for _, id := range ids {
+		rules = append(rules, sarifRule{ID: id, Name: ruleNames[id], ShortDescription: sarifText{Text: ruleNames[id]}})
+	}
+
+	doc := map[string]any{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []any{
+			map[string]any{
+				"tool": map[string]any{
+					"driver": map[string]any{
+						"name":           "azctl",
+						"version":        version,
+						"informationUri": "https://github.com/furiatona/azctl",
+						"rules":          rules,
+					},
+				},
+				"results": results,
+			},
+		},
+	}
+	return writeIndentedJSON(w, doc)
+}
+
+// ruleID turns a rule name like "ACI Configuration" into a stable identifier like "aci-configuration"
+func ruleID(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < '0' || r > '9')
+	})
+	return strings.Join(fields, "-")
+}
+
+func sarifLevel(severity Severity) string {
+	if severity == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// escapeGitHubData escapes a workflow command message
+func escapeGitHubData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	return strings.ReplaceAll(s, "\n", "%0A")
+}
+
+// escapeGitHubProperty escapes a workflow command property value
+func escapeGitHubProperty(s string) string {
+	s = escapeGitHubData(s)
+	s = strings.ReplaceAll(s, ":", "%3A")
+	return strings.ReplaceAll(s, ",", "%2C")
+}
+
+func writeIndentedJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	return nil
+}
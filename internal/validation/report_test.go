@@ -0,0 +1,114 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func sampleReport() *Report {
+	return &Report{Issues: []Issue{
+		{Rule: "ACI Configuration", Key: "ACI_CPU", Severity: SeverityError,
+			Message: "must be between 0.1 and 4.0", Source: ".env.prod", File: ".env.prod", Line: 7},
+		{Rule: "Security Configuration", Key: "SUPABASE_KEY", Severity: SeverityWarning,
+			Message: "sensitive value appears to be too short", Source: "Environment"},
+	}}
+}
+
+func TestReportWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := sampleReport().Write(&buf, FormatJSON, "dev"); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	var doc struct {
+		Issues   []Issue `json:"issues"`
+		Errors   int     `json:"errors"`
+		Warnings int     `json:"warnings"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if doc.Errors != 1 || doc.Warnings != 1 || len(doc.Issues) != 2 {
+		t.Errorf("unexpected counts: %+v", doc)
+	}
+	if doc.Issues[0].Line != 7 || doc.Issues[0].File != ".env.prod" {
+		t.Errorf("location not preserved: %+v", doc.Issues[0])
+	}
+}
+
+func TestReportWriteSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := sampleReport().Write(&buf, FormatSARIF, "1.2.3"); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	var doc struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid SARIF: %v", err)
+	}
+	if doc.Version != "2.1.0" || len(doc.Runs) != 1 || len(doc.Runs[0].Results) != 2 {
+		t.Fatalf("unexpected SARIF structure: %s", buf.String())
+	}
+
+	first := doc.Runs[0].Results[0]
+	if first.RuleID != "aci-configuration" || first.Level != "error" {
+		t.Errorf("unexpected result: %+v", first)
+	}
+	if len(first.Locations) != 1 || first.Locations[0].PhysicalLocation.Region.StartLine != 7 {
+		t.Errorf("expected location with line 7: %+v", first.Locations)
+	}
+	second := doc.Runs[0].Results[1]
+	if second.Level != "warning" {
+		t.Errorf("expected warning level for second result")
+	}
+	// An issue from the process environment is placed in the report's default file
+	if len(second.Locations) != 1 || second.Locations[0].PhysicalLocation.ArtifactLocation.URI != ".env" ||
+		second.Locations[0].PhysicalLocation.Region.StartLine != 1 {
+		t.Errorf("expected the default location, got %+v", second.Locations)
+	}
+}
+
+func TestReportWriteGitHub(t *testing.T) {
+	var buf bytes.Buffer
+	if err := sampleReport().Write(&buf, FormatGitHub, ""); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 annotations, got %q", buf.String())
+	}
+	want := "::error title=ACI Configuration,file=.env.prod,line=7::ACI_CPU: must be between 0.1 and 4.0"
+	if lines[0] != want {
+		t.Errorf("got %q want %q", lines[0], want)
+	}
+	if !strings.HasPrefix(lines[1], "::warning title=Security Configuration::") {
+		t.Errorf("unexpected warning annotation: %q", lines[1])
+	}
+}
+
+func TestReportWriteUnknownFormat(t *testing.T) {
+	if err := sampleReport().Write(&bytes.Buffer{}, "xml", ""); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
@@ -0,0 +1,161 @@
+package validation
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/furiatona/azctl/internal/config"
+)
+
+// SecretsRuleName is reported as the rule for secrets found in build arguments
+const SecretsRuleName = "Build Argument Secrets"
+
+// sensitiveFields are keys that always hold secrets
+var sensitiveFields = []string{
+	"ACR_PASSWORD",
+	"AZURE_OPENAI_API_KEY",
+	"FIREBASE_KEY",
+	"SUPABASE_KEY",
+}
+
+// SensitiveKeys returns the keys SecurityValidation treats as sensitive
+func SensitiveKeys() []string {
+	return append([]string(nil), sensitiveFields...)
+}
+
+// knownSecretFormats match well-known credential formats
+var knownSecretFormats = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+	{"AWS access key", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{"Stripe secret key", regexp.MustCompile(`\b(sk|rk)_live_[A-Za-z0-9]{16,}`)},
+	{"OpenAI API key", regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`)},
+	{"Azure storage connection string", regexp.MustCompile(`(?i)AccountKey=[A-Za-z0-9+/=]{20,}`)},
+	{"JSON Web Token", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{"URL with embedded credentials", regexp.MustCompile(`[a-z][a-z0-9+.-]*://[^/\s:@]+:[^/\s@]+@`)},
+}
+
+// Entropy thresholds for detecting random-looking values
+const (
+	minEntropyLength = 20
+	minEntropyBits   = 4.0
+)
+
+// secretSuffixes mark keys whose values are secrets regardless of what they look like
+var secretSuffixes = []string{"_KEY", "_SECRET", "_PASSWORD", "_TOKEN", "_CONNECTION_STRING"}
+
+// redactedValue replaces secret values in issue messages
+const redactedValue = "[redacted]"
+
+// IsSecret reports whether a value must not appear in plain text: keys named like
+// credentials (*_KEY, *_SECRET, *_PASSWORD, *_TOKEN, *_CONNECTION_STRING) and values
+// that look like secrets
+func IsSecret(key, value string) bool {
+	upperKey := strings.ToUpper(key)
+	for _, suffix := range secretSuffixes {
+		if strings.HasSuffix(upperKey, suffix) {
+			return true
+		}
+	}
+	return DetectSecret(key, value) != ""
+}
+
+// quoteValue quotes a configuration value for an issue message. Issues end up in reports,
+// SARIF and GitHub annotations, so secrets (see IsSecret, and keys listed in
+// ACI_SECRET_KEYS) are redacted.
+func quoteValue(cfg *config.Config, key, value string) string {
+	if IsSecret(key, value) {
+		return redactedValue
+	}
+	for _, secret := range config.SplitList(cfg.Get("ACI_SECRET_KEYS")) {
+		if strings.EqualFold(secret, key) {
+			return redactedValue
+		}
+	}
+	return strconv.Quote(value)
+}
+
+// DetectSecret reports why a key/value pair looks like a secret, or "" if it does not
+func DetectSecret(key, value string) string {
+	if value == "" {
+		return ""
+	}
+
+	upperKey := strings.ToUpper(key)
+	for _, sensitive := range sensitiveFields {
+		if upperKey == sensitive || strings.HasSuffix(upperKey, "_"+sensitive) {
+			return fmt.Sprintf("%s is a sensitive key", sensitive)
+		}
+	}
+
+	for _, format := range knownSecretFormats {
+		if format.pattern.MatchString(value) {
+			return fmt.Sprintf("value looks like a %s", format.name)
+		}
+	}
+
+	if len(value) >= minEntropyLength && !strings.Contains(value, "://") && !strings.ContainsAny(value, " \t") {
+		if entropy := shannonEntropy(value); entropy >= minEntropyBits {
+			return fmt.Sprintf("value has high entropy (%.1f bits/char)", entropy)
+		}
+	}
+
+	return ""
+}
+
+// ScanBuildArgs returns an issue for every build argument that looks like a secret.
+// Keys in allowlist are skipped.
+func ScanBuildArgs(args map[string]string, allowlist []string, severity Severity) []Issue {
+	allowed := make(map[string]bool, len(allowlist))
+	for _, key := range allowlist {
+		allowed[strings.ToUpper(strings.TrimSpace(key))] = true
+	}
+
+	keys := make([]string, 0, len(args))
+	for key := range args {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var issues []Issue
+	for _, key := range keys {
+		if allowed[strings.ToUpper(key)] {
+			continue
+		}
+		if reason := DetectSecret(key, args[key]); reason != "" {
+			issues = append(issues, Issue{
+				Rule:     SecretsRuleName,
+				Key:      key,
+				Severity: severity,
+				Message: fmt.Sprintf("%s; build args are baked into the client bundle and image history "+
+					"(allowlist it or pass it with --secret)", reason),
+			})
+		}
+	}
+	return issues
+}
+
+// shannonEntropy returns the Shannon entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+
+	var entropy float64
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
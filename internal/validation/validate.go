@@ -1,8 +1,12 @@
 package validation
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/furiatona/azctl/internal/config"
@@ -14,12 +18,122 @@ type Validator interface {
 	Name() string
 }
 
+// Severity indicates how serious a validation issue is
+type Severity string
+
+const (
+	// SeverityError marks issues that must block a deployment
+	SeverityError Severity = "error"
+	// SeverityWarning marks issues that are reported but do not block a deployment
+	SeverityWarning Severity = "warning"
+)
+
+// Validation targets understood by ForTarget
+const (
+	TargetACR    = "acr"
+	TargetACI    = "aci"
+	TargetWebApp = "webapp"
+)
+
 // ValidationRule represents a single validation rule
 type ValidationRule struct {
 	Name     string
 	Required []string
 	Patterns map[string]string
 	Custom   func(cfg *config.Config) error
+	// Severity applies to every issue raised by the rule; defaults to SeverityError
+	Severity Severity
+	// File and Line locate where the rule is defined (e.g. a policy file); issues use
+	// them when the offending value has no file location of its own
+	File string
+	Line int
+}
+
+// FieldError ties a custom validation failure to a specific configuration key.
+// Custom validators may return several of them combined with errors.Join.
+type FieldError struct {
+	Key     string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Key, e.Message)
+}
+
+// Issue describes a single validation problem
+type Issue struct {
+	Rule     string   `json:"rule"`
+	Key      string   `json:"key,omitempty"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	Source   string   `json:"source,omitempty"`
+	// File and Line locate the offending value when it was loaded from a file
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+}
+
+// String formats the issue as a single human-readable line
+func (i Issue) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: ", i.Rule)
+	if i.Key != "" {
+		fmt.Fprintf(&b, "%s: ", i.Key)
+	}
+	b.WriteString(i.Message)
+	switch {
+	case i.Line > 0:
+		fmt.Fprintf(&b, " (source: %s:%d)", i.File, i.Line)
+	case i.Source != "":
+		fmt.Fprintf(&b, " (source: %s)", i.Source)
+	}
+	return b.String()
+}
+
+// Report collects every issue found by a validation run
+type Report struct {
+	Issues []Issue
+	// DefaultFile locates issues that have no file of their own (process environment, App
+	// Configuration) in SARIF output, where every result needs a location
+	DefaultFile string
+}
+
+// Errors returns the issues with error severity
+func (r *Report) Errors() []Issue {
+	return r.filter(SeverityError)
+}
+
+// Warnings returns the issues with warning severity
+func (r *Report) Warnings() []Issue {
+	return r.filter(SeverityWarning)
+}
+
+// HasErrors reports whether any issue should block the operation
+func (r *Report) HasErrors() bool {
+	return len(r.Errors()) > 0
+}
+
+// Err returns an error listing every error-severity issue, or nil if there are none
+func (r *Report) Err() error {
+	errs := r.Errors()
+	if len(errs) == 0 {
+		return nil
+	}
+
+	lines := make([]string, 0, len(errs))
+	for _, issue := range errs {
+		lines = append(lines, issue.String())
+	}
+	return fmt.Errorf("validation failed:\n%s", strings.Join(lines, "\n"))
+}
+
+func (r *Report) filter(severity Severity) []Issue {
+	var issues []Issue
+	for _, issue := range r.Issues {
+		if issue.Severity == severity {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
 }
 
 // ValidationEngine handles validation of configuration
@@ -34,46 +148,86 @@ func NewEngine() *ValidationEngine {
 	}
 }
 
+// ForTarget creates a validation engine with the predefined rules for a deployment target
+func ForTarget(target string) (*ValidationEngine, error) {
+	engine := NewEngine()
+	switch target {
+	case TargetACR:
+		engine.AddRule(ACRValidation)
+	case TargetACI:
+		engine.AddRule(ACIValidation)
+	case TargetWebApp:
+		engine.AddRule(WebAppValidation)
+	default:
+		return nil, fmt.Errorf("unknown validation target: %s (expected %s, %s or %s)",
+			target, TargetACI, TargetWebApp, TargetACR)
+	}
+	engine.AddRule(SecurityValidation)
+	return engine, nil
+}
+
 // AddRule adds a validation rule to the engine
 func (e *ValidationEngine) AddRule(rule ValidationRule) {
 	e.rules = append(e.rules, rule)
 }
 
-// Validate validates configuration against all rules
+// Validate validates configuration against all rules, returning an error that lists every
+// error-severity issue. Warnings do not cause an error; use Run to inspect them.
 func (e *ValidationEngine) Validate(cfg *config.Config) error {
-	var errors []string
+	return e.Run(cfg).Err()
+}
 
+// Run evaluates every rule and returns all issues found instead of stopping at the first one
+func (e *ValidationEngine) Run(cfg *config.Config) *Report {
+	report := &Report{}
 	for _, rule := range e.rules {
-		if err := e.validateRule(cfg, rule); err != nil {
-			errors = append(errors, fmt.Sprintf("%s: %v", rule.Name, err))
-		}
+		report.Issues = append(report.Issues, e.validateRule(cfg, rule)...)
 	}
-
-	if len(errors) > 0 {
-		return fmt.Errorf("validation failed:\n%s", strings.Join(errors, "\n"))
-	}
-
-	return nil
+	return report
 }
 
 // validateRule validates a single rule
-func (e *ValidationEngine) validateRule(cfg *config.Config, rule ValidationRule) error {
+func (e *ValidationEngine) validateRule(cfg *config.Config, rule ValidationRule) []Issue {
+	var issues []Issue
+	add := func(key, message string) {
+		severity := rule.Severity
+		if severity == "" {
+			severity = SeverityError
+		}
+		issue := Issue{Rule: rule.Name, Key: key, Severity: severity, Message: message}
+		if key != "" {
+			issue.Source = cfg.Source(key)
+			issue.File, issue.Line = cfg.Location(key)
+		}
+		if issue.Line == 0 && rule.File != "" {
+			issue.File, issue.Line = rule.File, rule.Line
+		}
+		issues = append(issues, issue)
+	}
+
 	// Check required fields
 	for _, field := range rule.Required {
 		if !cfg.Has(field) {
-			return fmt.Errorf("missing required field: %s", field)
+			add(field, "missing required value")
 		}
 	}
 
-	// Check patterns
-	for field, pattern := range rule.Patterns {
+	// Check patterns in a stable order so reports are reproducible
+	fields := make([]string, 0, len(rule.Patterns))
+	for field := range rule.Patterns {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		pattern := rule.Patterns[field]
 		if value := cfg.Get(field); value != "" {
 			matched, err := regexp.MatchString(pattern, value)
 			if err != nil {
-				return fmt.Errorf("invalid regex pattern for %s: %w", field, err)
+				add(field, fmt.Sprintf("invalid regex pattern %s: %v", pattern, err))
+				continue
 			}
 			if !matched {
-				return fmt.Errorf("field %s does not match pattern %s", field, pattern)
+				add(field, fmt.Sprintf("value %s does not match pattern %s", quoteValue(cfg, field, value), pattern))
 			}
 		}
 	}
@@ -81,21 +235,106 @@ func (e *ValidationEngine) validateRule(cfg *config.Config, rule ValidationRule)
 	// Run custom validation
 	if rule.Custom != nil {
 		if err := rule.Custom(cfg); err != nil {
-			return err
+			for _, e := range splitErrors(err) {
+				var fieldErr *FieldError
+				if errors.As(e, &fieldErr) {
+					add(fieldErr.Key, fieldErr.Message)
+				} else {
+					add("", e.Error())
+				}
+			}
 		}
 	}
 
+	return issues
+}
+
+// splitErrors unpacks errors combined with errors.Join
+func splitErrors(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+// deployStrategyPattern matches the strategies accepted by DEPLOY_STRATEGY
+const deployStrategyPattern = `^(?i)(recreate|in-place|skip-if-unchanged)$`
+
+// acrAuthPattern matches the registry authentication modes accepted by ACR_AUTH
+const acrAuthPattern = `^(?i)(admin|identity)$`
+
+// identityIDPattern matches the resource ID of a user-assigned managed identity
+const identityIDPattern = `^(?i)/subscriptions/[^/]+/resourceGroups/[^/]+/providers/` +
+	`Microsoft\.ManagedIdentity/userAssignedIdentities/[^/]+$`
+
+// registryAuthIssues requires the ACR admin credentials, or with ACR_AUTH=identity the
+// managed identity pulling the images when needIdentity is set
+func registryAuthIssues(cfg *config.Config, needIdentity bool) []error {
+	var errs []error
+	if strings.EqualFold(cfg.Get("ACR_AUTH"), "identity") {
+		if needIdentity && !cfg.Has("ACR_IDENTITY_ID") {
+			errs = append(errs, &FieldError{Key: "ACR_IDENTITY_ID", Message: "required when ACR_AUTH=identity"})
+		}
+		return errs
+	}
+	for _, key := range []string{"ACR_USERNAME", "ACR_PASSWORD"} {
+		if !cfg.Has(key) {
+			errs = append(errs, &FieldError{Key: key, Message: "missing required value (or set ACR_AUTH=identity)"})
+		}
+	}
+	return errs
+}
+
+// subnetIDPattern matches the resource ID of a virtual network subnet
+var subnetIDPattern = regexp.MustCompile(`^(?i)/subscriptions/[^/]+/resourceGroups/[^/]+/providers/` +
+	`Microsoft\.Network/virtualNetworks/[^/]+/subnets/[^/]+$`)
+
+// registryNameIssue rejects ACR_REGISTRY with the .azurecr.io suffix: it is the bare
+// registry name and azctl appends the suffix itself
+func registryNameIssue(cfg *config.Config) error {
+	if strings.HasSuffix(strings.ToLower(cfg.Get("ACR_REGISTRY")), ".azurecr.io") {
+		return &FieldError{Key: "ACR_REGISTRY", Message: "use the registry name without the .azurecr.io suffix"}
+	}
 	return nil
 }
 
+// networkIssues requires DNS_NAME_LABEL for public container groups, and subnets for private
+// ones (ACI_NETWORK_MODE=private)
+func networkIssues(cfg *config.Config) []error {
+	if !strings.EqualFold(cfg.Get("ACI_NETWORK_MODE"), "private") {
+		if !cfg.Has("DNS_NAME_LABEL") {
+			return []error{&FieldError{Key: "DNS_NAME_LABEL",
+				Message: "missing required value (or set ACI_NETWORK_MODE=private)"}}
+		}
+		return nil
+	}
+
+	var errs []error
+	subnets := config.SplitList(cfg.Get("ACI_SUBNET_IDS"))
+	for _, id := range subnets {
+		if !subnetIDPattern.MatchString(id) {
+			errs = append(errs, &FieldError{Key: "ACI_SUBNET_IDS", Message: fmt.Sprintf("%q is not a subnet resource ID", id)})
+		}
+	}
+	if len(subnets) == 0 {
+		errs = append(errs, &FieldError{Key: "ACI_SUBNET_IDS", Message: "required when ACI_NETWORK_MODE=private"})
+	}
+	for _, server := range config.SplitList(cfg.Get("ACI_DNS_SERVERS")) {
+		if net.ParseIP(server) == nil {
+			errs = append(errs, &FieldError{Key: "ACI_DNS_SERVERS", Message: fmt.Sprintf("%q is not an IP address", server)})
+		}
+	}
+	return errs
+}
+
 // Predefined validation rules
 var (
-	// ACRValidation validates Azure Container Registry configuration
+	// ACRValidation validates Azure Container Registry configuration.
+	// ACR_RESOURCE_GROUP is not required because the acr command discovers it when unset.
 	ACRValidation = ValidationRule{
 		Name: "ACR Configuration",
 		Required: []string{
 			"ACR_REGISTRY",
-			"ACR_RESOURCE_GROUP",
 			"IMAGE_NAME",
 			"IMAGE_TAG",
 		},
@@ -104,14 +343,7 @@ var (
 			"IMAGE_NAME":   `^[a-zA-Z0-9_-]+$`,
 			"IMAGE_TAG":    `^[a-zA-Z0-9._-]+$`,
 		},
-		Custom: func(cfg *config.Config) error {
-			// Validate ACR registry format
-			registry := cfg.Get("ACR_REGISTRY")
-			if !strings.HasSuffix(registry, ".azurecr.io") {
-				return fmt.Errorf("ACR_REGISTRY should end with .azurecr.io")
-			}
-			return nil
-		},
+		Custom: registryNameIssue,
 	}
 
 	// WebAppValidation validates Azure Web App configuration
@@ -124,8 +356,16 @@ var (
 			"IMAGE_TAG",
 		},
 		Patterns: map[string]string{
-			"RESOURCE_GROUP": `^[a-zA-Z0-9_-]+$`,
-			"WEBAPP_NAME":    `^[a-zA-Z0-9_-]+$`,
+			"RESOURCE_GROUP":         `^[a-zA-Z0-9_-]+$`,
+			"WEBAPP_NAME":            `^[a-zA-Z0-9_-]+$`,
+			"DEPLOY_STRATEGY":        deployStrategyPattern,
+			"WEBAPP_DEPLOY_STRATEGY": deployStrategyPattern,
+			"ACR_AUTH":               acrAuthPattern,
+			"ACR_IDENTITY_ID":        identityIDPattern,
+		},
+		Custom: func(cfg *config.Config) error {
+			// Web Apps fall back to their system-assigned identity
+			return errors.Join(registryAuthIssues(cfg, false)...)
 		},
 	}
 
@@ -137,15 +377,12 @@ var (
 			"CONTAINER_GROUP_NAME",
 			"LOCATION",
 			"OS_TYPE",
-			"DNS_NAME_LABEL",
 			"ACI_PORT",
 			"ACI_CPU",
 			"ACI_MEMORY",
 			"ACR_REGISTRY",
 			"IMAGE_NAME",
 			"IMAGE_TAG",
-			"ACR_USERNAME",
-			"ACR_PASSWORD",
 		},
 		Patterns: map[string]string{
 			"RESOURCE_GROUP":       `^[a-zA-Z0-9_-]+$`,
@@ -155,62 +392,71 @@ var (
 			"ACI_CPU":              `^\d+(\.\d+)?$`,
 			"ACI_MEMORY":           `^\d+(\.\d+)?$`,
 			"OS_TYPE":              `^(Linux|Windows)$`,
+			"ACI_HEALTH_PATH":      `^/`,
+			"ACI_HEALTH_STATUS":    `^[1-5]\d\d$`,
+			"DEPLOY_STRATEGY":      deployStrategyPattern,
+			"ACI_DEPLOY_STRATEGY":  deployStrategyPattern,
+			"ACR_AUTH":             acrAuthPattern,
+			"ACR_IDENTITY_ID":      identityIDPattern,
+			"ACI_NETWORK_MODE":     `^(?i)(public|private)$`,
 		},
 		Custom: func(cfg *config.Config) error {
+			// Container groups only pull with a user-assigned identity
+			errs := registryAuthIssues(cfg, true)
+			errs = append(errs, registryNameIssue(cfg))
+			errs = append(errs, networkIssues(cfg)...)
+
 			// Validate CPU and memory values
 			cpu := cfg.Get("ACI_CPU")
 			memory := cfg.Get("ACI_MEMORY")
 
 			if cpu != "" {
 				if cpuFloat, err := parseFloat(cpu); err != nil {
-					return fmt.Errorf("invalid ACI_CPU value: %s", cpu)
+					errs = append(errs, &FieldError{Key: "ACI_CPU", Message: fmt.Sprintf("invalid value: %s", cpu)})
 				} else if cpuFloat <= 0 || cpuFloat > 4 {
-					return fmt.Errorf("ACI_CPU must be between 0.1 and 4.0")
+					errs = append(errs, &FieldError{Key: "ACI_CPU", Message: "must be between 0.1 and 4.0"})
 				}
 			}
 
 			if memory != "" {
 				if memoryFloat, err := parseFloat(memory); err != nil {
-					return fmt.Errorf("invalid ACI_MEMORY value: %s", memory)
+					errs = append(errs, &FieldError{Key: "ACI_MEMORY", Message: fmt.Sprintf("invalid value: %s", memory)})
 				} else if memoryFloat <= 0 || memoryFloat > 16 {
-					return fmt.Errorf("ACI_MEMORY must be between 0.1 and 16.0")
+					errs = append(errs, &FieldError{Key: "ACI_MEMORY", Message: "must be between 0.1 and 16.0"})
 				}
 			}
 
-			return nil
+			return errors.Join(errs...)
 		},
 	}
 
 	// SecurityValidation validates security-related configuration
 	SecurityValidation = ValidationRule{
-		Name: "Security Configuration",
+		Name:     "Security Configuration",
+		Severity: SeverityWarning,
 		Custom: func(cfg *config.Config) error {
 			// Check for sensitive data in plain text
-			sensitiveFields := []string{
-				"ACR_PASSWORD",
-				"AZURE_OPENAI_API_KEY",
-				"FIREBASE_KEY",
-				"SUPABASE_KEY",
-			}
-
+			var errs []error
 			for _, field := range sensitiveFields {
 				if value := cfg.Get(field); value != "" {
 					if len(value) < 8 {
-						return fmt.Errorf("sensitive field %s appears to be too short", field)
+						errs = append(errs, &FieldError{Key: field, Message: "sensitive value appears to be too short"})
 					}
 				}
 			}
 
-			return nil
+			return errors.Join(errs...)
 		},
 	}
 )
 
 // parseFloat safely parses a string to float64
 func parseFloat(s string) (float64, error) {
-	var f float64
-	_, err := fmt.Sscanf(s, "%f", &f)
-	return f, fmt.Errorf("failed to parse float: %w", err)
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse float: %w", err)
+	}
+	return f, nil
 }
 
 // Convenience functions for backward compatibility
@@ -256,14 +502,11 @@ func ACIRequiredVars() []string {
 		"CONTAINER_GROUP_NAME",
 		"LOCATION",
 		"OS_TYPE",
-		"DNS_NAME_LABEL",
 		"ACI_PORT",
 		"ACI_CPU",
 		"ACI_MEMORY",
 		"ACR_REGISTRY",
 		"IMAGE_NAME",
 		"IMAGE_TAG",
-		"ACR_USERNAME",
-		"ACR_PASSWORD",
 	}
 }
@@ -100,3 +100,177 @@ func TestACIRequiredVars(t *testing.T) {
 		}
 	}
 }
+
+func TestEngineReportsAllIssues(t *testing.T) {
+	cfg := config.New()
+	cfg.Set("ACI_CPU", "8")
+	cfg.Set("ACI_MEMORY", "lots")
+	cfg.Set("OS_TYPE", "Solaris")
+	cfg.Set("SUPABASE_KEY", "short")
+
+	engine := NewEngine()
+	engine.AddRule(ACIValidation)
+	engine.AddRule(SecurityValidation)
+	report := engine.Run(cfg)
+
+	keys := make(map[string]Severity)
+	for _, issue := range report.Issues {
+		keys[issue.Key] = issue.Severity
+	}
+
+	for _, key := range []string{"RESOURCE_GROUP", "DNS_NAME_LABEL", "ACI_CPU", "ACI_MEMORY", "OS_TYPE"} {
+		if keys[key] != SeverityError {
+			t.Errorf("expected error issue for %s, got %q", key, keys[key])
+		}
+	}
+	if keys["SUPABASE_KEY"] != SeverityWarning {
+		t.Errorf("expected warning issue for SUPABASE_KEY, got %q", keys["SUPABASE_KEY"])
+	}
+	if !report.HasErrors() {
+		t.Error("expected report to have errors")
+	}
+
+	err := report.Err()
+	if err == nil {
+		t.Fatal("expected error from report")
+	}
+	if strings.Contains(err.Error(), "SUPABASE_KEY") {
+		t.Errorf("warnings should not be part of the error: %v", err)
+	}
+	if !strings.Contains(err.Error(), "(source: derived)") {
+		t.Errorf("error should include value sources: %v", err)
+	}
+}
+
+func TestEngineWarningsOnly(t *testing.T) {
+	cfg := config.New()
+	cfg.Set("ACR_REGISTRY", "myregistry")
+	cfg.Set("IMAGE_NAME", "app")
+	cfg.Set("IMAGE_TAG", "v1.0.0")
+	cfg.Set("ACR_PASSWORD", "short")
+
+	engine, err := ForTarget(TargetACR)
+	if err != nil {
+		t.Fatalf("ForTarget failed: %v", err)
+	}
+	report := engine.Run(cfg)
+	if report.HasErrors() {
+		t.Errorf("expected no errors, got %v", report.Errors())
+	}
+	if len(report.Warnings()) != 1 {
+		t.Errorf("expected 1 warning, got %d", len(report.Warnings()))
+	}
+	if err := engine.Validate(cfg); err != nil {
+		t.Errorf("warnings should not fail validation: %v", err)
+	}
+}
+
+func TestPatternIssuesRedactSecrets(t *testing.T) {
+	cfg := config.New()
+	cfg.Set("API_TOKEN", "hunter2")
+	cfg.Set("TENANT", "tenant-secret")
+	cfg.Set("REGION", "moon")
+	cfg.Set("ACI_SECRET_KEYS", "TENANT")
+
+	engine := NewEngine()
+	engine.AddRule(ValidationRule{Name: "Patterns", Patterns: map[string]string{
+		"API_TOKEN": `^tok_`, "TENANT": `^[a-z]+$`, "REGION": `^eu`,
+	}})
+	messages := make(map[string]string)
+	for _, issue := range engine.Run(cfg).Issues {
+		messages[issue.Key] = issue.Message
+	}
+	for key, secret := range map[string]string{"API_TOKEN": "hunter2", "TENANT": "tenant-secret"} {
+		if !strings.Contains(messages[key], redactedValue) || strings.Contains(messages[key], secret) {
+			t.Errorf("expected %s to be redacted, got %q", key, messages[key])
+		}
+	}
+	if !strings.Contains(messages["REGION"], `"moon"`) {
+		t.Errorf("expected the plain value in %q", messages["REGION"])
+	}
+}
+
+func TestForTargetUnknown(t *testing.T) {
+	if _, err := ForTarget("lambda"); err == nil {
+		t.Error("expected error for unknown target")
+	}
+}
+
+func TestRegistryAuth(t *testing.T) {
+	issueKeys := func(cfg *config.Config, rule ValidationRule) map[string]bool {
+		engine := NewEngine()
+		engine.AddRule(rule)
+		keys := make(map[string]bool)
+		for _, issue := range engine.Run(cfg).Issues {
+			keys[issue.Key] = true
+		}
+		return keys
+	}
+
+	cfg := config.New()
+	keys := issueKeys(cfg, ACIValidation)
+	if !keys["ACR_USERNAME"] || !keys["ACR_PASSWORD"] {
+		t.Errorf("admin credentials must be required by default, got %v", keys)
+	}
+
+	cfg.Set("ACR_AUTH", "identity")
+	keys = issueKeys(cfg, ACIValidation)
+	if keys["ACR_USERNAME"] || keys["ACR_PASSWORD"] || !keys["ACR_IDENTITY_ID"] {
+		t.Errorf("identity mode must require ACR_IDENTITY_ID instead of credentials, got %v", keys)
+	}
+	if keys = issueKeys(cfg, WebAppValidation); keys["ACR_IDENTITY_ID"] || keys["ACR_USERNAME"] {
+		t.Errorf("Web Apps may use their system-assigned identity, got %v", keys)
+	}
+
+	cfg.Set("ACR_IDENTITY_ID", "/subscriptions/s/resourceGroups/rg/providers/Microsoft.ManagedIdentity/pull")
+	if keys = issueKeys(cfg, ACIValidation); !keys["ACR_IDENTITY_ID"] {
+		t.Error("expected an issue for a malformed identity ID")
+	}
+	cfg.Set("ACR_IDENTITY_ID",
+		"/subscriptions/s/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/pull")
+	if keys = issueKeys(cfg, ACIValidation); keys["ACR_IDENTITY_ID"] {
+		t.Errorf("unexpected issue for a valid identity ID: %v", keys)
+	}
+
+	// ACR_REGISTRY is the bare name for container groups too
+	cfg.Set("ACR_REGISTRY", "reg.azurecr.io")
+	if keys = issueKeys(cfg, ACIValidation); !keys["ACR_REGISTRY"] {
+		t.Errorf("expected an issue for the .azurecr.io suffix, got %v", keys)
+	}
+}
+
+func TestNetworkMode(t *testing.T) {
+	issues := func(cfg *config.Config) map[string]string {
+		engine := NewEngine()
+		engine.AddRule(ACIValidation)
+		keys := make(map[string]string)
+		for _, issue := range engine.Run(cfg).Issues {
+			keys[issue.Key] = issue.Message
+		}
+		return keys
+	}
+
+	cfg := config.New()
+	cfg.Set("ACI_NETWORK_MODE", "private")
+	keys := issues(cfg)
+	if _, ok := keys["DNS_NAME_LABEL"]; ok {
+		t.Errorf("private mode must not require DNS_NAME_LABEL: %v", keys)
+	}
+	if !strings.Contains(keys["ACI_SUBNET_IDS"], "required") {
+		t.Errorf("expected ACI_SUBNET_IDS to be required, got %v", keys)
+	}
+
+	cfg.Set("ACI_SUBNET_IDS",
+		"/subscriptions/s/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet/subnets/aci,aci-subnet")
+	cfg.Set("ACI_DNS_SERVERS", "10.0.0.4, dns.internal")
+	keys = issues(cfg)
+	if !strings.Contains(keys["ACI_SUBNET_IDS"], `"aci-subnet"`) ||
+		!strings.Contains(keys["ACI_DNS_SERVERS"], "dns.internal") {
+		t.Errorf("expected malformed subnet and DNS server issues, got %v", keys)
+	}
+
+	cfg.Set("ACI_NETWORK_MODE", "vnet")
+	if keys = issues(cfg); keys["ACI_NETWORK_MODE"] == "" || keys["DNS_NAME_LABEL"] == "" {
+		t.Errorf("expected an invalid mode to be reported and treated as public, got %v", keys)
+	}
+}